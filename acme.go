@@ -0,0 +1,62 @@
+package jwk
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+)
+
+// AccountKey builds the public JWK of an ACME account key, to be embedded in the jwk header of the newAccount
+// request. Alg is set to the JWS algorithm suiting the key, see https://tools.ietf.org/html/rfc8555#section-6.2
+func AccountKey(signer crypto.Signer) (Key, error) {
+	key, err := FromPublicKey(signer.Public())
+	if err != nil {
+		return key, err
+	}
+	switch key.Kty {
+	case "RSA":
+		key.Alg = "RS256"
+	default:
+		for alg, crv := range algCurves {
+			if crv == key.Crv {
+				key.Alg = alg
+			}
+		}
+		if key.Alg == "" {
+			return Key{}, fmt.Errorf("unsupported curve %q", key.Crv)
+		}
+	}
+	return key, nil
+}
+
+// ParseAccountKey decodes a stored ACME account JWK, i.e. the jwk header of a newAccount request, making
+// sure it's a valid public key
+func ParseAccountKey(data []byte) (Key, error) {
+	key, err := decodePublicJWK(data)
+	if err != nil {
+		return Key{}, fmt.Errorf("malformed account key: %w", err)
+	}
+	return key, nil
+}
+
+// KeyAuthorization returns the ACME key authorization of the given challenge token: the token and the key
+// thumbprint joined by a dot, see https://tools.ietf.org/html/rfc8555#section-8.1
+func (k Key) KeyAuthorization(token string) (string, error) {
+	thumbprint, err := k.Thumbprint()
+	if err != nil {
+		return "", err
+	}
+	return token + "." + thumbprint, nil
+}
+
+// DNS01Record returns the value of the TXT record answering the dns-01 challenge with the given token,
+// see https://tools.ietf.org/html/rfc8555#section-8.4
+func (k Key) DNS01Record(token string) (string, error) {
+	keyAuthorization, err := k.KeyAuthorization(token)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(keyAuthorization))
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
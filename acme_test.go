@@ -0,0 +1,62 @@
+package jwk
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+)
+
+func TestAccountKey(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := AccountKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.Alg != "ES384" || key.Crv != "P-384" {
+		t.Fatalf("unexpected account key %+v", key)
+	}
+
+	encoded, err := json.Marshal(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseAccountKey(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.X != key.X || parsed.Y != key.Y {
+		t.Fatalf("expecting %+v, got %+v", key, parsed)
+	}
+	if _, err := ParseAccountKey([]byte(`{"kty":"EC","crv":"P-256","x":"` + key.X + `","y":"` + key.Y + `","d":"secret"}`)); err == nil {
+		t.Fatal("expecting a private key to be rejected")
+	}
+}
+
+// the key and expected thumbprint come from RFC 7638, section 3.1
+func TestKeyAuthorization(t *testing.T) {
+	key := Key{Kty: "RSA", E: "AQAB", N: "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw"}
+
+	keyAuthorization, err := key.KeyAuthorization("token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyAuthorization != "token.NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Fatalf("unexpected key authorization %q", keyAuthorization)
+	}
+
+	record, err := key.DNS01Record("token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte(keyAuthorization))
+	if record != base64.RawURLEncoding.EncodeToString(sum[:]) {
+		t.Fatalf("unexpected dns-01 record %q", record)
+	}
+}
@@ -0,0 +1,74 @@
+package jwk
+
+import (
+	"strings"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+// AudienceMatch tells how the aud claim of a token is matched against the expected audiences
+type AudienceMatch int
+
+const (
+	// AnyAudience accepts the tokens whose aud holds any of the expected audiences
+	AnyAudience AudienceMatch = iota
+	// AllAudiences requires the aud claim to hold all the expected audiences
+	AllAudiences
+	// ExactAudience requires the aud claim to hold all the expected audiences and nothing else
+	ExactAudience
+	// PrefixAudience accepts the tokens with an aud value equal to or under any of the expected audiences, as
+	// issued for RFC 8707 resource indicators: https://api.example.com matches https://api.example.com/orders,
+	// but not https://api.example.com.evil.net
+	PrefixAudience
+)
+
+// matchAudience tells whether the audience satisfies the expected values with the given match, always true when
+// none is expected
+func matchAudience(audience jwt.Audience, expected []string, match AudienceMatch) bool {
+	if len(expected) == 0 {
+		return true
+	}
+	switch match {
+	case AllAudiences, ExactAudience:
+		for _, value := range expected {
+			if !audience.Contains(value) {
+				return false
+			}
+		}
+		if match == ExactAudience {
+			for _, value := range audience {
+				if !containsString(expected, value) {
+					return false
+				}
+			}
+		}
+		return true
+	case PrefixAudience:
+		for _, value := range audience {
+			for _, prefix := range expected {
+				if underPrefix(value, prefix) {
+					return true
+				}
+			}
+		}
+		return false
+	default:
+		for _, value := range expected {
+			if audience.Contains(value) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// underPrefix tells whether value is prefix or starts with it at a path, query or fragment boundary
+func underPrefix(value, prefix string) bool {
+	if !strings.HasPrefix(value, prefix) {
+		return false
+	}
+	if len(value) == len(prefix) || strings.HasSuffix(prefix, "/") {
+		return true
+	}
+	return strings.ContainsRune("/?#", rune(value[len(prefix)]))
+}
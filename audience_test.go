@@ -0,0 +1,63 @@
+package jwk
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+func TestMatchAudience(t *testing.T) {
+	tests := []struct {
+		audience jwt.Audience
+		expected []string
+		match    AudienceMatch
+		ok       bool
+	}{
+		{jwt.Audience{"a"}, nil, ExactAudience, true},
+		{jwt.Audience{"a", "b"}, []string{"b", "c"}, AnyAudience, true},
+		{jwt.Audience{"a"}, []string{"b", "c"}, AnyAudience, false},
+		{jwt.Audience{"a", "b", "c"}, []string{"a", "b"}, AllAudiences, true},
+		{jwt.Audience{"a"}, []string{"a", "b"}, AllAudiences, false},
+		{jwt.Audience{"b", "a"}, []string{"a", "b"}, ExactAudience, true},
+		{jwt.Audience{"a", "b", "c"}, []string{"a", "b"}, ExactAudience, false},
+		{jwt.Audience{"https://api.example.com/orders"}, []string{"https://api.example.com/"}, PrefixAudience, true},
+		{jwt.Audience{"https://api.example.com.evil/"}, []string{"https://api.example.com/"}, PrefixAudience, false},
+		{jwt.Audience{"https://api.example.com/orders"}, []string{"https://api.example.com"}, PrefixAudience, true},
+		{jwt.Audience{"https://api.example.com?tenant=1"}, []string{"https://api.example.com"}, PrefixAudience, true},
+		{jwt.Audience{"https://api.example.com"}, []string{"https://api.example.com"}, PrefixAudience, true},
+		{jwt.Audience{"https://api.example.com.evil.net"}, []string{"https://api.example.com"}, PrefixAudience, false},
+		{jwt.Audience{"https://api.example.comx"}, []string{"https://api.example.com"}, PrefixAudience, false},
+	}
+	for _, test := range tests {
+		if ok := matchAudience(test.audience, test.expected, test.match); ok != test.ok {
+			t.Errorf("expecting %v for %v against %v in mode %d, got %v", test.ok, test.audience, test.expected, test.match, ok)
+		}
+	}
+}
+
+func TestVerifyTokenAudienceMatch(t *testing.T) {
+	signer, j := newTestSigner(t, "test")
+	raw := signTestToken(t, signer, jwt.Claims{
+		Audience: jwt.Audience{"https://api.example.com/orders", "other"},
+		Expiry:   jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	})
+
+	j.Audience, j.AudienceMatch = "https://api.example.com/", PrefixAudience
+	if _, err := j.VerifyToken(context.Background(), raw); err != nil {
+		t.Fatal(err)
+	}
+	j.AudienceMatch = AnyAudience
+	if _, err := j.VerifyToken(context.Background(), raw); err == nil {
+		t.Fatal("expecting the audience not to match exactly")
+	}
+
+	// the per-call audiences replace the configured ones
+	if _, err := j.VerifyToken(context.Background(), raw, ExpectAudience(AllAudiences, "other", "https://api.example.com/orders")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.VerifyToken(context.Background(), raw, ExpectAudience(ExactAudience, "other")); err == nil {
+		t.Fatal("expecting the extra audience to be rejected")
+	}
+}
@@ -0,0 +1,73 @@
+package jwk
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+// AuditSink receives a record of every verification made by VerifyToken, VerifySDJWT, VerifyJWS,
+// VerifyDetachedJWS and SPIFFEBundles.VerifyJWTSVID, for authentication decision logging. It's called
+// synchronously, before the verification returns, so that no decision goes unrecorded: sinks shipping the records
+// elsewhere should buffer them
+type AuditSink interface {
+	Audit(ctx context.Context, record AuditRecord)
+}
+
+// AuditRecord describes a verification. The kid, alg, issuer and subject of rejected tokens are read without
+// verifying them, and may be forged
+type AuditRecord struct {
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"duration"`
+
+	Kid    string `json:"kid,omitempty"`
+	Alg    string `json:"alg,omitempty"`
+	Issuer string `json:"iss,omitempty"`
+
+	// SubjectHash is the base64url SHA-256 of the sub claim, identifying the subject without disclosing it
+	SubjectHash string `json:"sub_hash,omitempty"`
+
+	Verified bool `json:"verified"`
+
+	// Error is the redacted reason of the rejection
+	Error string `json:"error,omitempty"`
+}
+
+// audit sends the record of the verification of the given token to the Audit sink, if any
+func (j *JSONWebKeys) audit(ctx context.Context, start time.Time, raw string, claims map[string]interface{}, err error) {
+	sendAudit(ctx, j.Audit, start, raw, claims, err)
+}
+
+// sendAudit sends the record of the verification of the given token to sink, if any
+func sendAudit(ctx context.Context, sink AuditSink, start time.Time, raw string, claims map[string]interface{}, err error) {
+	if sink == nil {
+		return
+	}
+	record := AuditRecord{Time: start, Duration: time.Since(start), Verified: err == nil}
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	var subject string
+	if token, parseErr := jwt.ParseSigned(raw); parseErr == nil && len(token.Headers) > 0 {
+		record.Kid, record.Alg = token.Headers[0].KeyID, token.Headers[0].Algorithm
+		if claims == nil {
+			unverified := jwt.Claims{}
+			if token.UnsafeClaimsWithoutVerification(&unverified) == nil {
+				record.Issuer, subject = unverified.Issuer, unverified.Subject
+			}
+		}
+	}
+	if claims != nil {
+		record.Issuer, _ = claims["iss"].(string)
+		subject, _ = claims["sub"].(string)
+	}
+	if subject != "" {
+		sum := sha256.Sum256([]byte(subject))
+		record.SubjectHash = base64.RawURLEncoding.EncodeToString(sum[:])
+	}
+	sink.Audit(ctx, record)
+}
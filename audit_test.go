@@ -0,0 +1,98 @@
+package jwk
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+// recordingSink keeps the audit records it receives
+type recordingSink struct {
+	mutex   sync.Mutex
+	records []AuditRecord
+}
+
+func (s *recordingSink) Audit(ctx context.Context, record AuditRecord) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.records = append(s.records, record)
+}
+
+func TestAudit(t *testing.T) {
+	signer, j := newTestSigner(t, "test")
+	sink := &recordingSink{}
+	j.Audit = sink
+	j.Issuer = "https://issuer.example.com/"
+
+	valid := signTestToken(t, signer, jwt.Claims{
+		Issuer:  "https://issuer.example.com/",
+		Subject: "user",
+		Expiry:  jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	})
+	expired := signTestToken(t, signer, jwt.Claims{
+		Issuer:  "https://issuer.example.com/",
+		Subject: "user",
+		Expiry:  jwt.NewNumericDate(time.Now().Add(-time.Hour)),
+	})
+	for _, raw := range []string{valid, expired, "garbage"} {
+		j.VerifyToken(context.Background(), raw)
+	}
+
+	sum := sha256.Sum256([]byte("user"))
+	subjectHash := base64.RawURLEncoding.EncodeToString(sum[:])
+	if len(sink.records) != 3 {
+		t.Fatalf("expecting 3 records, got %d", len(sink.records))
+	}
+	for i, record := range sink.records[:2] {
+		if record.Kid != "test" || record.Alg != "RS256" || record.Issuer != "https://issuer.example.com/" || record.SubjectHash != subjectHash {
+			t.Fatalf("unexpected record %d: %+v", i, record)
+		}
+	}
+	if !sink.records[0].Verified || sink.records[0].Error != "" {
+		t.Fatalf("expecting the valid token to be recorded as verified, got %+v", sink.records[0])
+	}
+	if sink.records[1].Verified || sink.records[1].Error == "" {
+		t.Fatalf("expecting the expired token to be recorded as rejected, got %+v", sink.records[1])
+	}
+	if garbage := sink.records[2]; garbage.Verified || garbage.Kid != "" || garbage.Error == "" {
+		t.Fatalf("unexpected record for a malformed token: %+v", garbage)
+	}
+}
+
+func TestAuditVerifyPaths(t *testing.T) {
+	signer, j := newTestSigner(t, "test")
+	sink := &recordingSink{}
+	j.Audit = sink
+
+	jws, err := signer.Sign([]byte(`{"sub":"user"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	compact, _ := jws.CompactSerialize()
+	if _, _, err := j.VerifyJWS(context.Background(), []byte(compact)); err != nil {
+		t.Fatal(err)
+	}
+	j.VerifyJWS(context.Background(), []byte("garbage"))
+
+	r := &Registry{Audit: sink}
+	r.Add("https://issuer.example.com/", j)
+	r.VerifyToken(context.Background(), signTestToken(t, signer, jwt.Claims{Issuer: "https://other.example.com/"}))
+
+	if len(sink.records) != 3 {
+		t.Fatalf("expecting 3 records, got %d", len(sink.records))
+	}
+	if record := sink.records[0]; !record.Verified || record.Kid != "test" {
+		t.Fatalf("expecting the JWS to be recorded as verified, got %+v", record)
+	}
+	if record := sink.records[1]; record.Verified || record.Error == "" {
+		t.Fatalf("expecting the malformed JWS to be recorded as rejected, got %+v", record)
+	}
+	if record := sink.records[2]; record.Verified || record.Issuer != "https://other.example.com/" || record.Error == "" {
+		t.Fatalf("expecting the token of an unknown issuer to be recorded as rejected, got %+v", record)
+	}
+}
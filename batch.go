@@ -0,0 +1,59 @@
+package jwk
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// Result is the outcome of verifying one token of a batch, see VerifyTokens
+type Result struct {
+	// Claims are the claims of the token, when valid
+	Claims map[string]interface{}
+	// Err tells why the token is not valid
+	Err error
+}
+
+// VerifyTokens verifies a batch of tokens, as VerifyToken does, with a pool of GOMAXPROCS workers. The keys are
+// resolved once for the whole batch, so that a stale cache, ForceRefresh, CacheBypass or NoCache triggers a
+// single fetch. It returns the results in the order of the tokens
+func (j *JSONWebKeys) VerifyTokens(ctx context.Context, tokens []string, opts ...CallOption) []Result {
+	results := make([]Result, len(tokens))
+	if len(tokens) == 0 {
+		return results
+	}
+	certs, err := j.getKeys(ctx, opts...)
+	if err != nil {
+		for i := range results {
+			results[i].Err = err
+		}
+		return results
+	}
+	// the keys were just resolved, the tokens don't need to fetch them again
+	opts = append(opts[:len(opts):len(opts)], func(o *callOptions) {
+		o.forceRefresh = false
+		o.certs = certs
+	})
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(tokens) {
+		workers = len(tokens)
+	}
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i].Claims, results[i].Err = j.VerifyToken(ctx, tokens[i], opts...)
+			}
+		}()
+	}
+	for i := range tokens {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
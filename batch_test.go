@@ -0,0 +1,68 @@
+package jwk
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+func TestVerifyTokens(t *testing.T) {
+	signer, j := newTestSigner(t, "test")
+	valid := signTestToken(t, signer, jwt.Claims{Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour))})
+	expired := signTestToken(t, signer, jwt.Claims{Expiry: jwt.NewNumericDate(time.Now().Add(-time.Hour))})
+
+	tokens := []string{valid, expired, "garbage"}
+	for i := 0; i < 20; i++ {
+		tokens = append(tokens, valid)
+	}
+	results := j.VerifyTokens(context.Background(), tokens)
+	if len(results) != len(tokens) {
+		t.Fatalf("expecting %d results, got %d", len(tokens), len(results))
+	}
+	if results[0].Err != nil || results[0].Claims["scope"] != "read" {
+		t.Fatalf("unexpected result for the valid token: %+v", results[0])
+	}
+	if results[1].Err == nil || !strings.Contains(results[1].Err.Error(), "expired") {
+		t.Fatalf("expecting the expired token to be rejected, got %+v", results[1])
+	}
+	if results[2].Err == nil {
+		t.Fatal("expecting the malformed token to be rejected")
+	}
+	for _, result := range results[3:] {
+		if result.Err != nil {
+			t.Fatal(result.Err)
+		}
+	}
+}
+
+func TestVerifyTokensSingleFetch(t *testing.T) {
+	// the test signer key is not served, the point is the number of fetches
+	signer, _ := newTestSigner(t, "test")
+	token := signTestToken(t, signer, jwt.Claims{Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour))})
+
+	for name, tc := range map[string]struct {
+		noCache bool
+		opts    []CallOption
+	}{
+		"force refresh": {opts: []CallOption{ForceRefresh()}},
+		"cache bypass":  {opts: []CallOption{CacheBypass()}},
+		"no cache":      {noCache: true},
+	} {
+		server, requests := newTestJWKSServer(t, "", 0)
+		j := &JSONWebKeys{JWKURL: server.URL, NoCache: tc.noCache}
+		results := j.VerifyTokens(context.Background(), []string{token, token, token}, tc.opts...)
+		server.Close()
+		for _, result := range results {
+			if result.Err == nil {
+				t.Fatalf("%s: expecting the unknown key to be reported", name)
+			}
+		}
+		if n := atomic.LoadInt32(requests); n != 1 {
+			t.Errorf("%s: expecting a single fetch for the batch, got %d", name, n)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package jwk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+)
+
+// BundleType is the typ header of the bundles, so that no other JWT signed by the same key imports as one
+const BundleType = "jwk-bundle+jwt"
+
+// bundleClockSkew is how far in the future the iat of a bundle may be, for devices with a drifting clock
+const bundleClockSkew = time.Minute
+
+// bundleClaims is the payload of a bundle: the JWKS of the trusted keys along with its validity
+type bundleClaims struct {
+	IssuedAt  int64           `json:"iat"`
+	ExpiresAt int64           `json:"exp"`
+	Keys      json.RawMessage `json:"keys"`
+}
+
+// ExportBundle exports the public keys of the certs as a bundle for offline verification, i.e. on edge devices or
+// air-gapped networks: a compact JWS of their JWKS timestamped with iat and valid for the given duration with exp,
+// typed BundleType and signed with the given key, i.e. one made by SigningKey so that it carries its kid. See
+// ImportBundle and BundleFetcher
+func ExportBundle(certs *Certs, key jose.SigningKey, validity time.Duration) ([]byte, error) {
+	if validity <= 0 {
+		return nil, errors.New("the bundle validity must be positive")
+	}
+	signer, err := jose.NewSigner(key, (&jose.SignerOptions{}).WithType(BundleType))
+	if err != nil {
+		return nil, fmt.Errorf("unable to sign bundle: %w", err)
+	}
+	document, err := certs.MarshalJWKS()
+	if err != nil {
+		return nil, err
+	}
+	set := jwks{}
+	if err := json.Unmarshal(document, &set); err != nil {
+		return nil, err
+	}
+	keys, err := json.Marshal(set.Keys)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	payload, err := json.Marshal(bundleClaims{IssuedAt: now.Unix(), ExpiresAt: now.Add(validity).Unix(), Keys: keys})
+	if err != nil {
+		return nil, err
+	}
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		return nil, fmt.Errorf("unable to sign bundle: %w", err)
+	}
+	bundle, err := jws.CompactSerialize()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(bundle), nil
+}
+
+// ImportBundle checks the signature of a bundle made by ExportBundle against the trusted keys, i.e. the public key
+// of the publisher loaded with FromJSON, along with its BundleType typ, then its freshness: it must not be expired,
+// nor issued longer than maxAge ago when maxAge is positive. It returns the JWKS document of the bundle along with
+// when it stops being accepted: its exp, or earlier when maxAge runs out first
+func ImportBundle(bundle []byte, trusted *JSONWebKeys, maxAge time.Duration) ([]byte, time.Time, error) {
+	jws, err := jose.ParseSigned(string(bundle))
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("invalid bundle: %w", err)
+	}
+	if len(jws.Signatures) != 1 {
+		return nil, time.Time{}, errors.New("invalid bundle: expecting a single signature")
+	}
+	if err := checkMediaType(jws.Signatures[0].Protected, "typ", []string{BundleType}); err != nil {
+		return nil, time.Time{}, fmt.Errorf("invalid bundle: %w", err)
+	}
+	payload, err := trusted.VerifySignature(context.Background(), string(bundle))
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("invalid bundle: %w", err)
+	}
+	claims := bundleClaims{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, time.Time{}, fmt.Errorf("malformed bundle: %w", err)
+	}
+	if len(claims.Keys) == 0 || string(claims.Keys) == "null" || claims.IssuedAt == 0 || claims.ExpiresAt == 0 {
+		return nil, time.Time{}, errors.New("malformed bundle: keys, iat and exp are required")
+	}
+
+	now := time.Now()
+	issuedAt, expiry := time.Unix(claims.IssuedAt, 0), time.Unix(claims.ExpiresAt, 0)
+	switch {
+	case issuedAt.After(now.Add(bundleClockSkew)):
+		return nil, time.Time{}, fmt.Errorf("bundle issued in the future, at %s", issuedAt.UTC().Format(time.RFC3339))
+	case !now.Before(expiry):
+		return nil, time.Time{}, fmt.Errorf("bundle expired at %s", expiry.UTC().Format(time.RFC3339))
+	case maxAge > 0 && now.Sub(issuedAt) > maxAge:
+		return nil, time.Time{}, fmt.Errorf("bundle issued at %s is older than %s", issuedAt.UTC().Format(time.RFC3339), maxAge)
+	}
+	document, err := json.Marshal(map[string]json.RawMessage{"keys": claims.Keys})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if maxAge > 0 && issuedAt.Add(maxAge).Before(expiry) {
+		expiry = issuedAt.Add(maxAge)
+	}
+	return document, expiry, nil
+}
+
+// BundleFetcher reads the keys from a bundle made by ExportBundle, checked with ImportBundle on every fetch. The
+// keys are cached until the bundle expires or gets older than MaxAge, and refreshed as soon as a new bundle
+// replaces the file
+type BundleFetcher struct {
+	// Path is the path of the bundle
+	Path string
+
+	// Trusted holds the keys the bundle must be signed with
+	Trusted *JSONWebKeys
+
+	// MaxAge rejects the bundles issued longer ago, on top of their exp. Only exp applies when zero
+	MaxAge time.Duration
+
+	// PollInterval is how often the file is checked for changes where inotify is not available, 10 seconds by
+	// default
+	PollInterval time.Duration
+}
+
+// Fetch implements Fetcher
+func (b *BundleFetcher) Fetch(ctx context.Context) ([]byte, time.Duration, error) {
+	bundle, err := os.ReadFile(b.Path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to read bundle: %w", err)
+	}
+	document, expiry, err := ImportBundle(bundle, b.Trusted, b.MaxAge)
+	if err != nil {
+		return nil, 0, err
+	}
+	return document, time.Until(expiry), nil
+}
+
+// Changes implements ChangeNotifier
+func (b *BundleFetcher) Changes(ctx context.Context) (<-chan struct{}, error) {
+	return watchFile(ctx, b.Path, (&FileFetcher{PollInterval: b.PollInterval}).pollInterval())
+}
+
+// String describes the source of the certs
+func (b *BundleFetcher) String() string {
+	return "bundle://" + b.Path
+}
@@ -0,0 +1,142 @@
+package jwk
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+)
+
+// newBundlePublisher returns the signing key of a bundle publisher, along with the JSONWebKeys trusting it
+func newBundlePublisher(t *testing.T) (jose.SigningKey, *JSONWebKeys) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := FromPublicKey(private.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	public.Kid, public.Use = "publisher", "sig"
+	key, err := SigningKey(private, public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certs, err := parseCerts(&jwks{Keys: []Key{public}}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, &JSONWebKeys{cachedCerts: certs}
+}
+
+// signBundle signs the given bundle claims with the given typ, for the bundles ExportBundle can't make
+func signBundle(t *testing.T, key jose.SigningKey, typ string, claims bundleClaims) []byte {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := jose.NewSigner(key, (&jose.SignerOptions{}).WithType(jose.ContentType(typ)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle, err := jws.CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []byte(bundle)
+}
+
+func TestBundle(t *testing.T) {
+	key, trusted := newBundlePublisher(t)
+	certs, err := getTestCerts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle, err := ExportBundle(certs, key, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "bundle.jws")
+	if err := ioutil.WriteFile(path, bundle, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	j := &JSONWebKeys{Fetcher: &BundleFetcher{Path: path, Trusted: trusted, MaxAge: 24 * time.Hour}}
+	loaded, err := j.GetKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := loaded.Get(testKid); !ok || loaded.Source != "bundle://"+path {
+		t.Fatalf("expecting the bundled key, got %v from %s", loaded.Kids(), loaded.Source)
+	}
+	if until := time.Until(loaded.Expiry); until > time.Hour || until < 59*time.Minute {
+		t.Fatalf("expecting the keys to be cached until the bundle expires, got %s", until)
+	}
+
+	keys := json.RawMessage(`[{"kty":"RSA","kid":"k","use":"sig","n":"AQAB","e":"AQAB"}]`)
+	now := time.Now()
+	otherKey, _ := newBundlePublisher(t)
+	for _, tc := range []struct {
+		name   string
+		bundle []byte
+		maxAge time.Duration
+		err    string
+	}{
+		{"tampered", append(append([]byte{}, bundle[:len(bundle)-4]...), "AAAA"...), 0, "invalid bundle"},
+		{"untrusted", signBundle(t, otherKey, BundleType, bundleClaims{IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Hour).Unix(), Keys: keys}), 0, "invalid bundle"},
+		{"expired", signBundle(t, key, BundleType, bundleClaims{IssuedAt: now.Add(-2 * time.Hour).Unix(), ExpiresAt: now.Add(-time.Hour).Unix(), Keys: keys}), 0, "bundle expired"},
+		{"stale", signBundle(t, key, BundleType, bundleClaims{IssuedAt: now.Add(-2 * time.Hour).Unix(), ExpiresAt: now.Add(time.Hour).Unix(), Keys: keys}), time.Hour, "older than"},
+		{"future", signBundle(t, key, BundleType, bundleClaims{IssuedAt: now.Add(time.Hour).Unix(), ExpiresAt: now.Add(2 * time.Hour).Unix(), Keys: keys}), 0, "in the future"},
+		{"untyped", signBundle(t, key, "JWT", bundleClaims{IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Hour).Unix(), Keys: keys}), 0, "typ"},
+		{"no keys", signBundle(t, key, BundleType, bundleClaims{IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Hour).Unix()}), 0, "malformed bundle"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, _, err := ImportBundle(tc.bundle, trusted, tc.maxAge); err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expecting an error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestBundleFetcherMaxAge(t *testing.T) {
+	key, trusted := newBundlePublisher(t)
+	keys := json.RawMessage(`[{"kty":"RSA","kid":"k","use":"sig","n":"AQAB","e":"AQAB"}]`)
+	issuedAt := time.Now().Add(-30 * time.Minute)
+	bundle := signBundle(t, key, BundleType, bundleClaims{IssuedAt: issuedAt.Unix(), ExpiresAt: issuedAt.Add(24 * time.Hour).Unix(), Keys: keys})
+
+	dir, err := ioutil.TempDir("", "bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "bundle.jws")
+	if err := ioutil.WriteFile(path, bundle, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, cacheAge, err := (&BundleFetcher{Path: path, Trusted: trusted, MaxAge: time.Hour}).Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cacheAge > 31*time.Minute || cacheAge < 29*time.Minute {
+		t.Fatalf("expecting the keys to be cached until MaxAge runs out, got %s", cacheAge)
+	}
+}
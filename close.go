@@ -0,0 +1,66 @@
+package jwk
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// ErrClosed is returned by the lookups, Watch and RunRefresher once Close has been called
+var ErrClosed = errors.New("JSONWebKeys is closed")
+
+// Close cancels the in-flight fetches, stops the refreshers and ends the Watch channels, waiting for them to
+// return. The lookups fail with ErrClosed from then on. Closing again is a no-op
+func (j *JSONWebKeys) Close() error {
+	j.closeMutex.Lock()
+	if j.closed != 0 {
+		j.closeMutex.Unlock()
+		return nil
+	}
+	atomic.StoreInt32(&j.closed, 1)
+	j.closeMutex.Unlock()
+
+	j.lifecycle()
+	j.cancelLifecycle()
+	j.workers.Wait()
+	return nil
+}
+
+// isClosed tells whether Close has been called
+func (j *JSONWebKeys) isClosed() bool {
+	return atomic.LoadInt32(&j.closed) != 0
+}
+
+// startWorker registers a background goroutine that Close waits for, returning false when already closed.
+// The goroutine must call j.workers.Done when returning
+func (j *JSONWebKeys) startWorker() bool {
+	j.closeMutex.Lock()
+	defer j.closeMutex.Unlock()
+	if j.closed != 0 {
+		return false
+	}
+	j.workers.Add(1)
+	return true
+}
+
+// lifecycle returns a context done once Close is called
+func (j *JSONWebKeys) lifecycle() context.Context {
+	j.lifecycleOnce.Do(func() {
+		j.lifecycleCtx, j.cancelLifecycle = context.WithCancel(context.Background())
+	})
+	return j.lifecycleCtx
+}
+
+// withLifecycle derives a context from ctx that is also done once Close is called
+func (j *JSONWebKeys) withLifecycle(ctx context.Context) (context.Context, context.CancelFunc) {
+	lifecycle := j.lifecycle()
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-lifecycle.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
@@ -0,0 +1,73 @@
+package jwk
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClose(t *testing.T) {
+	server, _ := newTestJWKSServer(t, "", 0)
+	defer server.Close()
+
+	j := &JSONWebKeys{JWKURL: server.URL}
+	refresher := make(chan error, 1)
+	go func() { refresher <- j.RunRefresher(context.Background()) }()
+	events, err := j.Watch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-refresher:
+		if err != ErrClosed {
+			t.Fatalf("expecting the refresher to return %v, got %v", ErrClosed, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expecting the refresher to be stopped")
+	}
+	for range events {
+	}
+
+	if _, err := j.GetKeys(); err != ErrClosed {
+		t.Fatalf("expecting %v, got %v", ErrClosed, err)
+	}
+	if _, err := j.Watch(context.Background()); err != ErrClosed {
+		t.Fatalf("expecting %v, got %v", ErrClosed, err)
+	}
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCloseCancelsFetches(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	j := &JSONWebKeys{JWKURL: server.URL, Client: &http.Client{}}
+	fetched := make(chan error, 1)
+	go func() {
+		_, err := j.GetKeys()
+		fetched <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	j.Close()
+
+	select {
+	case err := <-fetched:
+		if err == nil {
+			t.Fatal("expecting the fetch to fail")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expecting the in-flight fetch to be cancelled")
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/serjlee/jwk-go"
+)
+
+// runConvert translates PEM/DER certificates and public keys to JWK/JWKS documents and back
+func runConvert(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: jwkctl convert [flags] <file|->")
+		fs.PrintDefaults()
+	}
+	to := fs.String("to", "", "output format: jwk, jwks, pem or der; defaults to pem for JSON input and jwks otherwise")
+	kid := fs.String("kid", "", "kid of the converted keys, defaults to their thumbprint")
+	alg := fs.String("alg", "", "alg of the converted keys")
+	use := fs.String("use", "", "use of the converted keys")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expecting exactly one input")
+	}
+	input, err := readInput(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	isJSON := len(bytes.TrimSpace(input)) > 0 && bytes.TrimSpace(input)[0] == '{'
+	if *to == "" {
+		*to = "jwks"
+		if isJSON {
+			*to = "pem"
+		}
+	}
+
+	var keys []jwk.Key
+	if isJSON {
+		keys, err = decodeJWKs(input)
+	} else {
+		keys, err = decodeKeys(input)
+	}
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("no keys found in the input")
+	}
+	for i := range keys {
+		if *alg != "" {
+			keys[i].Alg = *alg
+		}
+		if *use != "" {
+			keys[i].Use = *use
+		}
+		if *kid != "" {
+			keys[i].Kid = *kid
+		} else if keys[i].Kid == "" {
+			if keys[i].Kid, err = keys[i].Thumbprint(); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch *to {
+	case "jwks":
+		return printJSON(out, keys)
+	case "jwk":
+		if len(keys) != 1 {
+			return fmt.Errorf("expecting a single key for jwk output, found %d: use jwks", len(keys))
+		}
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(keys[0])
+	case "pem":
+		return printPEM(out, keys)
+	case "der":
+		if len(keys) != 1 {
+			return fmt.Errorf("expecting a single key for der output, found %d: use pem", len(keys))
+		}
+		der, err := keyDER(keys[0])
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(der)
+		return err
+	default:
+		return fmt.Errorf("unknown format %q", *to)
+	}
+}
+
+// readInput reads the given file, or stdin when it's "-"
+func readInput(arg string) ([]byte, error) {
+	if arg == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(arg)
+}
+
+// decodeJWKs parses either a single JWK or a JWKS document
+func decodeJWKs(input []byte) ([]jwk.Key, error) {
+	doc := struct {
+		Keys *[]jwk.Key `json:"keys"`
+	}{}
+	if err := json.Unmarshal(input, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Keys != nil {
+		return *doc.Keys, nil
+	}
+	key := jwk.Key{}
+	if err := json.Unmarshal(input, &key); err != nil {
+		return nil, err
+	}
+	return []jwk.Key{key}, nil
+}
+
+// decodeKeys parses PEM blocks, or a single DER certificate or public key
+func decodeKeys(input []byte) ([]jwk.Key, error) {
+	if !bytes.Contains(input, []byte("-----BEGIN")) {
+		key, err := decodeDER(input)
+		if err != nil {
+			return nil, err
+		}
+		return []jwk.Key{key}, nil
+	}
+
+	keys := []jwk.Key{}
+	for {
+		var block *pem.Block
+		block, input = pem.Decode(input)
+		if block == nil {
+			return keys, nil
+		}
+		var key jwk.Key
+		var err error
+		switch block.Type {
+		case "CERTIFICATE", "PUBLIC KEY":
+			key, err = decodeDER(block.Bytes)
+		case "RSA PUBLIC KEY":
+			var pub interface{}
+			if pub, err = x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+				key, err = jwk.FromPublicKey(pub)
+			}
+		default:
+			err = fmt.Errorf("unsupported PEM block %q", block.Type)
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+}
+
+// decodeDER parses a DER certificate or PKIX public key
+func decodeDER(der []byte) (jwk.Key, error) {
+	if cert, err := x509.ParseCertificate(der); err == nil {
+		return jwk.FromCertificate(cert)
+	}
+	pub, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return jwk.Key{}, fmt.Errorf("input is neither a certificate nor a public key")
+	}
+	return jwk.FromPublicKey(pub)
+}
+
+// keyDER returns the certificate of the key when present, its PKIX public key otherwise
+func keyDER(k jwk.Key) ([]byte, error) {
+	if len(k.X5c) > 0 {
+		return base64.StdEncoding.DecodeString(k.X5c[0])
+	}
+	pub, err := k.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return x509.MarshalPKIXPublicKey(pub)
+}
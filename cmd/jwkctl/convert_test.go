@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jwkctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// JWKS to PEM certificates
+	pemOut := &bytes.Buffer{}
+	if err := runConvert([]string{"../../testdata/jwks.json"}, pemOut); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(pemOut.String(), "-----BEGIN CERTIFICATE-----") {
+		t.Fatalf("expecting a certificate, got:\n%s", pemOut)
+	}
+	pemPath := filepath.Join(dir, "cert.pem")
+	if err := ioutil.WriteFile(pemPath, pemOut.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// and back to a JWK, keeping the certificate
+	jwkOut := &bytes.Buffer{}
+	if err := runConvert([]string{"-to", "jwk", "-use", "sig", pemPath}, jwkOut); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := decodeJWKs(jwkOut.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	original, err := readJWKSFile("../../testdata/jwks.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0].N != original[0].N || keys[0].PEM() != original[0].PEM() || keys[0].Use != "sig" {
+		t.Fatalf("unexpected round trip result:\n%s", jwkOut)
+	}
+
+	// a bare JWK to a DER public key
+	keys[0].X5c = nil
+	jwkPath := filepath.Join(dir, "key.json")
+	if err := writeJWKSFile(jwkPath, keys); err != nil {
+		t.Fatal(err)
+	}
+	derOut := &bytes.Buffer{}
+	if err := runConvert([]string{"-to", "der", jwkPath}, derOut); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := x509.ParsePKIXPublicKey(derOut.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	// and the DER back to a JWKS with a thumbprint kid
+	derPath := filepath.Join(dir, "key.der")
+	if err := ioutil.WriteFile(derPath, derOut.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	jwksOut := &bytes.Buffer{}
+	if err := runConvert([]string{derPath}, jwksOut); err != nil {
+		t.Fatal(err)
+	}
+	thumbprint, _ := keys[0].Thumbprint()
+	if !strings.Contains(jwksOut.String(), `"kid": "`+thumbprint+`"`) {
+		t.Fatalf("expecting the thumbprint as kid, got:\n%s", jwksOut)
+	}
+}
+
+func TestConvertInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jwkctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "key.pem")
+	block := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("secret")})
+	if err := ioutil.WriteFile(path, block, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runConvert([]string{path}, &bytes.Buffer{}); err == nil {
+		t.Fatal("expecting an error for a private key")
+	}
+}
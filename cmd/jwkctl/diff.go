@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/serjlee/jwk-go"
+)
+
+// errDifferent is returned by diff with -exit-code when the sources differ
+var errDifferent = errors.New("key sets differ")
+
+// runDiff compares two JWKS sources, reporting added, removed and changed kids
+func runDiff(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: jwkctl diff [flags] <old url|file> <new url|file>")
+		fs.PrintDefaults()
+	}
+	exitCode := fs.Bool("exit-code", false, "exit with status 1 when the key sets differ")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("expecting exactly two sources")
+	}
+
+	oldCerts, err := loadCerts(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	newCerts, err := loadCerts(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	added, removed, changed := jwk.Diff(oldCerts, newCerts)
+	printDiff(out, added, removed, changed)
+	if *exitCode && len(added)+len(removed)+len(changed) > 0 {
+		return errDifferent
+	}
+	return nil
+}
+
+// runWatch polls a JWKS URL, printing rotation events and optionally running a command on each of them
+func runWatch(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	src := addSourceFlags(fs)
+	interval := fs.Duration("interval", time.Minute, "polling interval")
+	command := fs.String("exec", "", "shell command run on every rotation, with the JWKCTL_ADDED, JWKCTL_REMOVED and JWKCTL_CHANGED kids in its environment")
+	count := fs.Int("count", 0, "stop after the given number of polls, 0 polls forever")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var previous *jwk.Certs
+	for poll := 1; ; poll++ {
+		// a new instance for every poll, so that cache headers can't hide a rotation
+		j, err := src.keys()
+		if err != nil {
+			return err
+		}
+		certs, err := j.GetKeys()
+		if err != nil {
+			fmt.Fprintf(out, "%s fetch failed: %v\n", time.Now().Format(time.RFC3339), err)
+		} else {
+			if previous == nil {
+				fmt.Fprintf(out, "%s watching %d keys\n", time.Now().Format(time.RFC3339), len(certs.Keys))
+			} else if added, removed, changed := jwk.Diff(previous, certs); len(added)+len(removed)+len(changed) > 0 {
+				fmt.Fprintf(out, "%s key set changed\n", time.Now().Format(time.RFC3339))
+				printDiff(out, added, removed, changed)
+				if *command != "" {
+					if err := runHook(*command, out, added, removed, changed); err != nil {
+						fmt.Fprintf(out, "exec failed: %v\n", err)
+					}
+				}
+			}
+			previous = certs
+		}
+
+		if *count > 0 && poll >= *count {
+			return nil
+		}
+		time.Sleep(*interval)
+	}
+}
+
+// loadCerts reads the keys of a JWKS from a URL, with the library parsing logic, or from a file
+func loadCerts(arg string) (*jwk.Certs, error) {
+	if strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://") {
+		return (&jwk.JSONWebKeys{JWKURL: arg}).GetKeys()
+	}
+	keys, err := readJWKSFile(arg)
+	if err != nil {
+		return nil, err
+	}
+	certs := &jwk.Certs{Keys: map[string]jwk.Key{}}
+	for _, k := range keys {
+		certs.Keys[k.Kid] = k
+	}
+	return certs, nil
+}
+
+// printDiff writes a line for each added (+), removed (-) and changed (~) key
+func printDiff(out io.Writer, added, removed, changed []jwk.Key) {
+	for _, k := range added {
+		fmt.Fprintf(out, "+ %s\n", k.Kid)
+	}
+	for _, k := range removed {
+		fmt.Fprintf(out, "- %s\n", k.Kid)
+	}
+	for _, k := range changed {
+		fmt.Fprintf(out, "~ %s\n", k.Kid)
+	}
+}
+
+// runHook runs the watch -exec command, passing the changed kids through the environment
+func runHook(command string, out io.Writer, added, removed, changed []jwk.Key) error {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdout = out
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"JWKCTL_ADDED="+kids(added),
+		"JWKCTL_REMOVED="+kids(removed),
+		"JWKCTL_CHANGED="+kids(changed),
+	)
+	return cmd.Run()
+}
+
+// kids joins the kids of the given keys with spaces
+func kids(keys []jwk.Key) string {
+	ids := make([]string, len(keys))
+	for i, k := range keys {
+		ids[i] = k.Kid
+	}
+	return strings.Join(ids, " ")
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/serjlee/jwk-go"
+)
+
+func TestDiff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jwkctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	oldKeys := []jwk.Key{
+		{Kty: "RSA", Kid: "kept", N: "AQAB", E: "AQAB"},
+		{Kty: "RSA", Kid: "removed", N: "AQAB", E: "AQAB"},
+		{Kty: "RSA", Kid: "changed", N: "AQAB", E: "AQAB"},
+	}
+	newKeys := []jwk.Key{
+		{Kty: "RSA", Kid: "kept", N: "AQAB", E: "AQAB"},
+		{Kty: "RSA", Kid: "changed", N: "AQAC", E: "AQAB"},
+		{Kty: "RSA", Kid: "added", N: "AQAB", E: "AQAB"},
+	}
+	oldPath, newPath := filepath.Join(dir, "old.json"), filepath.Join(dir, "new.json")
+	if err := writeJWKSFile(oldPath, oldKeys); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeJWKSFile(newPath, newKeys); err != nil {
+		t.Fatal(err)
+	}
+
+	out := &bytes.Buffer{}
+	if err := runDiff([]string{"-exit-code", oldPath, newPath}, out); err != errDifferent {
+		t.Fatalf("expecting errDifferent, got %v", err)
+	}
+	if out.String() != "+ added\n- removed\n~ changed\n" {
+		t.Fatalf("unexpected diff:\n%s", out)
+	}
+
+	out.Reset()
+	if err := runDiff([]string{"-exit-code", oldPath, oldPath}, out); err != nil || out.Len() != 0 {
+		t.Fatalf("expecting no differences, got %v:\n%s", err, out)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	body, err := ioutil.ReadFile("../../testdata/jwks.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := jwksDocument{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		t.Fatal(err)
+	}
+	rotated := doc.Keys[0]
+	rotated.Kid = "rotated"
+
+	var polls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys := doc.Keys
+		if atomic.AddInt32(&polls, 1) > 1 {
+			keys = []jwk.Key{rotated}
+		}
+		json.NewEncoder(w).Encode(jwksDocument{keys})
+	}))
+	defer server.Close()
+
+	out := &bytes.Buffer{}
+	args := []string{"-url", server.URL, "-interval", "1ms", "-count", "2", "-exec", `echo "hook: $JWKCTL_ADDED"`}
+	if err := runWatch(args, out); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"watching 1 keys", "key set changed", "+ rotated", "- " + doc.Keys[0].Kid, "hook: rotated"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("output does not contain %q:\n%s", expected, out)
+		}
+	}
+}
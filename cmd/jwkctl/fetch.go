@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/serjlee/jwk-go"
+)
+
+// runFetch downloads a JWKS and prints its keys as a table, JSON or PEM
+func runFetch(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("fetch", flag.ContinueOnError)
+	src := addSourceFlags(fs)
+	format := fs.String("format", "table", "output format: table, json or pem")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	j, err := src.keys()
+	if err != nil {
+		return err
+	}
+	certs, err := j.GetKeys()
+	if err != nil {
+		return err
+	}
+	keys := certs.ToSlice()
+
+	switch *format {
+	case "table":
+		return printTable(out, keys)
+	case "json":
+		return printJSON(out, keys)
+	case "pem":
+		return printPEM(out, keys)
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+}
+
+// printTable writes a summary line for each key
+func printTable(out io.Writer, keys []jwk.Key) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KID\tKTY\tALG\tUSE\tX5C")
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\n", k.Kid, k.Kty, k.Alg, k.Use, len(k.X5c))
+	}
+	return w.Flush()
+}
+
+// printJSON writes the keys as an indented JWKS document
+func printJSON(out io.Writer, keys []jwk.Key) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(jwksDocument{keys})
+}
+
+// printPEM writes the certificate of each key, or its bare public key when it has no x5c
+func printPEM(out io.Writer, keys []jwk.Key) error {
+	for _, k := range keys {
+		fmt.Fprintf(out, "# kid: %s\n", k.Kid)
+		if len(k.X5c) > 0 {
+			fmt.Fprintln(out, k.PEM())
+			continue
+		}
+		der, err := keyDER(k)
+		if err != nil {
+			return err
+		}
+		if err := pem.Encode(out, &pem.Block{Type: "PUBLIC KEY", Bytes: der}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
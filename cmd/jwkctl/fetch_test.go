@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newJWKSServer(t *testing.T) *httptest.Server {
+	body, err := ioutil.ReadFile("../../testdata/jwks.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+}
+
+func TestFetchFormats(t *testing.T) {
+	server := newJWKSServer(t)
+	defer server.Close()
+
+	expected := map[string]string{
+		"table": "QzQ4QzExMzNENkJCMThDNjNCN0ZEQjQwQkEwNUFFMzY1NDU5QzcxNA  RSA",
+		"json":  `"kid": "QzQ4QzExMzNENkJCMThDNjNCN0ZEQjQwQkEwNUFFMzY1NDU5QzcxNA"`,
+		"pem":   "-----BEGIN CERTIFICATE-----",
+	}
+	for format, want := range expected {
+		out := &bytes.Buffer{}
+		if err := runFetch([]string{"-url", server.URL, "-format", format}, out); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("%s output does not contain %q:\n%s", format, want, out)
+		}
+	}
+}
+
+func TestFetchRequiresSource(t *testing.T) {
+	if err := runFetch(nil, &bytes.Buffer{}); err == nil {
+		t.Fatal("expecting an error without -url or -issuer")
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/serjlee/jwk-go"
+)
+
+// keyFlags holds the flags describing the key to generate and how to emit it
+type keyFlags struct {
+	keyType string
+	bits    int
+	curve   string
+	use     string
+	format  string
+	out     string
+	kid     string
+}
+
+// addKeyFlags registers the key generation flags on the given set
+func addKeyFlags(fs *flag.FlagSet) *keyFlags {
+	k := &keyFlags{}
+	fs.StringVar(&k.keyType, "type", "rsa", "key type: rsa, ec or ed25519")
+	fs.IntVar(&k.bits, "bits", 2048, "RSA key size")
+	fs.StringVar(&k.curve, "curve", "P-256", "EC curve: P-256, P-384 or P-521")
+	fs.StringVar(&k.use, "use", "sig", "use member of the generated key")
+	fs.StringVar(&k.format, "format", "jwk", "private key output format: jwk or pem")
+	fs.StringVar(&k.out, "out", "", "file to write the private key to, stdout when empty")
+	fs.StringVar(&k.kid, "kid", "thumbprint", "kid scheme: thumbprint, uuid, date or sequential")
+	return k
+}
+
+// runGenerate generates a keypair, emits its private key and optionally appends its public key to a JWKS file
+func runGenerate(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	kf := addKeyFlags(fs)
+	jwksPath := fs.String("jwks", "", "JWKS file to append the public key to, created when missing")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var keys []jwk.Key
+	if *jwksPath != "" {
+		var err error
+		keys, err = readJWKSFile(*jwksPath)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	key, public, err := kf.generate(keys)
+	if err != nil {
+		return err
+	}
+	if *jwksPath != "" {
+		if err := writeJWKSFile(*jwksPath, append(keys, public)); err != nil {
+			return err
+		}
+	}
+	return kf.emit(out, key, public)
+}
+
+// runRotate generates a keypair and appends its public key to an existing JWKS file, retiring the oldest keys
+func runRotate(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("rotate", flag.ContinueOnError)
+	kf := addKeyFlags(fs)
+	jwksPath := fs.String("jwks", "", "existing JWKS file to append the public key to")
+	keep := fs.Int("keep", 0, "maximum number of keys left in the JWKS, oldest are removed first; 0 keeps all")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *jwksPath == "" {
+		return fmt.Errorf("-jwks is required")
+	}
+
+	keys, err := readJWKSFile(*jwksPath)
+	if err != nil {
+		return err
+	}
+	key, public, err := kf.generate(keys)
+	if err != nil {
+		return err
+	}
+	keys = append(keys, public)
+	if *keep > 0 && len(keys) > *keep {
+		keys = keys[len(keys)-*keep:]
+	}
+	if err := writeJWKSFile(*jwksPath, keys); err != nil {
+		return err
+	}
+	return kf.emit(out, key, public)
+}
+
+// kidStrategy maps the kid flag to the scheme naming generated keys
+func (kf *keyFlags) kidStrategy() (jwk.KidStrategy, error) {
+	switch kf.kid {
+	case "thumbprint":
+		return jwk.ThumbprintKid{}, nil
+	case "uuid":
+		return jwk.UUIDKid{}, nil
+	case "date":
+		return jwk.DateKid{}, nil
+	case "sequential":
+		return jwk.SequentialKid{Prefix: "key-"}, nil
+	default:
+		return nil, fmt.Errorf("unknown kid scheme %q", kf.kid)
+	}
+}
+
+// generate creates a new private key, returning it along with its public JWK named after the existing keys
+func (kf *keyFlags) generate(existing []jwk.Key) (crypto.Signer, jwk.Key, error) {
+	strategy, err := kf.kidStrategy()
+	if err != nil {
+		return nil, jwk.Key{}, err
+	}
+	var key crypto.Signer
+	var alg string
+	switch kf.keyType {
+	case "rsa":
+		key, err = rsa.GenerateKey(rand.Reader, kf.bits)
+		alg = "RS256"
+	case "ec":
+		var curve elliptic.Curve
+		switch kf.curve {
+		case "P-256":
+			curve, alg = elliptic.P256(), "ES256"
+		case "P-384":
+			curve, alg = elliptic.P384(), "ES384"
+		case "P-521":
+			curve, alg = elliptic.P521(), "ES512"
+		default:
+			return nil, jwk.Key{}, fmt.Errorf("unsupported curve %q", kf.curve)
+		}
+		key, err = ecdsa.GenerateKey(curve, rand.Reader)
+	case "ed25519":
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+		alg = "EdDSA"
+	default:
+		return nil, jwk.Key{}, fmt.Errorf("unsupported key type %q", kf.keyType)
+	}
+	if err != nil {
+		return nil, jwk.Key{}, err
+	}
+
+	public, err := jwk.FromPublicKey(key.Public())
+	if err != nil {
+		return nil, jwk.Key{}, err
+	}
+	public.Alg = alg
+	public.Use = kf.use
+	public.Kid, err = strategy.Kid(public, existing)
+	if err != nil {
+		return nil, jwk.Key{}, err
+	}
+	if err := public.Validate(); err != nil {
+		return nil, jwk.Key{}, err
+	}
+	return key, public, nil
+}
+
+// emit writes the private key in the requested format
+func (kf *keyFlags) emit(out io.Writer, key crypto.Signer, public jwk.Key) error {
+	var encoded []byte
+	switch kf.format {
+	case "jwk":
+		var err error
+		encoded, err = json.MarshalIndent(jose.JSONWebKey{
+			Key:       key,
+			KeyID:     public.Kid,
+			Algorithm: public.Alg,
+			Use:       public.Use,
+		}, "", "  ")
+		if err != nil {
+			return err
+		}
+		encoded = append(encoded, '\n')
+	case "pem":
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return err
+		}
+		encoded = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	default:
+		return fmt.Errorf("unknown format %q", kf.format)
+	}
+
+	if kf.out == "" {
+		_, err := out.Write(encoded)
+		return err
+	}
+	return os.WriteFile(kf.out, encoded, 0600)
+}
+
+// readJWKSFile reads the keys of a JWKS document from disk
+func readJWKSFile(path string) ([]jwk.Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc := jwksDocument{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %v", path, err)
+	}
+	return doc.Keys, nil
+}
+
+// writeJWKSFile writes the given keys as an indented JWKS document
+func writeJWKSFile(path string, keys []jwk.Key) error {
+	encoded, err := json.MarshalIndent(jwksDocument{keys}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(encoded, '\n'), 0644)
+}
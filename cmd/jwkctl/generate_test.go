@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jwkctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	jwksPath := filepath.Join(dir, "jwks.json")
+
+	for _, keyType := range []string{"rsa", "ec", "ed25519"} {
+		out := &bytes.Buffer{}
+		if err := runGenerate([]string{"-type", keyType, "-jwks", jwksPath}, out); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out.String(), `"d":`) {
+			t.Errorf("expecting a private %s JWK, got:\n%s", keyType, out)
+		}
+	}
+
+	keys, err := readJWKSFile(jwksPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 3 {
+		t.Fatalf("expecting 3 keys, got %d", len(keys))
+	}
+	for _, k := range keys {
+		thumbprint, err := k.Thumbprint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if k.Kid != thumbprint {
+			t.Errorf("expecting the thumbprint as kid, got %s", k.Kid)
+		}
+	}
+}
+
+func TestRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jwkctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	jwksPath := filepath.Join(dir, "jwks.json")
+
+	if err := runRotate([]string{"-jwks", jwksPath}, &bytes.Buffer{}); err == nil {
+		t.Fatal("expecting an error for a missing JWKS file")
+	}
+	if err := writeJWKSFile(jwksPath, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var lastKid string
+	for i := 0; i < 3; i++ {
+		out := &bytes.Buffer{}
+		if err := runRotate([]string{"-jwks", jwksPath, "-type", "ec", "-format", "pem", "-keep", "2"}, out); err != nil {
+			t.Fatal(err)
+		}
+		if block, _ := pem.Decode(out.Bytes()); block == nil || block.Type != "PRIVATE KEY" {
+			t.Fatalf("expecting a PEM private key, got:\n%s", out)
+		}
+		keys, err := readJWKSFile(jwksPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lastKid = keys[len(keys)-1].Kid
+	}
+
+	keys, err := readJWKSFile(jwksPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[1].Kid != lastKid {
+		t.Fatalf("expecting the 2 most recent keys, got %v", keys)
+	}
+}
+
+func TestGenerateKidScheme(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jwkctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	jwksPath := filepath.Join(dir, "jwks.json")
+
+	if err := runGenerate([]string{"-kid", "serial"}, &bytes.Buffer{}); err == nil {
+		t.Fatal("expecting an error for an unknown kid scheme")
+	}
+	if err := runGenerate([]string{"-type", "ec", "-kid", "sequential", "-jwks", jwksPath}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := runRotate([]string{"-type", "ec", "-kid", "sequential", "-jwks", jwksPath, "-keep", "2"}, &bytes.Buffer{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keys, err := readJWKSFile(jwksPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0].Kid != "key-3" || keys[1].Kid != "key-4" {
+		t.Fatalf("expecting sequential kids key-3 and key-4, got %v", keys)
+	}
+}
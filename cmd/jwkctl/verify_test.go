@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+func TestVerify(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"keys":[{"kty":"RSA","use":"sig","alg":"RS256","kid":"k1","n":"%s","e":"%s"}]}`,
+			base64.RawURLEncoding.EncodeToString(privateKey.N.Bytes()),
+			base64.RawURLEncoding.EncodeToString(big.NewInt(int64(privateKey.E)).Bytes()))
+	}))
+	defer server.Close()
+
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: privateKey},
+		(&jose.SignerOptions{}).WithHeader("kid", "k1"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := jwt.Signed(signer).Claims(jwt.Claims{
+		Subject:  "user",
+		Audience: jwt.Audience{"api"},
+		Expiry:   jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}).CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := &bytes.Buffer{}
+	if err := runVerify([]string{"-url", server.URL, "-audience", "api", raw}, out); err != nil {
+		t.Fatal(err, out)
+	}
+	if !strings.Contains(out.String(), `"sub": "user"`) || !strings.Contains(out.String(), "result: PASS") {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+
+	out.Reset()
+	err = runVerify([]string{"-url", server.URL, "-audience", "other", raw}, out)
+	if err != errVerificationFailed || !strings.Contains(out.String(), "result: FAIL") {
+		t.Fatalf("expecting a failure, got %v:\n%s", err, out)
+	}
+}
@@ -0,0 +1,170 @@
+package jwk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MergePolicy tells how a CompositeFetcher combines the documents of its sources
+type MergePolicy int
+
+const (
+	// FirstAvailable serves the document of the first source answering, the next ones being fallbacks
+	FirstAvailable MergePolicy = iota
+	// MergeKeys merges the keys of all the sources answering, a kid held by several of them being taken from
+	// the first one
+	MergeKeys
+	// OverrideKeys merges the keys of all the sources answering, a kid held by several of them being taken from
+	// the last one, i.e. for local overrides of the keys of a remote source
+	OverrideKeys
+)
+
+// CompositeFetcher stacks several sources, i.e. a remote URL first, a mounted file as fallback and an embedded
+// document last, combining their documents according to Policy. The source of each key is recorded in the
+// Provenance of the certs
+type CompositeFetcher struct {
+	Sources []Fetcher
+	Policy  MergePolicy
+}
+
+// Fetch implements Fetcher
+func (c *CompositeFetcher) Fetch(ctx context.Context) ([]byte, time.Duration, error) {
+	body, cacheAge, _, err := c.fetch(ctx)
+	return body, cacheAge, err
+}
+
+// String describes the source of the certs
+func (c *CompositeFetcher) String() string {
+	names := make([]string, len(c.Sources))
+	for i := range c.Sources {
+		names[i] = c.sourceName(i)
+	}
+	return "composite(" + strings.Join(names, ", ") + ")"
+}
+
+// fetch fetches the combined document, along with the source of each of its keys. The cache age is the shortest
+// one of the sources used
+func (c *CompositeFetcher) fetch(ctx context.Context) ([]byte, time.Duration, map[string]string, error) {
+	keys := []json.RawMessage{}
+	provenance := map[string]string{}
+	positions := map[string]int{}
+	cacheAge := time.Duration(0)
+	failures := []error{}
+	for i, source := range c.Sources {
+		body, age, err := source.Fetch(ctx)
+		if err == nil {
+			var doc struct {
+				Keys []json.RawMessage `json:"keys"`
+			}
+			if err = json.Unmarshal(body, &doc); err == nil && doc.Keys == nil {
+				err = errors.New("missing keys member")
+			}
+			for _, raw := range doc.Keys {
+				var key struct {
+					Kid string `json:"kid"`
+				}
+				if err := json.Unmarshal(raw, &key); err != nil {
+					// kept as is, so that parsing the combined document reports it in Report.Errors
+					keys = append(keys, raw)
+					continue
+				}
+				position, seen := positions[key.Kid]
+				switch {
+				case !seen || key.Kid == "":
+					positions[key.Kid] = len(keys)
+					keys = append(keys, raw)
+				case c.Policy == OverrideKeys:
+					keys[position] = raw
+				default:
+					continue
+				}
+				provenance[key.Kid] = c.sourceName(i)
+			}
+		}
+		if err != nil {
+			failures = append(failures, fmt.Errorf("%s: %w", c.sourceName(i), err))
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		if age > 0 && (cacheAge == 0 || age < cacheAge) {
+			cacheAge = age
+		}
+		if c.Policy == FirstAvailable {
+			break
+		}
+	}
+	if len(failures) == len(c.Sources) {
+		return nil, 0, nil, &SourcesError{Errs: failures}
+	}
+	delete(provenance, "")
+
+	body, err := json.Marshal(map[string][]json.RawMessage{"keys": keys})
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	return body, cacheAge, provenance, nil
+}
+
+// sourceName describes the source at the given index, with its String method if any
+func (c *CompositeFetcher) sourceName(i int) string {
+	if name := fetcherName(c.Sources[i]); name != "" {
+		return name
+	}
+	return fmt.Sprintf("source %d", i)
+}
+
+// URLFetcher fetches the JWKS document from a URL, honoring its cache-control header, so that it can be stacked
+// in a CompositeFetcher
+type URLFetcher struct {
+	URL string
+
+	// Client is the HTTP client of the requests. If unset it will default to a Client with a 10-seconds timeout
+	Client *http.Client
+
+	// client is Client or the default one, built once so that its connections are reused across fetches
+	client     *http.Client
+	clientOnce sync.Once
+}
+
+// Fetch implements Fetcher
+func (u *URLFetcher) Fetch(ctx context.Context) ([]byte, time.Duration, error) {
+	u.clientOnce.Do(func() {
+		u.client = (&JSONWebKeys{Client: u.Client}).httpClient()
+	})
+	return (&JSONWebKeys{Client: u.client, MaxDocumentSize: contextMaxDocumentSize(ctx)}).fetchJWKS(ctx, u.URL)
+}
+
+// String describes the source of the certs
+func (u *URLFetcher) String() string {
+	return u.URL
+}
+
+// StaticFetcher serves a fixed JWKS document, i.e. embedded at build time as the last resort of a
+// CompositeFetcher
+type StaticFetcher struct {
+	Document []byte
+
+	// Name describes the document in the provenance of the keys, static by default
+	Name string
+}
+
+// Fetch implements Fetcher
+func (s *StaticFetcher) Fetch(ctx context.Context) ([]byte, time.Duration, error) {
+	return s.Document, 0, nil
+}
+
+// String describes the source of the certs
+func (s *StaticFetcher) String() string {
+	if s.Name == "" {
+		return "static"
+	}
+	return s.Name
+}
@@ -0,0 +1,107 @@
+package jwk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCompositeFetcherFallback(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	fetcher := &CompositeFetcher{Sources: []Fetcher{
+		&URLFetcher{URL: down.URL},
+		&FileFetcher{Path: "testdata/jwks.json"},
+		&StaticFetcher{Document: []byte(`{"keys": []}`)},
+	}}
+	j := &JSONWebKeys{Fetcher: fetcher}
+	certs, err := j.GetKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := certs.Get(testKid); !ok || certs.Provenance[testKid] != "file://testdata/jwks.json" {
+		t.Fatalf("expecting the key of the file, got %+v", certs)
+	}
+	if certs.Source != "composite("+down.URL+", file://testdata/jwks.json, static)" {
+		t.Fatalf("unexpected source %q", certs.Source)
+	}
+}
+
+func TestCompositeFetcherMerge(t *testing.T) {
+	remoteKey, localKey, overridden := testKey, testKey, testKey
+	remoteKey.Kid, localKey.Kid, overridden.X5c = "remote", "local", nil
+	encode := func(keys ...Key) []byte {
+		encoded, err := json.Marshal(jwks{Keys: keys})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return encoded
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(encode(testKey, remoteKey))
+	}))
+	defer server.Close()
+	local := &StaticFetcher{Document: encode(overridden, localKey), Name: "embedded"}
+
+	tests := []struct {
+		policy  MergePolicy
+		source  string
+		withX5c bool
+	}{
+		{MergeKeys, server.URL, true},
+		{OverrideKeys, "embedded", false},
+	}
+	for _, test := range tests {
+		j := &JSONWebKeys{Fetcher: &CompositeFetcher{Sources: []Fetcher{&URLFetcher{URL: server.URL}, local},
+			Policy: test.policy}}
+		certs, err := j.GetKeys()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if certs.Len() != 3 || certs.Provenance["remote"] != server.URL || certs.Provenance["local"] != "embedded" {
+			t.Fatalf("expecting the keys of both sources, got %+v", certs)
+		}
+		key, _ := certs.Get(testKid)
+		if certs.Provenance[testKid] != test.source || (len(key.X5c) > 0) != test.withX5c {
+			t.Errorf("expecting the shared kid to come from %s, got %+v", test.source, key)
+		}
+	}
+}
+
+func TestCompositeFetcherMalformedKey(t *testing.T) {
+	valid, err := json.Marshal(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetcher := &CompositeFetcher{Sources: []Fetcher{
+		&StaticFetcher{Document: []byte(`{"keys": [{"kid": 42, "kty": "RSA"}, ` + string(valid) + `]}`)},
+	}}
+	certs, err := (&JSONWebKeys{Fetcher: fetcher}).GetKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := certs.Get(testKid); !ok || len(certs.Report.Errors) != 1 || certs.Report.Errors[0].Index != 0 {
+		t.Fatalf("expecting the malformed key to be reported, got %+v", certs.Report)
+	}
+}
+
+func TestURLFetcherReusesClient(t *testing.T) {
+	server, _ := newTestJWKSServer(t, "", 0)
+	defer server.Close()
+
+	fetcher := &URLFetcher{URL: server.URL}
+	for i := 0; i < 2; i++ {
+		if _, _, err := fetcher.Fetch(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first := fetcher.client
+	if _, _, err := fetcher.Fetch(context.Background()); err != nil || fetcher.client != first || first == nil {
+		t.Fatalf("expecting the default client to be built once, got %v", err)
+	}
+}
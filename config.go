@@ -0,0 +1,81 @@
+package jwk
+
+import (
+	"fmt"
+)
+
+// Config holds the settings that can be swapped at runtime with UpdateConfig
+type Config struct {
+	// JWKURL is the URL to the JWK definition, changing it drops the cached certs
+	JWKURL string
+
+	// MirrorURLs are tried in order when fetching from JWKURL fails
+	MirrorURLs []string
+
+	// Issuer is the expected iss claim of the tokens checked by VerifyToken, possibly templated. If empty the
+	// issuer is not checked
+	Issuer string
+
+	// Audiences are the accepted aud claims of the tokens checked by VerifyToken, any of them is enough.
+	// If empty the audience is not checked
+	Audiences []string
+
+	// AudienceMatch tells how Audiences are matched, any of them being enough by default
+	AudienceMatch AudienceMatch
+
+	// Algorithms restricts the token algs accepted while looking up the key of a token, all the supported
+	// ones when empty
+	Algorithms []string
+}
+
+// UpdateConfig atomically replaces the URL, issuer, audiences and algorithms in use, overriding the JWKURL,
+// Issuer and Audience fields from then on. It waits for the in-flight fetch, if any, including the uncached
+// ones made in NoCache mode or with CacheBypass, so that no fetch mixes the two configurations
+func (j *JSONWebKeys) UpdateConfig(config Config) {
+	j.fetchMutex.Lock()
+	defer j.fetchMutex.Unlock()
+
+	previous := j.config()
+	config.MirrorURLs = append([]string(nil), config.MirrorURLs...)
+	config.Audiences = append([]string(nil), config.Audiences...)
+	config.Algorithms = append([]string(nil), config.Algorithms...)
+
+	j.certsMutex.Lock()
+	j.updatedConfig = &config
+	if config.JWKURL != previous.JWKURL {
+		j.cachedCerts = nil
+	}
+	j.certsMutex.Unlock()
+	// the verified tokens were accepted by the previous configuration
+	j.verified.flush()
+}
+
+// config returns the settings in use: the ones set by UpdateConfig, or the ones from the fields
+func (j *JSONWebKeys) config() Config {
+	j.certsMutex.RLock()
+	updated := j.updatedConfig
+	j.certsMutex.RUnlock()
+	if updated != nil {
+		return *updated
+	}
+
+	config := Config{JWKURL: j.JWKURL, MirrorURLs: j.MirrorURLs, Issuer: j.Issuer}
+	if j.Audience != "" {
+		config.Audiences = []string{j.Audience}
+	}
+	config.AudienceMatch = j.AudienceMatch
+	return config
+}
+
+// checkAllowedAlg makes sure the given token alg is among the allowed ones
+func (c Config) checkAllowedAlg(alg string) error {
+	if len(c.Algorithms) == 0 {
+		return nil
+	}
+	for _, allowed := range c.Algorithms {
+		if alg == allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("token alg %q is not allowed", alg)
+}
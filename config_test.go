@@ -0,0 +1,97 @@
+package jwk
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+func TestUpdateConfigURL(t *testing.T) {
+	first, firstRequests := newTestJWKSServer(t, "", 0)
+	defer first.Close()
+	second, secondRequests := newTestJWKSServer(t, "", 0)
+	defer second.Close()
+
+	j := &JSONWebKeys{JWKURL: first.URL}
+	if _, err := j.GetKeys(); err != nil {
+		t.Fatal(err)
+	}
+	j.UpdateConfig(Config{JWKURL: second.URL})
+	if _, err := j.GetKeys(); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(firstRequests) != 1 || atomic.LoadInt32(secondRequests) != 1 {
+		t.Fatalf("expecting the new URL to be fetched right away, got %d and %d requests",
+			atomic.LoadInt32(firstRequests), atomic.LoadInt32(secondRequests))
+	}
+
+	// same URL, the cache is kept
+	j.UpdateConfig(Config{JWKURL: second.URL, Issuer: "https://issuer.example.com/"})
+	if _, err := j.GetKeys(); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(secondRequests) != 1 {
+		t.Fatalf("expecting the cache to be kept, got %d requests", atomic.LoadInt32(secondRequests))
+	}
+}
+
+func TestUpdateConfigUncached(t *testing.T) {
+	for _, noCache := range []bool{false, true} {
+		first, firstRequests := newTestJWKSServer(t, "", 0)
+		defer first.Close()
+		second, secondRequests := newTestJWKSServer(t, "", 0)
+		defer second.Close()
+
+		// fetchMutex is held the way UpdateConfig does while swapping the URL
+		j := &JSONWebKeys{JWKURL: first.URL, NoCache: noCache}
+		j.fetchMutex.Lock()
+		done := make(chan error, 1)
+		go func() {
+			var opts []CallOption
+			if !noCache {
+				opts = append(opts, CacheBypass())
+			}
+			_, err := j.GetKeys(opts...)
+			done <- err
+		}()
+		time.Sleep(50 * time.Millisecond)
+		j.certsMutex.Lock()
+		j.updatedConfig = &Config{JWKURL: second.URL}
+		j.certsMutex.Unlock()
+		j.fetchMutex.Unlock()
+
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+		if atomic.LoadInt32(firstRequests) != 0 || atomic.LoadInt32(secondRequests) != 1 {
+			t.Fatalf("expecting the uncached fetch to wait for the new URL, got %d and %d requests",
+				atomic.LoadInt32(firstRequests), atomic.LoadInt32(secondRequests))
+		}
+	}
+}
+
+func TestUpdateConfigClaims(t *testing.T) {
+	signer, j := newTestSigner(t, "test")
+	j.Audience = "api"
+	raw := signTestToken(t, signer, jwt.Claims{
+		Audience: jwt.Audience{"other"},
+		Expiry:   jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	})
+	if _, err := j.VerifyToken(context.Background(), raw); err == nil {
+		t.Fatal("expecting the audience to be rejected")
+	}
+
+	j.UpdateConfig(Config{Audiences: []string{"api", "other"}})
+	if _, err := j.VerifyToken(context.Background(), raw); err != nil {
+		t.Fatal(err)
+	}
+
+	j.UpdateConfig(Config{Algorithms: []string{"ES256"}})
+	if _, err := j.VerifyToken(context.Background(), raw); err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Fatalf("expecting RS256 not to be allowed, got %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package jwk
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// FromPublicKey builds a Key from an RSA, ECDSA, Ed25519 or ECDH (X25519 or NIST curve) public key.
+// Only the key members are set: Kid, Alg and Use are left to the caller
+func FromPublicKey(publicKey crypto.PublicKey) (Key, error) {
+	switch pub := publicKey.(type) {
+	case *rsa.PublicKey:
+		return Key{
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}, nil
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		return Key{
+			Kty: "EC",
+			Crv: curveName(pub.Curve),
+			X:   base64.RawURLEncoding.EncodeToString(padLeft(pub.X.Bytes(), size)),
+			Y:   base64.RawURLEncoding.EncodeToString(padLeft(pub.Y.Bytes(), size)),
+		}, nil
+	case ed25519.PublicKey:
+		return Key{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(pub),
+		}, nil
+	case *ecdh.PublicKey:
+		return fromECDHPublicKey(pub)
+	default:
+		return Key{}, fmt.Errorf("unsupported public key type %T", publicKey)
+	}
+}
+
+// fromECDHPublicKey builds an OKP Key from an X25519 public key, an EC one from a NIST curve public key
+func fromECDHPublicKey(pub *ecdh.PublicKey) (Key, error) {
+	crv := ""
+	switch pub.Curve() {
+	case ecdh.X25519():
+		return Key{
+			Kty: "OKP",
+			Crv: "X25519",
+			X:   base64.RawURLEncoding.EncodeToString(pub.Bytes()),
+		}, nil
+	case ecdh.P256():
+		crv = "P-256"
+	case ecdh.P384():
+		crv = "P-384"
+	case ecdh.P521():
+		crv = "P-521"
+	default:
+		return Key{}, fmt.Errorf("unsupported ECDH curve %v", pub.Curve())
+	}
+	// NIST curve public keys are encoded as uncompressed points: 0x04 || X || Y
+	point := pub.Bytes()[1:]
+	size := len(point) / 2
+	return Key{
+		Kty: "EC",
+		Crv: crv,
+		X:   base64.RawURLEncoding.EncodeToString(point[:size]),
+		Y:   base64.RawURLEncoding.EncodeToString(point[size:]),
+	}, nil
+}
+
+// FromCertificate builds a Key from the public key of the given certificate, keeping the certificate in X5c
+func FromCertificate(cert *x509.Certificate) (Key, error) {
+	key, err := FromPublicKey(cert.PublicKey)
+	if err != nil {
+		return key, err
+	}
+	key.X5c = []string{base64.StdEncoding.EncodeToString(cert.Raw)}
+	return key, nil
+}
+
+// Secret decodes the k member of an oct key, holding the secret of HMAC signatures
+func (k Key) Secret() ([]byte, error) {
+	if k.Kty != "oct" {
+		return nil, fmt.Errorf("kty %q holds no secret", k.Kty)
+	}
+	encoded := ""
+	if err := json.Unmarshal(k.Extra["k"], &encoded); err != nil || encoded == "" {
+		return nil, errors.New("missing member k")
+	}
+	secret, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("malformed k: %w", err)
+	}
+	return secret, nil
+}
+
+// verificationKey decodes the key as expected by go-jose: the secret for oct keys, the public key otherwise
+func (k Key) verificationKey() (interface{}, error) {
+	if k.Kty == "oct" {
+		return k.Secret()
+	}
+	return k.PublicKey()
+}
+
+// PublicKey decodes the key as an *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey or, for X25519 keys,
+// *ecdh.PublicKey depending on its type
+func (k Key) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		return k.rsaPublicKey()
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			custom, ok := customCurves[k.Crv]
+			if !ok {
+				return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+			}
+			curve = custom.curve
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("malformed x: %w", err)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("malformed y: %w", err)
+		}
+		pub := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return pub, nil
+	case "OKP":
+		if k.Crv != "Ed25519" && k.Crv != "X25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("malformed x: %w", err)
+		}
+		if k.Crv == "X25519" {
+			pub, err := ecdh.X25519().NewPublicKey(x)
+			if err != nil {
+				return nil, fmt.Errorf("invalid X25519 key: %w", err)
+			}
+			return pub, nil
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key size %d", len(x))
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// ECDHPublicKey decodes the key as an *ecdh.PublicKey, for the ECDH-ES key agreement: only X25519 OKP keys
+// and EC keys on the NIST curves qualify
+func (k Key) ECDHPublicKey() (*ecdh.PublicKey, error) {
+	publicKey, err := k.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	switch pub := publicKey.(type) {
+	case *ecdh.PublicKey:
+		return pub, nil
+	case *ecdsa.PublicKey:
+		if _, ok := customCurves[k.Crv]; !ok {
+			return pub.ECDH()
+		}
+	}
+	return nil, fmt.Errorf("%s key on curve %q can't be used for ECDH-ES", k.Kty, k.Crv)
+}
+
+// padLeft zero-pads b up to size bytes, as required for EC coordinates
+func padLeft(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	padded := make([]byte, size)
+	copy(padded[size-len(b):], b)
+	return padded
+}
@@ -0,0 +1,152 @@
+package jwk
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"reflect"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3"
+)
+
+func TestFromPublicKey(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		publicKey interface{}
+		kty, crv  string
+	}{
+		{testKey.RSA(), "RSA", ""},
+		{&ecKey.PublicKey, "EC", "P-384"},
+		{edKey, "OKP", "Ed25519"},
+	}
+	for _, test := range tests {
+		key, err := FromPublicKey(test.publicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key.Kty != test.kty || key.Crv != test.crv {
+			t.Errorf("unexpected kty/crv %s/%s for %T", key.Kty, key.Crv, test.publicKey)
+		}
+		decoded, err := key.PublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decoded, test.publicKey) {
+			t.Errorf("round trip mismatch for %T", test.publicKey)
+		}
+		// cross-check the encoding with go-jose
+		thumbprint, err := key.Thumbprint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err := (&jose.JSONWebKey{Key: test.publicKey}).Thumbprint(crypto.SHA256)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if thumbprint != base64.RawURLEncoding.EncodeToString(expected) {
+			t.Errorf("thumbprint mismatch for %T", test.publicKey)
+		}
+	}
+
+	if key, _ := FromPublicKey(testKey.RSA()); key.N != testKey.N || key.E != testKey.E {
+		t.Error("unexpected RSA members")
+	}
+	if _, err := FromPublicKey("not a key"); err == nil {
+		t.Error("expecting an error for an unsupported key")
+	}
+}
+
+func TestFromCertificate(t *testing.T) {
+	der, err := base64.StdEncoding.DecodeString(testX5c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := FromCertificate(cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.N != testKey.N || key.E != testKey.E || key.PEM() != testKey.PEM() {
+		t.Fatal("unexpected key from certificate")
+	}
+}
+
+// testX25519 is the x member of the X25519 public key from RFC 7748 section 6.1
+const testX25519 = "hSDwCYkwp1R0i33ctD73Wg2_Og0mOBr066SpjqqbTmo"
+
+func TestX25519(t *testing.T) {
+	key := Key{Kty: "OKP", Crv: "X25519", X: testX25519, Use: "enc", Alg: "ECDH-ES"}
+	pub, err := key.ECDHPublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pub.Curve() != ecdh.X25519() {
+		t.Fatalf("unexpected curve %v", pub.Curve())
+	}
+	converted, err := FromPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if converted.Kty != "OKP" || converted.Crv != "X25519" || converted.X != testX25519 {
+		t.Fatalf("unexpected key %+v", converted)
+	}
+
+	// NIST curve keys convert both ways, and are usable for ECDH-ES
+	privateKey, err := ecdh.P384().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := FromPublicKey(privateKey.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ecKey.Kty != "EC" || ecKey.Crv != "P-384" {
+		t.Fatalf("unexpected key %+v", ecKey)
+	}
+	ecdhKey, err := ecKey.ECDHPublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ecdhKey.Equal(privateKey.PublicKey()) {
+		t.Fatal("expecting the same ECDH public key back")
+	}
+
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	okp, _ := FromPublicKey(edKey.Public())
+	if _, err := okp.ECDHPublicKey(); err == nil {
+		t.Fatal("expecting Ed25519 keys to be refused for ECDH-ES")
+	}
+}
+
+func TestPublicKeyInvalid(t *testing.T) {
+	invalid := []Key{
+		{Kty: "oct"},
+		{Kty: "EC", Crv: "P-256", X: "AQ", Y: "AQ"},
+		{Kty: "EC", Crv: "secp256k1"},
+		{Kty: "OKP", Crv: "Ed25519", X: "AQ"},
+		{Kty: "OKP", Crv: "X25519", X: "AQ"},
+		{Kty: "RSA", N: "not base64!"},
+	}
+	for _, key := range invalid {
+		if _, err := key.PublicKey(); err == nil {
+			t.Errorf("expecting an error for %+v", key)
+		}
+	}
+}
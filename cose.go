@@ -0,0 +1,242 @@
+package jwk
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// COSE_Key labels and values, see https://www.rfc-editor.org/rfc/rfc9052#section-7
+// and https://www.rfc-editor.org/rfc/rfc9053
+const (
+	coseLabelKty    = 1
+	coseLabelKid    = 2
+	coseLabelAlg    = 3
+	coseLabelKeyOps = 4
+
+	// EC2 and OKP keys
+	coseLabelCrv = -1
+	coseLabelX   = -2
+	coseLabelY   = -3
+
+	// RSA keys, see https://www.rfc-editor.org/rfc/rfc8230
+	coseLabelN = -1
+	coseLabelE = -2
+
+	// symmetric keys
+	coseLabelK = -1
+)
+
+// coseKeyTypes maps the JWK key types to the COSE ones
+var coseKeyTypes = map[string]int{
+	"OKP": 1,
+	"EC":  2,
+	"RSA": 3,
+	"oct": 4,
+}
+
+// coseAlgs maps the JWS algorithms to the COSE ones
+var coseAlgs = map[string]int{
+	"HS256": 5,
+	"HS384": 6,
+	"HS512": 7,
+	"ES256": -7,
+	"EdDSA": -8,
+	"ES384": -35,
+	"ES512": -36,
+	"PS256": -37,
+	"PS384": -38,
+	"PS512": -39,
+	"RS256": -257,
+	"RS384": -258,
+	"RS512": -259,
+}
+
+// coseCurves maps the JWK curves to the COSE ones
+var coseCurves = map[string]int{
+	"P-256":   1,
+	"P-384":   2,
+	"P-521":   3,
+	"X25519":  4,
+	"Ed25519": 6,
+}
+
+// coseKeyOps maps the JWK key operations to the COSE ones
+var coseKeyOps = map[string]int{
+	"sign":       1,
+	"verify":     2,
+	"encrypt":    3,
+	"decrypt":    4,
+	"wrapKey":    5,
+	"unwrapKey":  6,
+	"deriveKey":  7,
+	"deriveBits": 8,
+}
+
+// COSEKey encodes the key as a deterministic CBOR COSE_Key structure (RFC 9052), e.g. to compare it with
+// a WebAuthn credential public key. Members with no COSE counterpart, such as use and x5c, are dropped, while
+// key types and curves with none, such as the ones added with RegisterCurve, are refused
+func (k Key) COSEKey() ([]byte, error) {
+	if err := k.Validate(); err != nil {
+		return nil, err
+	}
+	kty, ok := coseKeyTypes[k.Kty]
+	if !ok {
+		return nil, fmt.Errorf("kty %q has no COSE counterpart", k.Kty)
+	}
+	key := map[int]interface{}{coseLabelKty: kty}
+	if k.Kid != "" {
+		key[coseLabelKid] = []byte(k.Kid)
+	}
+	if k.Alg != "" {
+		alg, ok := coseAlgs[k.Alg]
+		if !ok {
+			return nil, fmt.Errorf("alg %q has no COSE counterpart", k.Alg)
+		}
+		key[coseLabelAlg] = alg
+	}
+	if len(k.KeyOps) > 0 {
+		ops := make([]int, len(k.KeyOps))
+		for i, op := range k.KeyOps {
+			if ops[i] = coseKeyOps[op]; ops[i] == 0 {
+				return nil, fmt.Errorf("key_ops value %q has no COSE counterpart", op)
+			}
+		}
+		key[coseLabelKeyOps] = ops
+	}
+
+	// members were already validated, so they can be safely decoded
+	decode := func(member string) []byte {
+		decoded, _ := base64.RawURLEncoding.DecodeString(member)
+		return decoded
+	}
+	switch k.Kty {
+	case "RSA":
+		key[coseLabelN] = decode(k.N)
+		key[coseLabelE] = decode(k.E)
+	case "oct":
+		key[coseLabelK], _ = k.Secret()
+	case "EC", "OKP":
+		crv, ok := coseCurves[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("curve %q has no COSE counterpart", k.Crv)
+		}
+		key[coseLabelCrv] = crv
+		key[coseLabelX] = decode(k.X)
+		if k.Kty == "EC" {
+			key[coseLabelY] = decode(k.Y)
+		}
+	}
+
+	mode, err := cbor.CoreDetEncOptions().EncMode()
+	if err != nil {
+		return nil, err
+	}
+	return mode.Marshal(key)
+}
+
+// FromCOSEKey decodes a CBOR COSE_Key structure (RFC 9052), such as a WebAuthn credential public key, as a Key
+func FromCOSEKey(data []byte) (Key, error) {
+	members := map[int]cbor.RawMessage{}
+	if err := cbor.Unmarshal(data, &members); err != nil {
+		return Key{}, fmt.Errorf("unable to decode COSE key: %w", err)
+	}
+
+	var kty int
+	if err := cbor.Unmarshal(members[coseLabelKty], &kty); err != nil {
+		return Key{}, fmt.Errorf("malformed COSE kty: %w", err)
+	}
+	key := Key{Kty: reverseLookup(coseKeyTypes, kty)}
+	if key.Kty == "" {
+		return Key{}, fmt.Errorf("unsupported COSE kty %d", kty)
+	}
+
+	if raw, ok := members[coseLabelKid]; ok {
+		var kid []byte
+		if err := cbor.Unmarshal(raw, &kid); err != nil {
+			return Key{}, fmt.Errorf("malformed COSE kid: %w", err)
+		}
+		key.Kid = string(kid)
+	}
+	if raw, ok := members[coseLabelAlg]; ok {
+		var alg int
+		if err := cbor.Unmarshal(raw, &alg); err != nil {
+			return Key{}, fmt.Errorf("malformed COSE alg: %w", err)
+		}
+		if key.Alg = reverseLookup(coseAlgs, alg); key.Alg == "" {
+			return Key{}, fmt.Errorf("unsupported COSE alg %d", alg)
+		}
+	}
+	if raw, ok := members[coseLabelKeyOps]; ok {
+		var ops []int
+		if err := cbor.Unmarshal(raw, &ops); err != nil {
+			return Key{}, fmt.Errorf("malformed COSE key_ops: %w", err)
+		}
+		for _, op := range ops {
+			name := reverseLookup(coseKeyOps, op)
+			if name == "" {
+				return Key{}, fmt.Errorf("unsupported COSE key_ops value %d", op)
+			}
+			key.KeyOps = append(key.KeyOps, name)
+		}
+	}
+
+	bytesMember := func(label int) (string, error) {
+		var value []byte
+		if err := cbor.Unmarshal(members[label], &value); err != nil {
+			return "", fmt.Errorf("malformed COSE member %d: %w", label, err)
+		}
+		return base64.RawURLEncoding.EncodeToString(value), nil
+	}
+	var err error
+	switch key.Kty {
+	case "RSA":
+		if key.N, err = bytesMember(coseLabelN); err != nil {
+			return Key{}, err
+		}
+		if key.E, err = bytesMember(coseLabelE); err != nil {
+			return Key{}, err
+		}
+	case "oct":
+		secret, err := bytesMember(coseLabelK)
+		if err != nil {
+			return Key{}, err
+		}
+		encoded, _ := json.Marshal(secret)
+		key.Extra = map[string]json.RawMessage{"k": encoded}
+	case "EC", "OKP":
+		var crv int
+		if err := cbor.Unmarshal(members[coseLabelCrv], &crv); err != nil {
+			return Key{}, fmt.Errorf("malformed COSE crv: %w", err)
+		}
+		if key.Crv = reverseLookup(coseCurves, crv); key.Crv == "" {
+			return Key{}, fmt.Errorf("unsupported COSE crv %d", crv)
+		}
+		if key.X, err = bytesMember(coseLabelX); err != nil {
+			return Key{}, err
+		}
+		if key.Kty == "EC" {
+			// compressed points, encoded as a bool y, are not supported
+			if key.Y, err = bytesMember(coseLabelY); err != nil {
+				return Key{}, err
+			}
+		}
+	}
+
+	if err := key.Validate(); err != nil {
+		return Key{}, err
+	}
+	return key, nil
+}
+
+// reverseLookup returns the name mapped to the given value, or an empty string
+func reverseLookup(names map[string]int, value int) string {
+	for name, v := range names {
+		if v == value {
+			return name
+		}
+	}
+	return ""
+}
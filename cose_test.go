@@ -0,0 +1,128 @@
+package jwk
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+func TestCOSEKey(t *testing.T) {
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := FromPublicKey(&ecdsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey.Kid, ecKey.Alg, ecKey.KeyOps = "ec", "ES256", []string{"verify"}
+
+	edPublicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edKey, err := FromPublicKey(edPublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edKey.Alg = "EdDSA"
+
+	rsaKey := Key{Kty: "RSA", Kid: testKid, Alg: "RS256", N: testKey.N, E: testKey.E}
+
+	for _, key := range []Key{ecKey, edKey, rsaKey} {
+		encoded, err := key.COSEKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := FromCOSEKey(encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(key, decoded) {
+			t.Errorf("round trip mismatch: %+v != %+v", key, decoded)
+		}
+	}
+
+	// check the labels of the EC2 key, as found in WebAuthn credential public keys
+	encoded, err := ecKey.COSEKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	members := map[int]interface{}{}
+	if err := cbor.Unmarshal(encoded, &members); err != nil {
+		t.Fatal(err)
+	}
+	if members[1] != uint64(2) || members[3] != int64(-7) || members[-1] != uint64(1) {
+		t.Fatalf("unexpected COSE members: %v", members)
+	}
+}
+
+func TestCOSEKeyInvalid(t *testing.T) {
+	if _, err := (Key{Kty: "RSA", N: testKey.N, E: testKey.E, Alg: "RSA-OAEP", Use: "enc"}).COSEKey(); err == nil {
+		t.Error("expecting an error for an alg with no COSE counterpart")
+	}
+	RegisterCurve("P-224", elliptic.P224(), "ES224", crypto.SHA256)
+	private, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	custom, err := FromPublicKey(private.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := custom.COSEKey(); err == nil || !strings.Contains(err.Error(), "COSE") {
+		t.Errorf("expecting an error for a curve with no COSE counterpart, got %v", err)
+	}
+
+	invalid := []map[int]interface{}{
+		{1: 4, 3: 5},
+		{1: 2, 3: -7, -1: 1, -2: []byte{1}, -3: true},
+		{1: 2, 3: -7, -1: 42, -2: []byte{1}, -3: []byte{1}},
+		{1: 3, 3: -65535, -1: []byte{1}, -2: []byte{1, 0, 1}},
+	}
+	for _, members := range invalid {
+		encoded, err := cbor.Marshal(members)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := FromCOSEKey(encoded); err == nil {
+			t.Errorf("expecting an error for %v", members)
+		}
+	}
+}
+
+func TestCOSEKeySymmetric(t *testing.T) {
+	secret := []byte("a secret of at least thirty-two bytes")
+	encoded, _ := json.Marshal(base64.RawURLEncoding.EncodeToString(secret))
+	key := Key{Kty: "oct", Kid: "hmac", Alg: "HS256", Extra: map[string]json.RawMessage{"k": encoded}}
+
+	cose, err := key.COSEKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	members := map[int]interface{}{}
+	if err := cbor.Unmarshal(cose, &members); err != nil {
+		t.Fatal(err)
+	}
+	if members[1] != uint64(4) || members[3] != uint64(5) || !bytes.Equal(members[-1].([]byte), secret) {
+		t.Fatalf("unexpected COSE members: %v", members)
+	}
+
+	decoded, err := FromCOSEKey(cose)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := decoded.Secret(); err != nil || !bytes.Equal(got, secret) || decoded.Alg != "HS256" {
+		t.Fatalf("unexpected round trip %+v", decoded)
+	}
+}
@@ -0,0 +1,85 @@
+package jwk
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// customCurve is an EC curve added with RegisterCurve
+type customCurve struct {
+	curve elliptic.Curve
+	alg   string
+	hash  crypto.Hash
+}
+
+// customCurves maps the crv names added with RegisterCurve to their curve
+var customCurves = map[string]customCurve{}
+
+// RegisterCurve adds an EC curve missing from the standard library, along with the JWS algorithm signing with
+// ECDSA over it and the hash it uses: keys on that curve can then be decoded, converted, validated and used to
+// verify signatures. It's not safe for concurrent use, so it's meant to be called from an init function, as done
+// by the opt-in github.com/serjlee/jwk-go/secp256k1 package for ES256K
+func RegisterCurve(crv string, curve elliptic.Curve, alg string, hash crypto.Hash) {
+	customCurves[crv] = customCurve{curve: curve, alg: alg, hash: hash}
+	algKeyTypes[alg] = "EC"
+	algCurves[alg] = crv
+}
+
+// curveName returns the crv name of the given curve, looking up the registered ones first
+func curveName(curve elliptic.Curve) string {
+	for name, custom := range customCurves {
+		if custom.curve == curve {
+			return name
+		}
+	}
+	return curve.Params().Name
+}
+
+// verifyCustomAlg checks the signature of the given compact JWS when alg is one added with RegisterCurve,
+// returning its payload. It returns ok false for the other algorithms, which are left to go-jose
+func verifyCustomAlg(raw, alg string, publicKey crypto.PublicKey) (payload []byte, ok bool, err error) {
+	var custom customCurve
+	for _, c := range customCurves {
+		if c.alg == alg {
+			custom, ok = c, true
+		}
+	}
+	if !ok {
+		return nil, false, nil
+	}
+
+	pub, isEC := publicKey.(*ecdsa.PublicKey)
+	if !isEC || pub.Curve != custom.curve {
+		return nil, true, fmt.Errorf("alg %q requires a key on its curve", alg)
+	}
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, true, errors.New("expecting a compact JWS")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	size := (custom.curve.Params().BitSize + 7) / 8
+	if err != nil || len(signature) != 2*size {
+		return nil, true, errors.New("malformed signature")
+	}
+	if !custom.hash.Available() {
+		return nil, true, fmt.Errorf("hash of alg %q is not available", alg)
+	}
+	h := custom.hash.New()
+	h.Write([]byte(parts[0] + "." + parts[1]))
+	r, s := new(big.Int).SetBytes(signature[:size]), new(big.Int).SetBytes(signature[size:])
+	if !ecdsa.Verify(pub, h.Sum(nil), r, s) {
+		return nil, true, errors.New("signature verification failed")
+	}
+
+	payload, err = base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, true, fmt.Errorf("malformed payload: %w", err)
+	}
+	return payload, true, nil
+}
@@ -0,0 +1,61 @@
+package jwk
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+)
+
+func TestRegisterCurve(t *testing.T) {
+	// P-224 is not supported by default, so it stands in for a curve missing from the standard library
+	RegisterCurve("P-224", elliptic.P224(), "ES224", crypto.SHA256)
+	privateKey, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := FromPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key.Alg, key.Use = "ES224", "sig"
+	if err := key.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if reason := acceptSigKey(key); reason != "" {
+		t.Fatalf("expecting the key to be accepted, got %q", reason)
+	}
+	publicKey, err := key.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"ES224"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"test"}`))
+	sum := sha256.Sum256([]byte(input))
+	r, s, err := ecdsa.Sign(rand.Reader, privateKey, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := make([]byte, 56)
+	r.FillBytes(signature[:28])
+	s.FillBytes(signature[28:])
+	raw := input + "." + base64.RawURLEncoding.EncodeToString(signature)
+
+	payload, ok, err := verifyCustomAlg(raw, "ES224", publicKey)
+	if !ok || err != nil || string(payload) != `{"sub":"test"}` {
+		t.Fatalf("unexpected verification result %q %v %v", payload, ok, err)
+	}
+	signature[0] ^= 1
+	tampered := input + "." + base64.RawURLEncoding.EncodeToString(signature)
+	if _, _, err := verifyCustomAlg(tampered, "ES224", publicKey); err == nil {
+		t.Fatal("expecting a tampered signature to be rejected")
+	}
+	if _, ok, _ := verifyCustomAlg(raw, "ES256", publicKey); ok {
+		t.Fatal("expecting standard algorithms to be left to go-jose")
+	}
+}
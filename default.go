@@ -0,0 +1,49 @@
+package jwk
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	// defaultKeys is the instance behind the package-level functions, set by SetDefault
+	defaultKeys      *JSONWebKeys
+	defaultKeysMutex sync.RWMutex
+)
+
+// errNoDefault is returned by the package-level functions when SetDefault has not been called
+var errNoDefault = errors.New("no default JSONWebKeys set, see SetDefault")
+
+// SetDefault sets the process-wide instance used by the package-level GetKey and VerifyToken, for services
+// that don't want to pass a JSONWebKeys around. It can be called again to replace it
+func SetDefault(j *JSONWebKeys) {
+	defaultKeysMutex.Lock()
+	defaultKeys = j
+	defaultKeysMutex.Unlock()
+}
+
+// Default returns the instance set by SetDefault, nil if none
+func Default() *JSONWebKeys {
+	defaultKeysMutex.RLock()
+	defer defaultKeysMutex.RUnlock()
+	return defaultKeys
+}
+
+// GetKey returns the key with the given kid from the default instance
+func GetKey(ctx context.Context, kid string, opts ...CallOption) (Key, error) {
+	j := Default()
+	if j == nil {
+		return Key{}, errNoDefault
+	}
+	return j.getKey(ctx, kid, opts...)
+}
+
+// VerifyToken verifies the given compact JWT with the default instance, see JSONWebKeys.VerifyToken
+func VerifyToken(ctx context.Context, raw string, opts ...CallOption) (map[string]interface{}, error) {
+	j := Default()
+	if j == nil {
+		return nil, errNoDefault
+	}
+	return j.VerifyToken(ctx, raw, opts...)
+}
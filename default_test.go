@@ -0,0 +1,31 @@
+package jwk
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+func TestDefault(t *testing.T) {
+	defer SetDefault(nil)
+
+	SetDefault(nil)
+	if _, err := GetKey(context.Background(), testKid); err != errNoDefault {
+		t.Fatalf("expecting %v, got %v", errNoDefault, err)
+	}
+
+	signer, j := newTestSigner(t, "test")
+	SetDefault(j)
+	if Default() != j {
+		t.Fatal("expecting the default instance to be returned")
+	}
+	if _, err := GetKey(context.Background(), "test"); err != nil {
+		t.Fatal(err)
+	}
+	raw := signTestToken(t, signer, jwt.Claims{Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour))})
+	if _, err := VerifyToken(context.Background(), raw); err != nil {
+		t.Fatal(err)
+	}
+}
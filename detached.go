@@ -0,0 +1,54 @@
+package jwk
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+)
+
+// VerifyDetachedJWS checks a compact JWS whose payload is detached (header..signature), as sent along with the
+// body of signed webhooks, against the key matching its header. Unencoded payloads, flagged with "b64": false per
+// RFC 7797, are supported. It returns the key that signed the payload. Errors are redacted, see RedactError
+func (j *JSONWebKeys) VerifyDetachedJWS(ctx context.Context, signature string, payload []byte) (Key, error) {
+	start := time.Now()
+	key, err := j.verifyDetachedJWS(ctx, signature, payload)
+	err = RedactError(err)
+	j.audit(ctx, start, signature, nil, err)
+	return key, err
+}
+
+// verifyDetachedJWS verifies the detached JWS, see VerifyDetachedJWS
+func (j *JSONWebKeys) verifyDetachedJWS(ctx context.Context, signature string, payload []byte) (Key, error) {
+	jws, err := jose.ParseDetached(signature, payload)
+	if err != nil {
+		return Key{}, fmt.Errorf("unable to parse detached signature: %w", err)
+	}
+	if len(jws.Signatures) != 1 {
+		return Key{}, errors.New("expecting a single signature")
+	}
+	header := jws.Signatures[0].Header
+	key, err := j.keyForHeader(ctx, header)
+	if err != nil {
+		return Key{}, err
+	}
+	verificationKey, err := key.verificationKey()
+	if err != nil {
+		return Key{}, fmt.Errorf("malformed key: %w", err)
+	}
+
+	parts := strings.Split(signature, ".")
+	attached := parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + parts[len(parts)-1]
+	_, ok, err := verifyCustomAlg(attached, header.Algorithm, verificationKey)
+	if !ok {
+		err = jws.DetachedVerify(payload, verificationKey)
+	}
+	if err != nil {
+		return Key{}, fmt.Errorf("invalid signature: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,58 @@
+package jwk
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+)
+
+func TestVerifyDetachedJWS(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := FromPublicKey(&privateKey.PublicKey)
+	key.Kid, key.Use = "webhook", "sig"
+	certs, _ := parseCerts(&jwks{Keys: []Key{key}}, time.Hour)
+	j := &JSONWebKeys{cachedCerts: certs}
+	payload := []byte(`{"event":"payment.succeeded"}`)
+
+	sign := func(options *jose.SignerOptions) string {
+		signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: privateKey}, options.WithHeader("kid", "webhook"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		jws, err := signer.Sign(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature, err := jws.DetachedCompactSerialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signature
+	}
+
+	tests := map[string]string{
+		"encoded":   sign(&jose.SignerOptions{}),
+		"unencoded": sign((&jose.SignerOptions{}).WithBase64(false).WithCritical("b64")),
+	}
+	for name, signature := range tests {
+		t.Run(name, func(t *testing.T) {
+			signer, err := j.VerifyDetachedJWS(context.Background(), signature, payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if signer.Kid != "webhook" {
+				t.Fatalf("unexpected key %+v", signer)
+			}
+			if _, err := j.VerifyDetachedJWS(context.Background(), signature, []byte(`{"event":"payment.failed"}`)); err == nil {
+				t.Fatal("expecting a tampered payload to be rejected")
+			}
+		})
+	}
+}
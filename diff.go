@@ -0,0 +1,53 @@
+package jwk
+
+import (
+	"reflect"
+	"sort"
+	"time"
+)
+
+// KeySetChange describes how a refresh changed the cached key set, see Diff
+type KeySetChange struct {
+	// Source is the URL the new key set was fetched from
+	Source string
+	// Time is when the change was observed
+	Time time.Time
+
+	Added   []Key
+	Removed []Key
+	Changed []Key
+}
+
+// Diff compares two key sets by kid, returning the keys only found in new, the ones only found in old and the
+// ones found in both but with different members (as they are in new). Each result is sorted by kid, and a nil
+// Certs is treated as an empty set
+func Diff(old, new *Certs) (added, removed, changed []Key) {
+	oldKeys, newKeys := map[string]Key{}, map[string]Key{}
+	if old != nil {
+		oldKeys = old.Keys
+	}
+	if new != nil {
+		newKeys = new.Keys
+	}
+
+	for kid, key := range newKeys {
+		oldKey, ok := oldKeys[kid]
+		if !ok {
+			added = append(added, key)
+		} else if !reflect.DeepEqual(oldKey, key) {
+			changed = append(changed, key)
+		}
+	}
+	for kid, key := range oldKeys {
+		if _, ok := newKeys[kid]; !ok {
+			removed = append(removed, key)
+		}
+	}
+
+	for _, keys := range [][]Key{added, removed, changed} {
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].Kid < keys[j].Kid
+		})
+	}
+	return added, removed, changed
+}
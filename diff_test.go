@@ -0,0 +1,33 @@
+package jwk
+
+import "testing"
+
+func TestDiff(t *testing.T) {
+	old := &Certs{Keys: map[string]Key{
+		"kept":    {Kty: "RSA", Kid: "kept", N: "AQAB", E: "AQAB"},
+		"removed": {Kty: "RSA", Kid: "removed", N: "AQAB", E: "AQAB"},
+		"changed": {Kty: "RSA", Kid: "changed", N: "AQAB", E: "AQAB", X5c: []string{"a"}},
+	}}
+	new := &Certs{Keys: map[string]Key{
+		"kept":    {Kty: "RSA", Kid: "kept", N: "AQAB", E: "AQAB"},
+		"changed": {Kty: "RSA", Kid: "changed", N: "AQAB", E: "AQAB", X5c: []string{"b"}},
+		"added2":  {Kty: "RSA", Kid: "added2", N: "AQAB", E: "AQAB"},
+		"added1":  {Kty: "RSA", Kid: "added1", N: "AQAB", E: "AQAB"},
+	}}
+
+	added, removed, changed := Diff(old, new)
+	if len(added) != 2 || added[0].Kid != "added1" || added[1].Kid != "added2" {
+		t.Errorf("unexpected added keys: %v", added)
+	}
+	if len(removed) != 1 || removed[0].Kid != "removed" {
+		t.Errorf("unexpected removed keys: %v", removed)
+	}
+	if len(changed) != 1 || changed[0].X5c[0] != "b" {
+		t.Errorf("unexpected changed keys: %v", changed)
+	}
+
+	added, removed, changed = Diff(nil, old)
+	if len(added) != 3 || len(removed) != 0 || len(changed) != 0 {
+		t.Errorf("expecting all keys added from a nil set, got %v %v %v", added, removed, changed)
+	}
+}
@@ -0,0 +1,100 @@
+package jwk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// discoveryPath is the well-known path of the OpenID Connect discovery document
+const discoveryPath = "/.well-known/openid-configuration"
+
+// providerMetadata maps the subset of the OpenID Connect discovery document we care about
+type providerMetadata struct {
+	Issuer           string `json:"issuer"`
+	JWKSURI          string `json:"jwks_uri"`
+	UserInfoEndpoint string `json:"userinfo_endpoint"`
+
+	// JWKS is the key set some issuers embed in place of, or along with, jwks_uri
+	JWKS json.RawMessage `json:"jwks"`
+}
+
+// DiscoverJWKURL reads the jwks_uri from the OpenID Connect discovery document of the given issuer,
+// see https://openid.net/specs/openid-connect-discovery-1_0.html
+// If client is nil a Client with a 10-seconds timeout is used
+func DiscoverJWKURL(client *http.Client, issuer string) (string, error) {
+	metadata, err := discover(client, issuer)
+	if err != nil {
+		return "", err
+	}
+	if metadata.JWKSURI == "" {
+		return "", errors.New("discovery document does not define a jwks_uri")
+	}
+	return metadata.JWKSURI, nil
+}
+
+// FromDiscovery builds a JSONWebKeys for the given issuer from its OpenID Connect discovery document, checking
+// the iss claim of the tokens against the issuer declared there. The keys are fetched from jwks_uri or, when the
+// document embeds them in a jwks member instead, read from the discovery document itself, fetched again on each
+// refresh. jwks_uri is preferred when both are present. If client is nil a Client with a 10-seconds timeout is used
+func FromDiscovery(client *http.Client, issuer string) (*JSONWebKeys, error) {
+	metadata, err := discover(client, issuer)
+	if err != nil {
+		return nil, err
+	}
+	return fromMetadata(client, issuer, discoveryURL(issuer), metadata)
+}
+
+// fromMetadata builds a JSONWebKeys from the metadata of the given issuer, found at metadataURL, see FromDiscovery
+func fromMetadata(client *http.Client, issuer, metadataURL string, metadata providerMetadata) (*JSONWebKeys, error) {
+	j := &JSONWebKeys{Client: client, Issuer: metadata.Issuer, JWKURL: metadata.JWKSURI}
+	if j.Issuer == "" {
+		j.Issuer = issuer
+	}
+	if j.JWKURL == "" {
+		if len(metadata.JWKS) == 0 || string(metadata.JWKS) == "null" {
+			return nil, errors.New("metadata document defines neither jwks_uri nor jwks")
+		}
+		// the inline jwks is unwrapped from the metadata document by fetchJWKS
+		j.JWKURL = metadataURL
+	}
+	return j, nil
+}
+
+// discoveryURL returns the URL of the OpenID Connect discovery document of the given issuer
+func discoveryURL(issuer string) string {
+	return strings.TrimSuffix(issuer, "/") + discoveryPath
+}
+
+// discover fetches the OpenID Connect discovery document of the given issuer, see DiscoverJWKURL
+func discover(client *http.Client, issuer string) (providerMetadata, error) {
+	return fetchMetadata(client, discoveryURL(issuer))
+}
+
+// fetchMetadata fetches and decodes the metadata document at the given URL
+func fetchMetadata(client *http.Client, u string) (providerMetadata, error) {
+	metadata := providerMetadata{}
+	if client == nil {
+		client = &http.Client{Timeout: time.Second * 10}
+	}
+	resp, err := client.Get(u)
+	if err != nil {
+		return metadata, fmt.Errorf("unable to fetch metadata document: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return metadata, &StatusError{URL: u, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	body, err := readBody(resp, defaultMaxDocumentSize)
+	if err != nil {
+		return metadata, fmt.Errorf("unable to read metadata document: %w", err)
+	}
+	if err := json.Unmarshal(body, &metadata); err != nil {
+		return metadata, fmt.Errorf("unable to decode metadata document: %w", err)
+	}
+	return metadata, nil
+}
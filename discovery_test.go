@@ -0,0 +1,89 @@
+package jwk
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDiscoverJWKURL(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != discoveryPath {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"issuer":"` + server.URL + `","jwks_uri":"` + server.URL + `/jwks.json"}`))
+	}))
+	defer server.Close()
+
+	jwkURL, err := DiscoverJWKURL(nil, server.URL+"/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jwkURL != server.URL+"/jwks.json" {
+		t.Fatalf("unexpected jwks_uri: %s", jwkURL)
+	}
+}
+
+func TestDiscoverJWKURLMissing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"issuer":"https://example.com"}`))
+	}))
+	defer server.Close()
+
+	if _, err := DiscoverJWKURL(nil, server.URL); err == nil {
+		t.Fatal("expecting an error for a missing jwks_uri")
+	}
+}
+
+func TestFromDiscovery(t *testing.T) {
+	set, err := ioutil.ReadFile("testdata/jwks.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var metadata string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case discoveryPath:
+			w.Write([]byte(strings.Replace(metadata, "{server}", server.URL, -1)))
+		case "/jwks.json":
+			w.Write(set)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	for _, tc := range []struct {
+		name     string
+		metadata string
+		jwkURL   string
+	}{
+		{"jwks_uri", `{"issuer":"{server}/","jwks_uri":"{server}/jwks.json"}`, "/jwks.json"},
+		{"inline", `{"issuer":"{server}/","jwks":` + string(set) + `}`, discoveryPath},
+		{"both", `{"issuer":"{server}/","jwks_uri":"{server}/jwks.json","jwks":{"keys":[]}}`, "/jwks.json"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			metadata = tc.metadata
+			j, err := FromDiscovery(nil, server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if j.JWKURL != server.URL+tc.jwkURL || j.Issuer != server.URL+"/" {
+				t.Fatalf("unexpected JWKURL %q or Issuer %q", j.JWKURL, j.Issuer)
+			}
+			if _, err := j.GetKey(testKid); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	metadata = `{"issuer":"{server}/"}`
+	if _, err := FromDiscovery(nil, server.URL); err == nil {
+		t.Fatal("expecting an error when no key set is defined")
+	}
+}
@@ -0,0 +1,100 @@
+package jwk
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+const (
+	// dpopMaxAge is how old the iat of a DPoP proof can be
+	dpopMaxAge = 5 * time.Minute
+	// dpopLeeway tolerates clock skew for the proofs issued in the future
+	dpopLeeway = time.Minute
+)
+
+// DPoPProof is a validated DPoP proof, see https://tools.ietf.org/html/rfc9449
+type DPoPProof struct {
+	// Key is the public key embedded in the proof, which signed it
+	Key Key
+	// Thumbprint is the RFC 7638 thumbprint of Key, to be matched against the cnf.jkt claim of the access token
+	Thumbprint string
+	// ID is the jti claim, to be checked against replays by the caller
+	ID string
+	// IssuedAt is the iat claim
+	IssuedAt time.Time
+}
+
+// ValidateDPoP checks a DPoP proof sent along with a request with the given method and URL: its signature must
+// match the key in its jwk header, its htm and htu claims must match the request and its iat must be recent.
+// When accessToken is not empty, the ath claim must hold its hash. Replays are left to the caller, see DPoPProof
+func ValidateDPoP(proof, method, uri string, accessToken string) (DPoPProof, error) {
+	payload, key, err := VerifyEmbeddedJWK(proof, func(key Key, header jose.Header) error {
+		if typ, _ := header.ExtraHeaders["typ"].(string); typ != "dpop+jwt" {
+			return fmt.Errorf("unexpected DPoP proof typ %q", typ)
+		}
+		return nil
+	})
+	if err != nil {
+		return DPoPProof{}, err
+	}
+
+	claims := struct {
+		ID       string           `json:"jti"`
+		Method   string           `json:"htm"`
+		URI      string           `json:"htu"`
+		IssuedAt *jwt.NumericDate `json:"iat"`
+		ATH      string           `json:"ath"`
+	}{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return DPoPProof{}, fmt.Errorf("unable to decode DPoP proof claims: %w", err)
+	}
+	if claims.ID == "" || claims.IssuedAt == nil {
+		return DPoPProof{}, errors.New("DPoP proof is missing jti or iat")
+	}
+	if claims.Method != method {
+		return DPoPProof{}, fmt.Errorf("DPoP proof htm %q does not match method %q", claims.Method, method)
+	}
+	if !sameHTU(claims.URI, uri) {
+		return DPoPProof{}, fmt.Errorf("DPoP proof htu %q does not match %q", claims.URI, uri)
+	}
+	issuedAt := claims.IssuedAt.Time()
+	if now := time.Now(); issuedAt.Before(now.Add(-dpopMaxAge)) || issuedAt.After(now.Add(dpopLeeway)) {
+		return DPoPProof{}, fmt.Errorf("DPoP proof iat %v is out of the accepted window", issuedAt)
+	}
+	if accessToken != "" {
+		sum := sha256.Sum256([]byte(accessToken))
+		if claims.ATH != base64.RawURLEncoding.EncodeToString(sum[:]) {
+			return DPoPProof{}, errors.New("DPoP proof ath does not match the access token")
+		}
+	}
+
+	thumbprint, err := key.Thumbprint()
+	if err != nil {
+		return DPoPProof{}, err
+	}
+	return DPoPProof{Key: key, Thumbprint: thumbprint, ID: claims.ID, IssuedAt: issuedAt}, nil
+}
+
+// sameHTU compares two HTTP URIs ignoring their query and fragment, as well as the case of scheme and host
+func sameHTU(a, b string) bool {
+	normalize := func(raw string) string {
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" {
+			return ""
+		}
+		u.Scheme, u.Host = strings.ToLower(u.Scheme), strings.ToLower(u.Host)
+		u.RawQuery, u.Fragment, u.ForceQuery = "", "", false
+		return u.String()
+	}
+	normalized := normalize(a)
+	return normalized != "" && normalized == normalize(b)
+}
@@ -0,0 +1,83 @@
+package jwk
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+func signTestDPoP(t *testing.T, privateKey *ecdsa.PrivateKey, typ string, embed bool, claims map[string]interface{}) string {
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.ES256, Key: privateKey},
+		(&jose.SignerOptions{EmbedJWK: embed}).WithType(jose.ContentType(typ)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestValidateDPoP(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte("access-token"))
+	claims := func(overrides map[string]interface{}) map[string]interface{} {
+		claims := map[string]interface{}{
+			"jti": "proof-id",
+			"htm": "POST",
+			"htu": "https://Server.example.com/token",
+			"iat": time.Now().Unix(),
+			"ath": base64.RawURLEncoding.EncodeToString(sum[:]),
+		}
+		for name, value := range overrides {
+			claims[name] = value
+		}
+		return claims
+	}
+
+	proof, err := ValidateDPoP(signTestDPoP(t, privateKey, "dpop+jwt", true, claims(nil)),
+		"POST", "https://server.example.com/token?grant_type=code", "access-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := FromPublicKey(&privateKey.PublicKey)
+	thumbprint, _ := expected.Thumbprint()
+	if proof.ID != "proof-id" || proof.Thumbprint != thumbprint || proof.Key.X != expected.X {
+		t.Fatalf("unexpected proof %+v", proof)
+	}
+
+	tests := map[string]struct {
+		proof string
+		err   string
+	}{
+		"wrong typ":    {signTestDPoP(t, privateKey, "JWT", true, claims(nil)), "typ"},
+		"missing jwk":  {signTestDPoP(t, privateKey, "dpop+jwt", false, claims(nil)), "missing jwk header"},
+		"wrong method": {signTestDPoP(t, privateKey, "dpop+jwt", true, claims(map[string]interface{}{"htm": "GET"})), "htm"},
+		"wrong uri":    {signTestDPoP(t, privateKey, "dpop+jwt", true, claims(map[string]interface{}{"htu": "https://server.example.com/other"})), "htu"},
+		"stale":        {signTestDPoP(t, privateKey, "dpop+jwt", true, claims(map[string]interface{}{"iat": time.Now().Add(-time.Hour).Unix()})), "iat"},
+		"wrong ath":    {signTestDPoP(t, privateKey, "dpop+jwt", true, claims(map[string]interface{}{"ath": "nope"})), "ath"},
+		"missing jti":  {signTestDPoP(t, privateKey, "dpop+jwt", true, claims(map[string]interface{}{"jti": ""})), "jti"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ValidateDPoP(test.proof, "POST", "https://server.example.com/token", "access-token")
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expecting an error about %s, got %v", test.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package jwk
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-jose/go-jose/v3"
+)
+
+// EmbeddedKeyPolicy decides whether the key carried by a JWS can be trusted, given the rest of its protected
+// header, returning an error when it can't
+type EmbeddedKeyPolicy func(key Key, header jose.Header) error
+
+// VerifyEmbeddedJWK checks the signature of the given compact JWS against the key in its protected jwk header,
+// as done by ACME and DPoP, returning its payload and the key. Any valid key proves only the possession of its
+// private half, so the policy is required to decide whether it's trusted, i.e. by matching a known thumbprint
+func VerifyEmbeddedJWK(raw string, policy EmbeddedKeyPolicy) ([]byte, Key, error) {
+	if policy == nil {
+		return nil, Key{}, errors.New("an embedded key policy is required")
+	}
+	jws, err := jose.ParseSigned(raw)
+	if err != nil || strings.Count(raw, ".") != 2 {
+		return nil, Key{}, errors.New("unable to parse token")
+	}
+	if len(jws.Signatures) != 1 {
+		return nil, Key{}, errors.New("expecting a token with a single signature")
+	}
+	header := jws.Signatures[0].Protected
+
+	key, err := embeddedKey(raw)
+	if err != nil {
+		return nil, Key{}, err
+	}
+	if err := checkAlg(header.Algorithm, key); err != nil {
+		return nil, Key{}, err
+	}
+	if err := policy(key, header); err != nil {
+		return nil, Key{}, fmt.Errorf("embedded key rejected: %w", err)
+	}
+
+	publicKey, err := key.PublicKey()
+	if err != nil {
+		return nil, Key{}, fmt.Errorf("malformed jwk header: %w", err)
+	}
+	payload, err := jws.Verify(publicKey)
+	if err != nil {
+		return nil, Key{}, fmt.Errorf("invalid token signature: %w", err)
+	}
+	return payload, key, nil
+}
+
+// embeddedKey decodes the jwk member of the protected header of the given compact JWS, making sure it's a
+// valid public key
+func embeddedKey(compact string) (Key, error) {
+	encoded := strings.SplitN(compact, ".", 2)[0]
+	decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Key{}, fmt.Errorf("unable to decode header: %w", err)
+	}
+	header := struct {
+		JWK json.RawMessage `json:"jwk"`
+	}{}
+	if err := json.Unmarshal(decoded, &header); err != nil {
+		return Key{}, fmt.Errorf("unable to decode header: %w", err)
+	}
+	if len(header.JWK) == 0 {
+		return Key{}, errors.New("missing jwk header")
+	}
+
+	key, err := decodePublicJWK(header.JWK)
+	if err != nil {
+		return Key{}, fmt.Errorf("malformed jwk header: %w", err)
+	}
+	return key, nil
+}
+
+// decodePublicJWK decodes a single JWK, making sure it's valid and holds no private member
+func decodePublicJWK(data []byte) (Key, error) {
+	key := Key{}
+	if err := json.Unmarshal(data, &key); err != nil {
+		return Key{}, err
+	}
+	if err := key.Validate(); err != nil {
+		return Key{}, err
+	}
+	for _, name := range privateMembers {
+		if _, ok := key.Extra[name]; ok {
+			return Key{}, fmt.Errorf("private member %q is not allowed", name)
+		}
+	}
+	return key, nil
+}
@@ -0,0 +1,60 @@
+package jwk
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3"
+)
+
+func TestVerifyEmbeddedJWK(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: privateKey}, &jose.SignerOptions{EmbedJWK: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jws, err := signer.Sign([]byte("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := jws.CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := FromPublicKey(&privateKey.PublicKey)
+	thumbprint, _ := expected.Thumbprint()
+
+	trusted := func(key Key, header jose.Header) error {
+		if got, _ := key.Thumbprint(); got != thumbprint {
+			return errors.New("unknown key")
+		}
+		return nil
+	}
+	payload, key, err := VerifyEmbeddedJWK(raw, trusted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(payload) != "payload" || key.X != expected.X {
+		t.Fatalf("unexpected payload %q or key %+v", payload, key)
+	}
+
+	if _, _, err := VerifyEmbeddedJWK(raw, nil); err == nil {
+		t.Fatal("expecting a policy to be required")
+	}
+	rejecting := func(Key, jose.Header) error { return errors.New("unknown key") }
+	if _, _, err := VerifyEmbeddedJWK(raw, rejecting); err == nil || !strings.Contains(err.Error(), "embedded key rejected") {
+		t.Fatalf("expecting the key to be rejected, got %v", err)
+	}
+	parts := strings.Split(raw, ".")
+	tampered := parts[0] + "." + "dGFtcGVyZWQ" + "." + parts[2]
+	if _, _, err := VerifyEmbeddedJWK(tampered, trusted); err == nil || !strings.Contains(err.Error(), "invalid token signature") {
+		t.Fatalf("expecting an invalid signature, got %v", err)
+	}
+}
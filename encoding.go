@@ -0,0 +1,162 @@
+package jwk
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf16"
+)
+
+// defaultMaxDocumentSize is the default of MaxDocumentSize
+const defaultMaxDocumentSize = 1 << 20
+
+// ErrDocumentTooLarge is wrapped by the errors of the fetches whose document, once decompressed, is larger than
+// MaxDocumentSize
+var ErrDocumentTooLarge = errors.New("document too large")
+
+// readBody reads the body of the response, decompressing it according to its Content-Encoding. Neither the body
+// nor the decompressed document may be larger than limit bytes
+func readBody(resp *http.Response, limit int64) ([]byte, error) {
+	body, err := readLimited(resp.Body, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	// the encodings are listed in the order they were applied, some gateways adding a redundant identity
+	encodings := strings.Split(resp.Header.Get("Content-Encoding"), ",")
+	for i := len(encodings) - 1; i >= 0; i-- {
+		if body, err = decode(body, strings.ToLower(strings.TrimSpace(encodings[i])), limit); err != nil {
+			return nil, err
+		}
+	}
+	return body, nil
+}
+
+// decode undoes the given content encoding, refusing to decompress more than limit bytes
+func decode(body []byte, encoding string, limit int64) ([]byte, error) {
+	var reader io.ReadCloser
+	var err error
+	switch encoding {
+	case "", "identity":
+		return body, nil
+	case "gzip", "x-gzip":
+		if reader, err = gzip.NewReader(bytes.NewReader(body)); err != nil {
+			return nil, fmt.Errorf("malformed gzip body: %w", err)
+		}
+	case "deflate":
+		// deflate is meant to be zlib-wrapped, but some servers send raw deflate data
+		if reader, err = zlib.NewReader(bytes.NewReader(body)); err != nil {
+			reader = flate.NewReader(bytes.NewReader(body))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+	defer reader.Close()
+
+	decoded, err := readLimited(reader, limit)
+	if err != nil && !errors.Is(err, ErrDocumentTooLarge) {
+		return nil, fmt.Errorf("malformed compressed body: %w", err)
+	}
+	return decoded, err
+}
+
+// readLimited reads r to the end, failing with ErrDocumentTooLarge rather than reading more than limit bytes
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrDocumentTooLarge, limit)
+	}
+	return data, nil
+}
+
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+	gzipMagic  = []byte{0x1F, 0x8B}
+)
+
+// cleanDocument undoes what some gateways do to JSON documents, which the decoder would reject: compressing them
+// without telling, prepending a byte order mark, encoding them as UTF-16 or padding them with NUL bytes. It returns
+// the document along with warnings describing what was fixed. A compressed document may not decompress to more
+// than limit bytes, or the default MaxDocumentSize when zero
+func cleanDocument(data []byte, limit int64) ([]byte, []string, error) {
+	if limit <= 0 {
+		limit = defaultMaxDocumentSize
+	}
+	var warnings []string
+	if bytes.HasPrefix(data, gzipMagic) {
+		if reader, err := gzip.NewReader(bytes.NewReader(data)); err == nil {
+			decoded, err := readLimited(reader, limit)
+			if errors.Is(err, ErrDocumentTooLarge) {
+				return nil, nil, err
+			}
+			if err == nil {
+				data = decoded
+				warnings = append(warnings, "gzip-compressed document without Content-Encoding")
+			}
+		}
+	}
+
+	switch {
+	case bytes.HasPrefix(data, utf8BOM):
+		data = data[len(utf8BOM):]
+		warnings = append(warnings, "UTF-8 byte order mark")
+	case bytes.HasPrefix(data, utf16LEBOM):
+		data = decodeUTF16(data[len(utf16LEBOM):], binary.LittleEndian)
+		warnings = append(warnings, "UTF-16LE document")
+	case bytes.HasPrefix(data, utf16BEBOM):
+		data = decodeUTF16(data[len(utf16BEBOM):], binary.BigEndian)
+		warnings = append(warnings, "UTF-16BE document")
+	}
+
+	if trimmed := bytes.TrimRight(data, " \t\r\n\x00\x1a"); len(trimmed) < len(bytes.TrimRight(data, " \t\r\n")) {
+		data = trimmed
+		warnings = append(warnings, "trailing NUL or end-of-file bytes")
+	}
+	return data, warnings, nil
+}
+
+// decodeUTF16 converts the UTF-16 data to UTF-8, ignoring a trailing odd byte
+func decodeUTF16(data []byte, order binary.ByteOrder) []byte {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+	return []byte(string(utf16.Decode(units)))
+}
+
+// maxDocumentSize returns MaxDocumentSize, or its default
+func (j *JSONWebKeys) maxDocumentSize() int64 {
+	if j.MaxDocumentSize <= 0 {
+		return defaultMaxDocumentSize
+	}
+	return j.MaxDocumentSize
+}
+
+// maxDocumentSizeKey is the context key of the MaxDocumentSize of the JSONWebKeys running a Fetcher
+type maxDocumentSizeKey struct{}
+
+// withMaxDocumentSize passes the given MaxDocumentSize down to the Fetcher reading its document with ctx
+func withMaxDocumentSize(ctx context.Context, limit int64) context.Context {
+	return context.WithValue(ctx, maxDocumentSizeKey{}, limit)
+}
+
+// contextMaxDocumentSize returns the MaxDocumentSize of the JSONWebKeys running the fetch, or its default
+func contextMaxDocumentSize(ctx context.Context) int64 {
+	if limit, ok := ctx.Value(maxDocumentSizeKey{}).(int64); ok && limit > 0 {
+		return limit
+	}
+	return defaultMaxDocumentSize
+}
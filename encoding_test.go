@@ -0,0 +1,162 @@
+package jwk
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCompressedResponses(t *testing.T) {
+	body, err := ioutil.ReadFile("testdata/jwks.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	compress := func(newWriter func(io.Writer) io.WriteCloser) []byte {
+		buf := &bytes.Buffer{}
+		w := newWriter(buf)
+		w.Write(body)
+		w.Close()
+		return buf.Bytes()
+	}
+	tests := map[string][]byte{
+		"gzip":    compress(func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }),
+		"deflate": compress(func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }),
+		"raw deflate": compress(func(w io.Writer) io.WriteCloser {
+			fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+			return fw
+		}),
+	}
+	for name, compressed := range tests {
+		t.Run(name, func(t *testing.T) {
+			encoding := name
+			if name == "raw deflate" {
+				encoding = "deflate"
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Accept") != acceptJWKS || r.Header.Get("Accept-Encoding") != "gzip, deflate" {
+					t.Errorf("unexpected request headers %v", r.Header)
+				}
+				w.Header().Set("Content-Encoding", encoding)
+				w.Write(compressed)
+			}))
+			defer server.Close()
+
+			// automatic decompression disabled on the transport
+			client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+			j := &JSONWebKeys{JWKURL: server.URL, Client: client}
+			if _, err := j.GetKey(testKid); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestMaxDocumentSize(t *testing.T) {
+	// a few KB of gzip expanding to 10 MB
+	bomb := &bytes.Buffer{}
+	w := gzip.NewWriter(bomb)
+	w.Write(bytes.Repeat([]byte(" "), 10<<20))
+	w.Close()
+	doubleBomb := &bytes.Buffer{}
+	w = gzip.NewWriter(doubleBomb)
+	w.Write(bomb.Bytes())
+	w.Close()
+	tests := map[string]struct {
+		body            []byte
+		contentEncoding string
+	}{
+		"plain":             {bytes.Repeat([]byte(" "), 2<<20), ""},
+		"gzip bomb":         {bomb.Bytes(), "gzip"},
+		"stacked gzip bomb": {bomb.Bytes(), "gzip, identity"},
+		"undeclared bomb":   {bomb.Bytes(), ""},
+		"double gzip bomb":  {doubleBomb.Bytes(), "gzip"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if test.contentEncoding != "" {
+					w.Header().Set("Content-Encoding", test.contentEncoding)
+				}
+				w.Write(test.body)
+			}))
+			defer server.Close()
+
+			client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+			j := &JSONWebKeys{JWKURL: server.URL, Client: client}
+			if _, err := j.GetKeys(); !errors.Is(err, ErrDocumentTooLarge) {
+				t.Fatalf("expecting ErrDocumentTooLarge, got %v", err)
+			}
+		})
+	}
+
+	j := &JSONWebKeys{MaxDocumentSize: 10}
+	server, _ := newTestJWKSServer(t, "", 0)
+	defer server.Close()
+	j.JWKURL = server.URL
+	if _, err := j.GetKeys(); !errors.Is(err, ErrDocumentTooLarge) {
+		t.Fatalf("expecting MaxDocumentSize to apply, got %v", err)
+	}
+}
+
+func TestMangledDocuments(t *testing.T) {
+	body, err := ioutil.ReadFile("testdata/jwks.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gzipped := &bytes.Buffer{}
+	w := gzip.NewWriter(gzipped)
+	w.Write(body)
+	w.Close()
+	utf16LE := append([]byte{}, utf16LEBOM...)
+	utf16BE := append([]byte{}, utf16BEBOM...)
+	for _, r := range string(body) {
+		utf16LE = append(utf16LE, byte(r), byte(r>>8))
+		utf16BE = append(utf16BE, byte(r>>8), byte(r))
+	}
+
+	tests := map[string]struct {
+		body            []byte
+		contentEncoding string
+		warning         string
+	}{
+		"bom":                 {append(append([]byte{}, utf8BOM...), body...), "", "UTF-8 byte order mark"},
+		"utf-16le":            {utf16LE, "", "UTF-16LE document"},
+		"utf-16be":            {utf16BE, "", "UTF-16BE document"},
+		"trailing whitespace": {append(append([]byte{}, body...), " \r\n\t"...), "", ""},
+		"trailing nul":        {append(append([]byte{}, body...), "\n\x00\x00"...), "", "trailing NUL or end-of-file bytes"},
+		"undeclared gzip":     {gzipped.Bytes(), "", "gzip-compressed document without Content-Encoding"},
+		"stacked encodings":   {gzipped.Bytes(), "GZIP, identity", ""},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if test.contentEncoding != "" {
+					w.Header().Set("Content-Encoding", test.contentEncoding)
+				}
+				w.Write(test.body)
+			}))
+			defer server.Close()
+
+			client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+			j := &JSONWebKeys{JWKURL: server.URL, Client: client}
+			certs, err := j.GetKeys()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := certs.Get(testKid); !ok {
+				t.Fatalf("expecting the key to be found, got %+v", certs.Report)
+			}
+			warnings := certs.Report.Warnings
+			if test.warning == "" && len(warnings) != 0 || test.warning != "" && (len(warnings) != 1 || warnings[0] != test.warning) {
+				t.Fatalf("unexpected warnings %q", warnings)
+			}
+		})
+	}
+}
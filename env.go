@@ -0,0 +1,76 @@
+package jwk
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FromEnv builds a JSONWebKeys configured by the following environment variables, the unset ones leaving their
+// setting to its default:
+//
+//	JWKS_URL                   JWKURL, required
+//	JWKS_CACHE_AGE             DefaultCacheAge, as a duration, i.e. 1h
+//	JWKS_ISSUER                Issuer
+//	JWKS_AUDIENCE              Audience
+//	JWKS_PARSE_MODE            ParseMode, either lenient or strict
+//	JWKS_IGNORE_CACHE_CONTROL  IgnoreCacheControl, as a boolean
+//	JWKS_FETCH_RETRIES         FetchRetries
+//	JWKS_TIMEOUT               FetchTimeout, as a duration
+func FromEnv() (*JSONWebKeys, error) {
+	j := &JSONWebKeys{JWKURL: os.Getenv("JWKS_URL")}
+	if j.JWKURL == "" {
+		return nil, errors.New("JWKS_URL is not set")
+	}
+	j.Issuer = os.Getenv("JWKS_ISSUER")
+	j.Audience = os.Getenv("JWKS_AUDIENCE")
+
+	if err := envDuration("JWKS_CACHE_AGE", &j.DefaultCacheAge); err != nil {
+		return nil, err
+	}
+	if value, ok := os.LookupEnv("JWKS_PARSE_MODE"); ok {
+		switch strings.ToLower(value) {
+		case "lenient":
+			j.ParseMode = Lenient
+		case "strict":
+			j.ParseMode = Strict
+		default:
+			return nil, fmt.Errorf("invalid JWKS_PARSE_MODE %q, expecting lenient or strict", value)
+		}
+	}
+	if value, ok := os.LookupEnv("JWKS_IGNORE_CACHE_CONTROL"); ok {
+		ignore, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWKS_IGNORE_CACHE_CONTROL: %w", err)
+		}
+		j.IgnoreCacheControl = ignore
+	}
+	if value, ok := os.LookupEnv("JWKS_FETCH_RETRIES"); ok {
+		retries, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWKS_FETCH_RETRIES: %w", err)
+		}
+		j.FetchRetries = retries
+	}
+	if err := envDuration("JWKS_TIMEOUT", &j.FetchTimeout); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// envDuration parses the given environment variable into value, when set
+func envDuration(name string, value *time.Duration) error {
+	raw, ok := os.LookupEnv(name)
+	if !ok {
+		return nil
+	}
+	duration, err := time.ParseDuration(raw)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", name, err)
+	}
+	*value = duration
+	return nil
+}
@@ -0,0 +1,53 @@
+package jwk
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// setTestEnv sets the given environment variables, returning a function unsetting them
+func setTestEnv(env map[string]string) func() {
+	for name, value := range env {
+		os.Setenv(name, value)
+	}
+	return func() {
+		for name := range env {
+			os.Unsetenv(name)
+		}
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	defer setTestEnv(map[string]string{
+		"JWKS_URL":                  "https://example.com/.well-known/jwks.json",
+		"JWKS_CACHE_AGE":            "1h",
+		"JWKS_ISSUER":               "https://example.com/",
+		"JWKS_AUDIENCE":             "api",
+		"JWKS_PARSE_MODE":           "strict",
+		"JWKS_IGNORE_CACHE_CONTROL": "true",
+		"JWKS_FETCH_RETRIES":        "3",
+		"JWKS_TIMEOUT":              "5s",
+	})()
+
+	j, err := FromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j.JWKURL != "https://example.com/.well-known/jwks.json" || j.DefaultCacheAge != time.Hour ||
+		j.Issuer != "https://example.com/" || j.Audience != "api" || j.ParseMode != Strict ||
+		!j.IgnoreCacheControl || j.FetchRetries != 3 || j.FetchTimeout != 5*time.Second {
+		t.Fatalf("unexpected configuration %+v", j)
+	}
+}
+
+func TestFromEnvInvalid(t *testing.T) {
+	if _, err := FromEnv(); err == nil {
+		t.Fatal("expecting JWKS_URL to be required")
+	}
+
+	defer setTestEnv(map[string]string{"JWKS_URL": "https://example.com/", "JWKS_CACHE_AGE": "forever"})()
+	if _, err := FromEnv(); err == nil {
+		t.Fatal("expecting an invalid JWKS_CACHE_AGE to be reported")
+	}
+}
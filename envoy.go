@@ -0,0 +1,67 @@
+package jwk
+
+import (
+	"context"
+	"time"
+)
+
+// EnvoyRemoteJWKS mirrors the remote_jwks settings of the Envoy jwt_authn filter, see
+// https://www.envoyproxy.io/docs/envoy/latest/api-v3/extensions/filters/http/jwt_authn/v3/config.proto,
+// so that platform teams can keep in-process verification behaving like their sidecars
+type EnvoyRemoteJWKS struct {
+	// URI is the JWKS URL, as http_uri.uri
+	URI string
+
+	// Timeout bounds each fetch, as http_uri.timeout. Defaults to 10 seconds
+	Timeout time.Duration
+
+	// CacheDuration is how long the keys are cached, whatever the cache-control header says.
+	// Defaults to 10 minutes, as in Envoy
+	CacheDuration time.Duration
+
+	// AsyncFetch fetches the keys in the background right away and refreshes them before they expire,
+	// instead of fetching them on demand
+	AsyncFetch bool
+
+	// FailedRefetchDuration is the wait before trying again after a failed background fetch.
+	// Defaults to 1 second, as in Envoy
+	FailedRefetchDuration time.Duration
+
+	// NumRetries, RetryBaseInterval and RetryMaxInterval map the retry_policy settings: no retries by default,
+	// then a backoff starting at 1 second up to 10 times that, as in Envoy
+	NumRetries        int
+	RetryBaseInterval time.Duration
+	RetryMaxInterval  time.Duration
+}
+
+// JSONWebKeys builds JSONWebKeys configured like Envoy. With AsyncFetch, the keys are refreshed in the background
+// until ctx is done
+func (e EnvoyRemoteJWKS) JSONWebKeys(ctx context.Context) *JSONWebKeys {
+	timeout := e.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	cacheDuration := e.CacheDuration
+	if cacheDuration == 0 {
+		cacheDuration = 10 * time.Minute
+	}
+	failedRefetch := e.FailedRefetchDuration
+	if failedRefetch == 0 {
+		failedRefetch = time.Second
+	}
+
+	j := &JSONWebKeys{
+		JWKURL:                e.URI,
+		FetchTimeout:          timeout,
+		DefaultCacheAge:       cacheDuration,
+		IgnoreCacheControl:    true,
+		FetchRetries:          e.NumRetries,
+		RetryBaseInterval:     e.RetryBaseInterval,
+		RetryMaxInterval:      e.RetryMaxInterval,
+		FailedRefreshInterval: failedRefetch,
+	}
+	if e.AsyncFetch {
+		go j.RunRefresher(ctx)
+	}
+	return j
+}
@@ -0,0 +1,39 @@
+package jwk
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEnvoyRemoteJWKS(t *testing.T) {
+	server, requests := newTestJWKSServer(t, "max-age=1", 0)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	j := EnvoyRemoteJWKS{URI: server.URL, AsyncFetch: true}.JSONWebKeys(ctx)
+
+	if j.DefaultCacheAge != 10*time.Minute || !j.IgnoreCacheControl || j.FailedRefreshInterval != time.Second || j.FetchTimeout != 10*time.Second || j.Client != nil {
+		t.Fatalf("unexpected configuration: %+v", j)
+	}
+	if client := (EnvoyRemoteJWKS{Timeout: 30 * time.Second}).JSONWebKeys(ctx).httpClient(); client.Timeout != 30*time.Second {
+		t.Errorf("expecting the default client to wait for the Envoy timeout, got %s", client.Timeout)
+	}
+
+	// keys are fetched in the background, ignoring the max-age header
+	waitFor(t, func() bool {
+		return atomic.LoadInt32(requests) == 1
+	})
+	certs, err := j.GetKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Until(certs.Expiry) < 9*time.Minute {
+		t.Fatalf("expecting the cache duration to be used, got %s", certs.Expiry)
+	}
+	if atomic.LoadInt32(requests) != 1 {
+		t.Fatalf("expecting a single request, got %d", atomic.LoadInt32(requests))
+	}
+}
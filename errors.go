@@ -0,0 +1,68 @@
+package jwk
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrKeyNotFound is wrapped by the errors of the lookups finding no key for the requested kid
+var ErrKeyNotFound = errors.New("Unable to find the appropriate key")
+
+// KeyNotFoundError is returned by the lookups finding no key for the requested kid, and wraps ErrKeyNotFound.
+// Its message is only formatted when read, keeping the misses cheap
+type KeyNotFoundError struct {
+	Kid string
+	// Reason is why the key was skipped, when it was
+	Reason string
+	// Err is why the key was rejected, when it's malformed
+	Err error
+}
+
+// Error implements the error interface
+func (e *KeyNotFoundError) Error() string {
+	switch {
+	case e.Reason != "":
+		return fmt.Sprintf("%v: key %q was skipped, %s.", ErrKeyNotFound, e.Kid, e.Reason)
+	case e.Err != nil:
+		return fmt.Sprintf("%v: key %q is malformed, %v.", ErrKeyNotFound, e.Kid, e.Err)
+	}
+	return fmt.Sprintf("%v: no key %q.", ErrKeyNotFound, e.Kid)
+}
+
+// Unwrap returns ErrKeyNotFound
+func (e *KeyNotFoundError) Unwrap() error {
+	return ErrKeyNotFound
+}
+
+// StatusError is returned when an endpoint answers with an unexpected HTTP status
+type StatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+}
+
+// Error implements the error interface
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status from %s: %s", e.URL, e.Status)
+}
+
+// SourcesError is returned when the JWKS was fetched from several sources, i.e. mirrors or the sources of a
+// CompositeFetcher, and all of them failed. errors.Is and errors.As look through each of the errors
+type SourcesError struct {
+	Errs []error
+}
+
+// Error implements the error interface
+func (e *SourcesError) Error() string {
+	failures := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		failures[i] = err.Error()
+	}
+	return "all the JWKS sources failed: " + strings.Join(failures, "; ")
+}
+
+// Unwrap returns the errors of the sources
+func (e *SourcesError) Unwrap() []error {
+	return e.Errs
+}
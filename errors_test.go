@@ -0,0 +1,98 @@
+package jwk
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestErrKeyNotFound(t *testing.T) {
+	server, _ := newTestJWKSServer(t, "", 0)
+	defer server.Close()
+
+	j := &JSONWebKeys{JWKURL: server.URL}
+	_, err := j.GetKey("unknown")
+	if !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expecting ErrKeyNotFound, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `"unknown"`) {
+		t.Fatalf("expecting the kid in %q", err)
+	}
+	var notFound *KeyNotFoundError
+	if !errors.As(err, &notFound) || notFound.Kid != "unknown" {
+		t.Fatalf("expecting a KeyNotFoundError, got %v", err)
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	server, _ := newTestJWKSServer(t, "", 1)
+	defer server.Close()
+
+	j := &JSONWebKeys{JWKURL: server.URL}
+	_, err := j.GetKeys()
+	var status *StatusError
+	if !errors.As(err, &status) {
+		t.Fatalf("expecting a StatusError, got %v", err)
+	}
+	if status.StatusCode != http.StatusServiceUnavailable || status.URL != server.URL {
+		t.Fatalf("unexpected status error %+v", status)
+	}
+}
+
+func TestDeadlineExceeded(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	j := &JSONWebKeys{JWKURL: server.URL, Client: &http.Client{}, FetchTimeout: 50 * time.Millisecond}
+	if _, err := j.GetKeys(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expecting context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestSourcesError(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	release := make(chan struct{})
+	hanging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer hanging.Close()
+	defer close(release)
+
+	j := &JSONWebKeys{JWKURL: failing.URL, MirrorURLs: []string{failing.URL + "/mirror"}}
+	_, err := j.GetKeys()
+	var sources *SourcesError
+	var status *StatusError
+	if !errors.As(err, &sources) || len(sources.Errs) != 2 || !errors.As(err, &status) {
+		t.Fatalf("expecting the status errors of both sources, got %v", err)
+	}
+	if status.StatusCode != http.StatusServiceUnavailable || status.URL != failing.URL {
+		t.Fatalf("unexpected status error %+v", status)
+	}
+
+	composite := &CompositeFetcher{Sources: []Fetcher{
+		&URLFetcher{URL: failing.URL},
+		&URLFetcher{URL: hanging.URL, Client: &http.Client{Timeout: 50 * time.Millisecond}},
+	}}
+	j = &JSONWebKeys{Fetcher: composite}
+	if _, err := j.GetKeys(); !errors.As(err, &status) || !errors.Is(err, context.DeadlineExceeded) && !isTimeout(err) {
+		t.Fatalf("expecting the errors of the composite sources to be kept, got %v", err)
+	}
+}
+
+// isTimeout tells whether err wraps a net.Error timing out
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,69 @@
+package jwk
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// KeyExposure reports a key fetched from a remote source along with private members, which are no longer secret:
+// the key is refused, and the issuer should revoke it. Only the names of the members are reported, never their
+// values
+type KeyExposure struct {
+	// Source is where the key set came from, see Certs.Source
+	Source string
+	// Time is when the exposure was detected
+	Time time.Time
+
+	Kid     string
+	Kty     string
+	Members []string
+}
+
+// exposedMembers returns the names of the private members held by the key
+func exposedMembers(key Key) []string {
+	var members []string
+	for _, name := range privateMembers {
+		if _, ok := key.Extra[name]; ok {
+			members = append(members, name)
+		}
+	}
+	return members
+}
+
+// refusePrivate wraps the filter of a remote key set, also refusing the keys it accepts when they expose private
+// members
+func refusePrivate(filter keyFilter) keyFilter {
+	return func(key Key) string {
+		if reason := filter(key); reason != "" {
+			return reason
+		}
+		if members := exposedMembers(key); len(members) > 0 {
+			return fmt.Sprintf("private members %s exposed by a remote source", strings.Join(members, ", "))
+		}
+		return ""
+	}
+}
+
+// findExposures lists the keys of the document exposing private members
+func findExposures(res *jwks) []KeyExposure {
+	var exposures []KeyExposure
+	for _, key := range res.Keys {
+		if members := exposedMembers(key); len(members) > 0 {
+			exposures = append(exposures, KeyExposure{Kid: key.Kid, Kty: key.Kty, Members: members})
+		}
+	}
+	return exposures
+}
+
+// reportExposures calls OnKeyExposure, in its own goroutine, for each key of the certs exposing private members
+func (j *JSONWebKeys) reportExposures(certs *Certs) {
+	if j.OnKeyExposure == nil {
+		return
+	}
+	now := time.Now()
+	for _, exposure := range certs.exposures {
+		exposure.Source, exposure.Time = certs.Source, now
+		go j.OnKeyExposure(exposure)
+	}
+}
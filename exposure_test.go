@@ -0,0 +1,57 @@
+package jwk
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrivateMembersRefused(t *testing.T) {
+	document := `{"keys":[
+		{"kty":"RSA","kid":"leaked","use":"sig","n":"` + testKey.N + `","e":"AQAB","d":"c2VjcmV0LWQ","p":"c2VjcmV0LXA","q":"c2VjcmV0LXE"},
+		{"kty":"RSA","kid":"public","use":"sig","n":"` + testKey.N + `","e":"AQAB"}
+	]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(document))
+	}))
+	defer server.Close()
+
+	exposures := make(chan KeyExposure, 1)
+	j := &JSONWebKeys{
+		JWKURL:        server.URL,
+		OnKeyExposure: func(exposure KeyExposure) { exposures <- exposure },
+		KeyFilter:     func(Key) bool { return true },
+	}
+	certs, err := j.GetKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := certs.Get("leaked"); ok {
+		t.Fatal("expecting the key exposing private members to be refused")
+	}
+	if _, ok := certs.Get("public"); !ok {
+		t.Fatal("expecting the public key to be kept")
+	}
+	if len(certs.Report.Skipped) != 1 || certs.Report.Skipped[0].Reason != "private members d, p, q exposed by a remote source" {
+		t.Fatalf("unexpected skipped keys %+v", certs.Report.Skipped)
+	}
+	if _, err := j.GetKey("leaked"); err == nil || !strings.Contains(err.Error(), "private members") {
+		t.Fatalf("expecting the lookup error to tell why the key was refused, got %v", err)
+	}
+	if dump := fmt.Sprintf("%+v %+v", certs, certs.AllKeys()); strings.Contains(dump, "c2VjcmV0") {
+		t.Fatalf("expecting the private members to be scrubbed, got %s", dump)
+	}
+
+	select {
+	case exposure := <-exposures:
+		if exposure.Source != server.URL || exposure.Kid != "leaked" || !reflect.DeepEqual(exposure.Members, []string{"d", "p", "q"}) {
+			t.Fatalf("unexpected exposure %+v", exposure)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expecting the exposure to be reported")
+	}
+}
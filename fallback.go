@@ -0,0 +1,22 @@
+package jwk
+
+// fallbackCerts returns the certs built from FallbackKeys, when set and no fetch has succeeded yet.
+// It returns nil otherwise, as well as when FallbackKeys can't be parsed
+func (j *JSONWebKeys) fallbackCerts() *Certs {
+	if len(j.FallbackKeys) == 0 {
+		return nil
+	}
+	j.certsMutex.RLock()
+	cached := j.cachedCerts
+	j.certsMutex.RUnlock()
+	if cached != nil && !cached.Fallback {
+		return nil
+	}
+
+	certs, err := j.buildCerts(j.FallbackKeys, j.failedRefreshInterval())
+	if err != nil {
+		return nil
+	}
+	certs.Fallback = true
+	return certs
+}
@@ -0,0 +1,48 @@
+package jwk
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestFallbackKeys(t *testing.T) {
+	body, err := ioutil.ReadFile("testdata/jwks.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var up int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&up) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	j := &JSONWebKeys{JWKURL: server.URL, FallbackKeys: body}
+	certs, err := j.GetKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !certs.Fallback {
+		t.Fatal("expecting the fallback keys to be served")
+	}
+	if _, err := j.GetKey(testKid); err != nil {
+		t.Fatal(err)
+	}
+
+	atomic.StoreInt32(&up, 1)
+	if certs, err = j.GetKeys(ForceRefresh()); err != nil || certs.Fallback {
+		t.Fatalf("expecting the live keys to replace the fallback ones, got %v", err)
+	}
+
+	// once a fetch succeeded, the fallback keys are never used again
+	atomic.StoreInt32(&up, 0)
+	if _, err = j.GetKeys(ForceRefresh()); err == nil {
+		t.Fatal("expecting the failed fetch to be reported")
+	}
+}
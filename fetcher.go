@@ -0,0 +1,96 @@
+package jwk
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Fetcher fetches the JWKS document from a source other than an HTTP URL, i.e. a mounted file or a secret store
+type Fetcher interface {
+	// Fetch returns the document along with how long it can be cached, DefaultCacheAge being used when zero
+	Fetch(ctx context.Context) (body []byte, cacheAge time.Duration, err error)
+}
+
+// ChangeNotifier is implemented by the fetchers able to tell when their document changes, RunRefresher
+// refreshing right away then
+type ChangeNotifier interface {
+	// Changes delivers a value whenever the document may have changed, until ctx is done
+	Changes(ctx context.Context) (<-chan struct{}, error)
+}
+
+// StreamingFetcher is a Fetcher whose source pushes the new documents, i.e. an xDS-style config distribution:
+// RunRefresher loads them as soon as they are received, for instant key rotations without polling
+type StreamingFetcher interface {
+	Fetcher
+
+	// Subscribe delivers the documents pushed by the source until ctx is done
+	Subscribe(ctx context.Context) <-chan []byte
+}
+
+// fetchFromFetcher fetches the JWKS with Fetcher, returning the body, its cache age and its origin
+func (j *JSONWebKeys) fetchFromFetcher(ctx context.Context) ([]byte, time.Duration, origin, error) {
+	var body []byte
+	var cacheAge time.Duration
+	var provenance map[string]string
+	var err error
+	ctx = withMaxDocumentSize(ctx, j.maxDocumentSize())
+	if composite, ok := j.Fetcher.(*CompositeFetcher); ok {
+		body, cacheAge, provenance, err = composite.fetch(ctx)
+	} else {
+		body, cacheAge, err = j.Fetcher.Fetch(ctx)
+	}
+	if err != nil {
+		return nil, 0, origin{}, err
+	}
+	if cacheAge == 0 {
+		cacheAge = j.defaultCacheAge()
+	}
+	return body, cacheAge, origin{source: fetcherName(j.Fetcher), provenance: provenance}, nil
+}
+
+// fetcherName describes the given fetcher with its String method, if any
+func fetcherName(fetcher Fetcher) string {
+	if stringer, ok := fetcher.(fmt.Stringer); ok {
+		return stringer.String()
+	}
+	return ""
+}
+
+// subscribeChanges subscribes to the changes of Fetcher, returning a nil channel, never ready, when it can't
+// notify them or fails to
+func (j *JSONWebKeys) subscribeChanges(ctx context.Context) <-chan struct{} {
+	notifier, ok := j.Fetcher.(ChangeNotifier)
+	if !ok {
+		return nil
+	}
+	changes, err := notifier.Changes(ctx)
+	if err != nil {
+		return nil
+	}
+	return changes
+}
+
+// subscribeDocuments subscribes to the documents pushed to Fetcher, returning a nil channel, never ready, when
+// it doesn't stream them
+func (j *JSONWebKeys) subscribeDocuments(ctx context.Context) <-chan []byte {
+	streaming, ok := j.Fetcher.(StreamingFetcher)
+	if !ok {
+		return nil
+	}
+	return streaming.Subscribe(ctx)
+}
+
+// loadDocument caches the certs of a document pushed by a StreamingFetcher, lasting the default cache age
+func (j *JSONWebKeys) loadDocument(body []byte) {
+	j.fetchMutex.Lock()
+	defer j.fetchMutex.Unlock()
+	certs, err := j.buildCerts(body, j.defaultCacheAge())
+	j.recordFetch(err)
+	if err != nil {
+		return
+	}
+	certs.Source = fetcherName(j.Fetcher)
+	j.reportExposures(certs)
+	j.storeCerts(certs)
+}
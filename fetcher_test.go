@@ -0,0 +1,59 @@
+package jwk
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// pushFetcher is a StreamingFetcher pushing the documents sent on its channel
+type pushFetcher struct {
+	initial   []byte
+	documents chan []byte
+}
+
+func (p *pushFetcher) Fetch(ctx context.Context) ([]byte, time.Duration, error) {
+	return p.initial, 0, nil
+}
+
+func (p *pushFetcher) Subscribe(ctx context.Context) <-chan []byte {
+	return p.documents
+}
+
+func (p *pushFetcher) String() string {
+	return "push"
+}
+
+func TestStreamingFetcher(t *testing.T) {
+	oldKey := testKey
+	oldKey.Kid = "old"
+	encode := func(keys ...Key) []byte {
+		encoded, err := json.Marshal(jwks{Keys: keys})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return encoded
+	}
+	fetcher := &pushFetcher{initial: encode(oldKey), documents: make(chan []byte)}
+	j := &JSONWebKeys{Fetcher: fetcher}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go j.RunRefresher(ctx)
+
+	fetcher.documents <- encode(testKey)
+	deadline := time.Now().Add(5 * time.Second)
+	for !j.holdsKid(testKid) {
+		if time.Now().After(deadline) {
+			t.Fatal("expecting the pushed document to be loaded")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	certs, err := j.GetKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := certs.Get("old"); ok || certs.Source != "push" {
+		t.Fatalf("expecting the pushed keys only, got %+v", certs)
+	}
+}
@@ -0,0 +1,58 @@
+package jwk
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// FileFetcher reads the JWKS document from a file, i.e. a Kubernetes ConfigMap or Secret mounted in the pod by
+// a platform syncing the keys of the issuer centrally, so that the workloads make no egress call. It notifies
+// the changes of the file, watched with inotify on Linux and polled elsewhere
+type FileFetcher struct {
+	// Path is the path of the JWKS document
+	Path string
+
+	// CacheAge is how long the document is cached, DefaultCacheAge of JSONWebKeys when zero
+	CacheAge time.Duration
+
+	// PollInterval is how often the file is checked for changes where inotify is not available, 10 seconds by
+	// default
+	PollInterval time.Duration
+}
+
+// Fetch implements Fetcher
+func (f *FileFetcher) Fetch(ctx context.Context) ([]byte, time.Duration, error) {
+	body, err := os.ReadFile(f.Path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to read key set: %w", err)
+	}
+	return body, f.CacheAge, nil
+}
+
+// Changes implements ChangeNotifier
+func (f *FileFetcher) Changes(ctx context.Context) (<-chan struct{}, error) {
+	return watchFile(ctx, f.Path, f.pollInterval())
+}
+
+// String describes the source of the certs
+func (f *FileFetcher) String() string {
+	return "file://" + f.Path
+}
+
+// pollInterval returns PollInterval, or its default
+func (f *FileFetcher) pollInterval() time.Duration {
+	if f.PollInterval == 0 {
+		return 10 * time.Second
+	}
+	return f.PollInterval
+}
+
+// notifyChange queues a change without blocking, a pending one being enough to trigger a refresh
+func notifyChange(changes chan<- struct{}) {
+	select {
+	case changes <- struct{}{}:
+	default:
+	}
+}
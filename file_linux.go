@@ -0,0 +1,45 @@
+package jwk
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// inotifyMask picks the events of the watched directory. Kubernetes updates the mounted ConfigMaps and Secrets
+// by swapping a symlink, which is never written to
+const inotifyMask = syscall.IN_CREATE | syscall.IN_MOVED_TO | syscall.IN_CLOSE_WRITE | syscall.IN_DELETE
+
+// watchFile notifies the changes of the directory holding path with inotify, the poll interval being unused
+func watchFile(ctx context.Context, path string, _ time.Duration) (<-chan struct{}, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("unable to watch %s: %w", path, err)
+	}
+	if _, err := syscall.InotifyAddWatch(fd, filepath.Dir(path), inotifyMask); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("unable to watch %s: %w", path, err)
+	}
+	// a non-blocking descriptor goes through the runtime poller, so that closing it ends the pending read
+	events := os.NewFile(uintptr(fd), "inotify")
+	go func() {
+		<-ctx.Done()
+		events.Close()
+	}()
+
+	changes := make(chan struct{}, 1)
+	go func() {
+		defer close(changes)
+		buf := make([]byte, 4096)
+		for {
+			if _, err := events.Read(buf); err != nil {
+				return
+			}
+			notifyChange(changes)
+		}
+	}()
+	return changes, nil
+}
@@ -0,0 +1,41 @@
+//go:build !linux
+// +build !linux
+
+package jwk
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// watchFile notifies the changes of the file at path, polled every interval for its size and modification time
+func watchFile(ctx context.Context, path string, interval time.Duration) (<-chan struct{}, error) {
+	stat := func() (time.Time, int64) {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, -1
+		}
+		return info.ModTime(), info.Size()
+	}
+	modTime, size := stat()
+
+	changes := make(chan struct{}, 1)
+	go func() {
+		defer close(changes)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if newModTime, newSize := stat(); !newModTime.Equal(modTime) || newSize != size {
+				modTime, size = newModTime, newSize
+				notifyChange(changes)
+			}
+		}
+	}()
+	return changes, nil
+}
@@ -0,0 +1,81 @@
+package jwk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileFetcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jwks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "jwks.json")
+	write := func(keys ...Key) {
+		body, err := json.Marshal(jwks{Keys: keys})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// written aside then renamed, as Kubernetes swaps the mounted files
+		if err := ioutil.WriteFile(path+".tmp", body, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(path+".tmp", path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldKey := testKey
+	oldKey.Kid = "old"
+	write(oldKey)
+
+	fetcher := &FileFetcher{Path: path, PollInterval: 10 * time.Millisecond}
+	j := &JSONWebKeys{Fetcher: fetcher, RefreshInterval: time.Hour}
+	certs, err := j.GetKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := certs.Get("old"); !ok || certs.Source != "file://"+path {
+		t.Fatalf("unexpected certs %+v", certs)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go j.RunRefresher(ctx)
+	for j.RefreshStatus().NextRefresh.IsZero() {
+		time.Sleep(time.Millisecond)
+	}
+
+	write(oldKey, testKey)
+	deadline := time.Now().Add(5 * time.Second)
+	for !j.holdsKid(testKid) {
+		if time.Now().After(deadline) {
+			t.Fatal("expecting the certs to be refreshed once the file changes")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFileFetcherMissing(t *testing.T) {
+	j := &JSONWebKeys{Fetcher: &FileFetcher{Path: "testdata/missing.json"}}
+	if _, err := j.GetKeys(); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expecting a missing file error, got %v", err)
+	}
+}
+
+func TestFileFetcherCacheAge(t *testing.T) {
+	j := &JSONWebKeys{Fetcher: &FileFetcher{Path: "testdata/jwks.json"}}
+	certs, err := j.GetKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Until(certs.Expiry) < 9*time.Hour {
+		t.Fatalf("expecting the default cache age, expiring at %v", certs.Expiry)
+	}
+}
@@ -0,0 +1,65 @@
+package jwk
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// gcsEndpoint is the XML API endpoint of Google Cloud Storage
+const gcsEndpoint = "https://storage.googleapis.com"
+
+// GCSFetcher reads the JWKS document from a Google Cloud Storage object, i.e. a mirror of the JWKS of the issuer
+// kept in a bucket of the organization. The object is only downloaded again once its ETag changes
+type GCSFetcher struct {
+	Bucket string
+	Object string
+
+	// Token returns the OAuth2 access token of the requests, i.e. from golang.org/x/oauth2/google. The object
+	// is read anonymously when nil, as allowed by public buckets
+	Token func(ctx context.Context) (string, error)
+
+	// Endpoint replaces https://storage.googleapis.com, i.e. for an emulator
+	Endpoint string
+
+	// Client is the HTTP client of the requests. If unset it will default to a Client with a 10-seconds timeout
+	Client *http.Client
+
+	// CacheAge is how long the document is cached, DefaultCacheAge of JSONWebKeys when zero
+	CacheAge time.Duration
+
+	cache objectCache
+}
+
+// Fetch implements Fetcher
+func (g *GCSFetcher) Fetch(ctx context.Context) ([]byte, time.Duration, error) {
+	endpoint := g.Endpoint
+	if endpoint == "" {
+		endpoint = gcsEndpoint
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(endpoint, "/")+"/"+escapeObjectKey(g.Bucket)+"/"+
+		escapeObjectKey(g.Object), nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to build GCS request: %w", err)
+	}
+	if g.Token != nil {
+		token, err := g.Token(ctx)
+		if err != nil {
+			return nil, 0, fmt.Errorf("unable to get GCS access token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	body, err := g.cache.get(objectClient(g.Client), req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to fetch %s: %w", g, err)
+	}
+	return body, g.CacheAge, nil
+}
+
+// String describes the source of the certs
+func (g *GCSFetcher) String() string {
+	return "gs://" + g.Bucket + "/" + g.Object
+}
@@ -0,0 +1,47 @@
+package jwk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestGCSFetcher(t *testing.T) {
+	server, requests, downloads := newTestObjectServer(t, func(r *http.Request) {
+		if r.URL.Path != "/keys/idp/jwks.json" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("unexpected Authorization %q", r.Header.Get("Authorization"))
+		}
+	})
+	defer server.Close()
+
+	fetcher := &GCSFetcher{Bucket: "keys", Object: "idp/jwks.json", Endpoint: server.URL,
+		Token: func(ctx context.Context) (string, error) { return "token", nil }}
+	j := &JSONWebKeys{Fetcher: fetcher}
+	for i := 0; i < 2; i++ {
+		certs, err := j.GetKeys(ForceRefresh())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := certs.Get(testKid); !ok || certs.Source != "gs://keys/idp/jwks.json" {
+			t.Fatalf("unexpected certs %+v", certs)
+		}
+	}
+	if atomic.LoadInt32(requests) != 2 || atomic.LoadInt32(downloads) != 1 {
+		t.Fatalf("expecting a conditional GET, got %d requests and %d downloads",
+			atomic.LoadInt32(requests), atomic.LoadInt32(downloads))
+	}
+
+	notFound := httptest.NewServer(http.NotFoundHandler())
+	defer notFound.Close()
+	missing := &GCSFetcher{Bucket: "keys", Object: "missing.json", Endpoint: notFound.URL}
+	var status *StatusError
+	if _, _, err := missing.Fetch(context.Background()); !errors.As(err, &status) {
+		t.Fatalf("expecting a StatusError, got %v", err)
+	}
+}
@@ -1,5 +1,11 @@
 module github.com/serjlee/jwk-go
 
-go 1.13
+go 1.20
 
-require github.com/pkg/errors v0.8.1
+require (
+	github.com/fxamacker/cbor/v2 v2.5.0
+	github.com/go-jose/go-jose/v3 v3.0.5
+	golang.org/x/crypto v0.19.0
+)
+
+require github.com/x448/float16 v0.8.4 // indirect
@@ -0,0 +1,222 @@
+// Package grpcsource distributes JWKS documents over gRPC, for environments with push-based config distribution:
+// its Fetcher is a jwk.StreamingFetcher receiving every new document as soon as it's published to a Server, so
+// that key rotations are instant instead of waiting for a refresh. It's a separate module, so that the jwk package
+// doesn't depend on gRPC.
+//
+// The service is described by keydistribution.proto, its messages being well-known types so that no generated
+// code is needed
+package grpcsource
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	jwk "github.com/serjlee/jwk-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// serviceName is the full name of the KeyDistribution service
+const serviceName = "jwk.v1.KeyDistribution"
+
+var _ jwk.StreamingFetcher = &Fetcher{}
+
+// Fetcher fetches the JWKS document from a KeyDistribution service, and receives its new versions as they are
+// published when RunRefresher is running
+type Fetcher struct {
+	// Conn is the connection to the KeyDistribution service
+	Conn grpc.ClientConnInterface
+
+	// CacheAge is how long the document is cached, DefaultCacheAge of JSONWebKeys when zero
+	CacheAge time.Duration
+
+	// RetryInterval is the delay before watching again once the stream breaks, 1 second by default
+	RetryInterval time.Duration
+}
+
+// Fetch implements jwk.Fetcher
+func (f *Fetcher) Fetch(ctx context.Context) ([]byte, time.Duration, error) {
+	document := &wrapperspb.BytesValue{}
+	if err := f.Conn.Invoke(ctx, "/"+serviceName+"/Get", &emptypb.Empty{}, document); err != nil {
+		return nil, 0, fmt.Errorf("unable to get key set: %w", err)
+	}
+	return document.Value, f.CacheAge, nil
+}
+
+// Subscribe implements jwk.StreamingFetcher, watching again after RetryInterval whenever the stream breaks
+func (f *Fetcher) Subscribe(ctx context.Context) <-chan []byte {
+	documents := make(chan []byte)
+	go func() {
+		defer close(documents)
+		for {
+			f.watch(ctx, documents)
+			retry := time.NewTimer(f.retryInterval())
+			select {
+			case <-ctx.Done():
+				retry.Stop()
+				return
+			case <-retry.C:
+			}
+		}
+	}()
+	return documents
+}
+
+// String describes the source of the certs
+func (f *Fetcher) String() string {
+	if conn, ok := f.Conn.(interface{ Target() string }); ok {
+		return "grpc://" + conn.Target()
+	}
+	return "grpc"
+}
+
+// watch delivers the documents streamed by the service until the stream breaks or ctx is done
+func (f *Fetcher) watch(ctx context.Context, documents chan<- []byte) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := f.Conn.NewStream(ctx, &serviceDesc.Streams[0], "/"+serviceName+"/Watch")
+	if err != nil {
+		return
+	}
+	if err := stream.SendMsg(&emptypb.Empty{}); err != nil {
+		return
+	}
+	if err := stream.CloseSend(); err != nil {
+		return
+	}
+	for {
+		document := &wrapperspb.BytesValue{}
+		if err := stream.RecvMsg(document); err != nil {
+			return
+		}
+		select {
+		case documents <- document.Value:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// retryInterval returns RetryInterval, or its default
+func (f *Fetcher) retryInterval() time.Duration {
+	if f.RetryInterval == 0 {
+		return time.Second
+	}
+	return f.RetryInterval
+}
+
+// Server is a reference implementation of the KeyDistribution service, serving the last published document
+type Server struct {
+	mutex    sync.Mutex
+	document []byte
+	watchers map[chan []byte]struct{}
+}
+
+// Register registers the service on the given gRPC server
+func (s *Server) Register(registrar grpc.ServiceRegistrar) {
+	registrar.RegisterService(&serviceDesc, s)
+}
+
+// Publish replaces the served document, pushing it to the watchers
+func (s *Server) Publish(document []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.document = document
+	for watcher := range s.watchers {
+		// only the latest document matters to a slow watcher
+		select {
+		case <-watcher:
+		default:
+		}
+		watcher <- document
+	}
+}
+
+// get returns the served document
+func (s *Server) get() (*wrapperspb.BytesValue, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.document == nil {
+		return nil, status.Error(codes.Unavailable, "no key set published yet")
+	}
+	return &wrapperspb.BytesValue{Value: s.document}, nil
+}
+
+// watch streams the served document, then the published ones, until the client goes away
+func (s *Server) watch(stream grpc.ServerStream) error {
+	watcher := make(chan []byte, 1)
+	s.mutex.Lock()
+	if s.watchers == nil {
+		s.watchers = map[chan []byte]struct{}{}
+	}
+	s.watchers[watcher] = struct{}{}
+	if s.document != nil {
+		watcher <- s.document
+	}
+	s.mutex.Unlock()
+	defer func() {
+		s.mutex.Lock()
+		delete(s.watchers, watcher)
+		s.mutex.Unlock()
+	}()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case document := <-watcher:
+			if err := stream.SendMsg(&wrapperspb.BytesValue{Value: document}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// keyDistributionServer is implemented by Server, as required by the service description
+type keyDistributionServer interface {
+	get() (*wrapperspb.BytesValue, error)
+	watch(stream grpc.ServerStream) error
+}
+
+// serviceDesc describes the KeyDistribution service of keydistribution.proto
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*keyDistributionServer)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Get",
+		Handler:    getHandler,
+	}},
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Watch",
+		Handler:       watchHandler,
+		ServerStreams: true,
+	}},
+	Metadata: "keydistribution.proto",
+}
+
+// getHandler serves the Get method
+func getHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	if err := dec(&emptypb.Empty{}); err != nil {
+		return nil, err
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(keyDistributionServer).get()
+	}
+	if interceptor == nil {
+		return handler(ctx, &emptypb.Empty{})
+	}
+	return interceptor(ctx, &emptypb.Empty{}, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + serviceName + "/Get"}, handler)
+}
+
+// watchHandler serves the Watch method
+func watchHandler(srv interface{}, stream grpc.ServerStream) error {
+	if err := stream.RecvMsg(&emptypb.Empty{}); err != nil {
+		return err
+	}
+	return srv.(keyDistributionServer).watch(stream)
+}
@@ -0,0 +1,82 @@
+package grpcsource
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	jwk "github.com/serjlee/jwk-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestDocument returns a JWKS document holding an EC key with the given kid
+func newTestDocument(t *testing.T, kid string) []byte {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := jwk.FromPublicKey(&private.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key.Kid, key.Use, key.Alg = kid, "sig", "ES256"
+	document, err := json.Marshal(map[string][]jwk.Key{"keys": {key}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return document
+}
+
+func TestFetcher(t *testing.T) {
+	listener := bufconn.Listen(1 << 20)
+	server := &Server{}
+	grpcServer := grpc.NewServer()
+	server.Register(grpcServer)
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.Dial("bufnet", grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	j := &jwk.JSONWebKeys{Fetcher: &Fetcher{Conn: conn}, KeyFilter: func(jwk.Key) bool { return true }}
+	if _, err := j.GetKeys(); err == nil {
+		t.Fatal("expecting an error before any document is published")
+	}
+	server.Publish(newTestDocument(t, "first"))
+	certs, err := j.GetKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := certs.Get("first"); !ok || certs.Source != "grpc://bufnet" {
+		t.Fatalf("unexpected certs %+v", certs)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go j.RunRefresher(ctx)
+	// published until received, as the refresher may not be watching yet
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		server.Publish(newTestDocument(t, "rotated"))
+		if _, err := j.GetKey("rotated"); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expecting the published document to be pushed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,77 @@
+package jwk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+// Introspection configures the RFC 7662 token introspection of opaque access tokens, see
+// https://tools.ietf.org/html/rfc7662
+type Introspection struct {
+	// Endpoint is the URL of the introspection endpoint of the authorization server
+	Endpoint string
+
+	// ClientID and ClientSecret authenticate the requests with HTTP basic authentication
+	ClientID     string
+	ClientSecret string
+}
+
+// introspect checks the given opaque token against the introspection endpoint, returning the claims of the
+// response when the token is active. The claims of the response are then validated as the ones of a JWT, so that
+// a token active for another issuer or resource server, or already expired, is rejected all the same
+func (j *JSONWebKeys) introspect(ctx context.Context, token string, opts []CallOption) (map[string]interface{}, error) {
+	if j.FetchTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, j.FetchTimeout)
+		defer cancel()
+	}
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, j.Introspection.Endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("unable to build introspection request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", j.userAgent())
+	// RFC 6749 section 2.3.1 form-encodes the credentials before the basic authentication
+	req.SetBasicAuth(url.QueryEscape(j.Introspection.ClientID), url.QueryEscape(j.Introspection.ClientSecret))
+
+	resp, err := j.httpClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token introspection failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if err := j.checkTLS(resp); err != nil {
+		return nil, fmt.Errorf("refusing %s: %w", j.Introspection.Endpoint, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{URL: j.Introspection.Endpoint, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	body, err := readBody(resp, j.maxDocumentSize())
+	if err != nil {
+		return nil, fmt.Errorf("unable to read introspection response: %w", err)
+	}
+
+	claims := map[string]interface{}{}
+	if err := json.Unmarshal(body, &claims); err != nil {
+		return nil, fmt.Errorf("unable to decode introspection response: %w", err)
+	}
+	if active, _ := claims["active"].(bool); !active {
+		return nil, errors.New("token is not active")
+	}
+	registered := jwt.Claims{}
+	if err := json.Unmarshal(body, &registered); err != nil {
+		return nil, fmt.Errorf("malformed introspection claims: %w", err)
+	}
+	if err := j.validateClaims(ctx, registered, claims, opts); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
@@ -0,0 +1,85 @@
+package jwk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+func TestIntrospection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, ok := r.BasicAuth(); !ok || id != "client" || secret != "s%C3%A9cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodPost || r.PostFormValue("token_type_hint") != "access_token" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		response := map[string]interface{}{"active": false}
+		switch r.PostFormValue("token") {
+		case "opaque-active":
+			response = map[string]interface{}{"active": true, "sub": "user", "scope": "read",
+				"iss": "https://issuer.example.com/", "aud": "api", "exp": time.Now().Add(time.Hour).Unix()}
+		case "opaque-other-audience":
+			response = map[string]interface{}{"active": true, "iss": "https://issuer.example.com/", "aud": "other"}
+		case "opaque-other-issuer":
+			response = map[string]interface{}{"active": true, "iss": "https://evil.example.com/", "aud": "api"}
+		case "opaque-expired":
+			response = map[string]interface{}{"active": true, "iss": "https://issuer.example.com/", "aud": "api",
+				"exp": time.Now().Add(-time.Hour).Unix()}
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	signer, j := newTestSigner(t, "test")
+	j.Introspection = &Introspection{Endpoint: server.URL, ClientID: "client", ClientSecret: "sécret"}
+	j.Issuer, j.Audience = "https://issuer.example.com/", "api"
+
+	claims, err := j.VerifyToken(context.Background(), "opaque-active")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims["sub"] != "user" {
+		t.Fatalf("unexpected claims %v", claims)
+	}
+	if _, err := j.VerifyToken(context.Background(), "opaque-revoked"); err == nil || !strings.Contains(err.Error(), "not active") {
+		t.Fatalf("expecting an inactive token, got %v", err)
+	}
+	j.RequiredClaims = []string{"client_id"}
+	if _, err := j.VerifyToken(context.Background(), "opaque-active"); err == nil || !strings.Contains(err.Error(), "missing client_id") {
+		t.Fatalf("expecting the required claims to be checked, got %v", err)
+	}
+	j.RequiredClaims = nil
+	for token, expected := range map[string]string{
+		"opaque-other-audience": "audience",
+		"opaque-other-issuer":   "issuer",
+		"opaque-expired":        "expired",
+	} {
+		if _, err := j.VerifyToken(context.Background(), token); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expecting an invalid %s for %s, got %v", expected, token, err)
+		}
+	}
+
+	// JWTs are still verified locally
+	raw := signTestToken(t, signer, jwt.Claims{
+		Issuer:   "https://issuer.example.com/",
+		Audience: jwt.Audience{"api"},
+		Expiry:   jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	})
+	if _, err := j.VerifyToken(context.Background(), raw); err != nil {
+		t.Fatal(err)
+	}
+
+	j.Introspection.ClientSecret = "wrong"
+	if _, err := j.VerifyToken(context.Background(), "opaque-active"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expecting the status to be reported, got %v", err)
+	}
+}
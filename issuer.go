@@ -0,0 +1,41 @@
+package jwk
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+// issuerPlaceholder matches the placeholders of a templated issuer, i.e. {tenantid}
+var issuerPlaceholder = regexp.MustCompile(`\{[^{}/]+\}`)
+
+// checkIssuer makes sure the iss claim is trusted: by IssuerValidator when set, by matching the expected,
+// possibly templated, issuer otherwise
+func (j *JSONWebKeys) checkIssuer(expected, iss string) error {
+	if j.IssuerValidator != nil {
+		if err := j.IssuerValidator(iss); err != nil {
+			return fmt.Errorf("issuer %q rejected: %w", iss, err)
+		}
+		return nil
+	}
+	if expected == "" || matchIssuer(expected, iss) {
+		return nil
+	}
+	return jwt.ErrInvalidIssuer
+}
+
+// matchIssuer tells whether iss matches the given issuer template, where each placeholder stands for a non-empty
+// string holding no slash nor dot
+func matchIssuer(template, iss string) bool {
+	if !strings.Contains(template, "{") {
+		return template == iss
+	}
+	literals := issuerPlaceholder.Split(template, -1)
+	for i, literal := range literals {
+		literals[i] = regexp.QuoteMeta(literal)
+	}
+	pattern, err := regexp.Compile("^" + strings.Join(literals, "[^/.]+") + "$")
+	return err == nil && pattern.MatchString(iss)
+}
@@ -0,0 +1,64 @@
+package jwk
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+func TestMatchIssuer(t *testing.T) {
+	azure := "https://login.microsoftonline.com/{tenantid}/v2.0"
+	tests := []struct {
+		template, iss string
+		match         bool
+	}{
+		{"https://issuer.example.com/", "https://issuer.example.com/", true},
+		{"https://issuer.example.com/", "https://issuer.example.com", false},
+		{azure, "https://login.microsoftonline.com/9188040d-6c67-4c5b-b112-36a304b66dad/v2.0", true},
+		{azure, "https://login.microsoftonline.com//v2.0", false},
+		{azure, "https://login.microsoftonline.com/a/b/v2.0", false},
+		{azure, "https://login.microsoftonline.comX/tenant/v2.0", false},
+		{"https://{tenant}.auth.example.com/", "https://acme.auth.example.com/", true},
+		{"https://{tenant}.auth.example.com/", "https://evil.com/.auth.example.com/", false},
+	}
+	for _, test := range tests {
+		if match := matchIssuer(test.template, test.iss); match != test.match {
+			t.Errorf("expecting %v matching %q against %q, got %v", test.match, test.iss, test.template, match)
+		}
+	}
+}
+
+func TestVerifyTokenIssuer(t *testing.T) {
+	signer, j := newTestSigner(t, "test")
+	expiry := jwt.NewNumericDate(time.Now().Add(time.Hour))
+	tenant := signTestToken(t, signer, jwt.Claims{Issuer: "https://login.microsoftonline.com/tenant-a/v2.0", Expiry: expiry})
+	other := signTestToken(t, signer, jwt.Claims{Issuer: "https://login.microsoftonline.com/tenant-b/v2.0", Expiry: expiry})
+
+	j.Issuer = "https://login.microsoftonline.com/{tenantid}/v2.0"
+	for _, raw := range []string{tenant, other} {
+		if _, err := j.VerifyToken(context.Background(), raw); err != nil {
+			t.Fatal(err)
+		}
+	}
+	j.Issuer = "https://issuer.example.com/"
+	if _, err := j.VerifyToken(context.Background(), tenant); err == nil || !strings.Contains(err.Error(), "issuer") {
+		t.Fatalf("expecting an invalid issuer, got %v", err)
+	}
+
+	j.IssuerValidator = func(iss string) error {
+		if iss != "https://login.microsoftonline.com/tenant-a/v2.0" {
+			return errors.New("unknown tenant")
+		}
+		return nil
+	}
+	if _, err := j.VerifyToken(context.Background(), tenant); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.VerifyToken(context.Background(), other); err == nil || !strings.Contains(err.Error(), "unknown tenant") {
+		t.Fatalf("expecting the validator error, got %v", err)
+	}
+}
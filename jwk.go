@@ -37,49 +37,292 @@
 package jwk
 
 import (
+	"context"
 	"crypto/rsa"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"math/big"
+	"math/rand"
+	"net"
 	"net/http"
+	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
-
-	"github.com/pkg/errors"
 )
 
 // Certs holds a map of KeyID-RSA public key and their expiration time
 type Certs struct {
+	// Keys maps the kids to their key.
+	//
+	// Deprecated: read the keys with Get, Range or ToSlice. Keys is kept for compatibility, GetKeys handing out
+	// copies so that changing it never affects the cached certs
 	Keys   map[string]Key
 	Expiry time.Time
+
+	// Report describes how the key set was parsed, listing the keys that were skipped and why
+	Report ParseReport
+
+	// Source is the URL the certs were fetched from: JSONWebKeys.JWKURL or one of its mirrors
+	Source string
+
+	// Fallback tells that the certs come from JSONWebKeys.FallbackKeys, as no fetch succeeded yet
+	Fallback bool
+
+	// Provenance maps the kids to the source of their key, when merged from several ones by a CompositeFetcher
+	Provenance map[string]string
+
+	// all holds every well-formed key of the document, in order, including the ones left out of Keys
+	all []Key
+
+	// chains memoizes the decoded x5c chains, shared by the copies of the certs handed out
+	chains *chainCache
+
+	// exposures lists the keys of a remote document refused for exposing private members
+	exposures []KeyExposure
+}
+
+// Get returns the key with the given kid, if any
+func (c Certs) Get(kid string) (Key, bool) {
+	key, ok := c.Keys[kid]
+	return key, ok
+}
+
+// Range calls fn for each key, sorted by kid, until it returns false
+func (c Certs) Range(fn func(key Key) bool) {
+	for _, key := range c.ToSlice() {
+		if !fn(key) {
+			return
+		}
+	}
+}
+
+// Kids returns the sorted kids of the keys
+func (c Certs) Kids() []string {
+	kids := make([]string, 0, len(c.Keys))
+	for kid := range c.Keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+	return kids
 }
 
-// ToSlice returns the keys in a slice
+// clone returns a copy of the certs not sharing their keys map, so that it can be handed out
+func (c *Certs) clone() *Certs {
+	cloned := *c
+	cloned.Keys = make(map[string]Key, len(c.Keys))
+	for kid, key := range c.Keys {
+		cloned.Keys[kid] = key
+	}
+	cloned.all = append([]Key(nil), c.all...)
+	if c.Provenance != nil {
+		cloned.Provenance = make(map[string]string, len(c.Provenance))
+		for kid, source := range c.Provenance {
+			cloned.Provenance[kid] = source
+		}
+	}
+	return &cloned
+}
+
+// ToSlice returns the keys in a slice, sorted by kid so that the same set always comes in the same order
 func (c Certs) ToSlice() []Key {
-	keys := []Key{}
+	keys := make([]Key, 0, len(c.Keys))
 	for _, v := range c.Keys {
 		keys = append(keys, v)
 	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Kid < keys[j].Kid
+	})
+	return keys
+}
+
+// Len returns the number of keys
+func (c Certs) Len() int {
+	return len(c.Keys)
+}
+
+// KeysForAlg returns the keys, sorted by kid, able to verify the tokens signed with the given alg
+func (c Certs) KeysForAlg(alg string) []Key {
+	keys := []Key{}
+	for _, key := range c.ToSlice() {
+		if checkAlg(alg, key) == nil {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// SigningKeys returns the keys, sorted by kid, meant for signatures: the ones without use enc nor key_ops ruling
+// out verification
+func (c Certs) SigningKeys() []Key {
+	keys := []Key{}
+	for _, key := range c.ToSlice() {
+		if key.Use != "enc" && (len(key.KeyOps) == 0 || containsString(key.KeyOps, "verify")) {
+			keys = append(keys, key)
+		}
+	}
 	return keys
 }
 
+// AllKeys returns every well-formed key of the fetched document in order, including the ones left out of Keys
+// by the key filter, so that keys of another use don't need a second fetch. Certs built by hand return their Keys
+func (c Certs) AllKeys() []Key {
+	if c.all == nil {
+		return c.ToSlice()
+	}
+	return append([]Key{}, c.all...)
+}
+
+// EncryptionKeys returns the encryption keys of the fetched document, the ones with use enc or key_ops allowing
+// encrypt or wrapKey
+func (c Certs) EncryptionKeys() []Key {
+	keys := []Key{}
+	for _, key := range c.AllKeys() {
+		if key.Use == "enc" || containsString(key.KeyOps, "encrypt") || containsString(key.KeyOps, "wrapKey") {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// MarshalJWKS encodes the keys as an RFC 7517 JSON Web Key Set with keys sorted by kid, so that the same set
+// always produces the same document. Only public members are emitted
+func (c Certs) MarshalJWKS() ([]byte, error) {
+	keys := c.ToSlice()
+	for i := range keys {
+		keys[i] = keys[i].public()
+	}
+	return json.Marshal(jwks{Keys: keys})
+}
+
+// missingKeyError describes why no key was found for the given kid, pointing out when it was skipped
+func (c Certs) missingKeyError(kid string) error {
+	for _, skipped := range c.Report.Skipped {
+		if skipped.Key.Kid == kid {
+			return &KeyNotFoundError{Kid: kid, Reason: skipped.Reason}
+		}
+	}
+	for _, keyErr := range c.Report.Errors {
+		if keyErr.Kid == kid {
+			return &KeyNotFoundError{Kid: kid, Err: keyErr.Err}
+		}
+	}
+	return &KeyNotFoundError{Kid: kid}
+}
+
 // jwks maps a JSON Web Key Store to a struct
 type jwks struct {
 	Keys []Key `json:"keys"`
+
+	// refreshHint is the spiffe_refresh_hint of a SPIFFE bundle, in seconds
+	refreshHint int64
 }
 
 // Key maps a JSON Web Key to a struct
 type Key struct {
-	// alg is the algorithm: it's currently ignored: only RSA is supported
-	Alg string   `json:"alg"`
+	// Alg is the algorithm the key is used with, checked against the alg of the tokens when set
+	Alg string   `json:"alg,omitempty"`
 	Kty string   `json:"kty"`
-	Kid string   `json:"kid"`
-	Use string   `json:"use"`
-	N   string   `json:"n"`
-	E   string   `json:"e"`
-	X5c []string `json:"x5c"`
+	Kid string   `json:"kid,omitempty"`
+	Use string   `json:"use,omitempty"`
+	N   string   `json:"n,omitempty"`
+	E   string   `json:"e,omitempty"`
+	X5c []string `json:"x5c,omitempty"`
+	X5t string   `json:"x5t,omitempty"`
+
+	// KeyOps lists the operations the key is intended for, e.g. "verify"
+	KeyOps []string `json:"key_ops,omitempty"`
+
+	// Crv, X and Y hold the public members of EC and OKP keys
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+
+	// Extra holds any member not modeled by the fields above (e.g. x5t#S256 or vendor extensions),
+	// so that it survives a round trip through JSON
+	Extra map[string]json.RawMessage `json:"-"`
+
+	// SyntheticKid tells that Kid was not part of the document, but derived from the key thumbprint
+	SyntheticKid bool `json:"-"`
+}
+
+// keyMembers has the same fields of Key but none of its methods, to avoid recursing in its JSON (un)marshaling
+type keyMembers Key
+
+// knownMembers holds the JSON names of the members modeled by Key fields
+var knownMembers = func() map[string]bool {
+	known := map[string]bool{}
+	t := reflect.TypeOf(Key{})
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			known[name] = true
+		}
+	}
+	return known
+}()
+
+// privateMembers holds the JWK members carrying private or secret key material
+var privateMembers = []string{"d", "p", "q", "dp", "dq", "qi", "oth", "k"}
+
+// UnmarshalJSON decodes a JWK, collecting the members not modeled by Key in Extra
+func (k *Key) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*keyMembers)(k)); err != nil {
+		return err
+	}
+	members := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+	for name := range members {
+		if knownMembers[name] {
+			delete(members, name)
+		}
+	}
+	k.Extra = nil
+	if len(members) > 0 {
+		k.Extra = members
+	}
+	return nil
+}
+
+// MarshalJSON encodes the JWK along with the members held in Extra
+func (k Key) MarshalJSON() ([]byte, error) {
+	encoded, err := json.Marshal(keyMembers(k))
+	if err != nil || len(k.Extra) == 0 {
+		return encoded, err
+	}
+	members := map[string]json.RawMessage{}
+	if err := json.Unmarshal(encoded, &members); err != nil {
+		return nil, err
+	}
+	for name, value := range k.Extra {
+		if !knownMembers[name] {
+			members[name] = value
+		}
+	}
+	return json.Marshal(members)
+}
+
+// public returns a copy of the key without any private member held in Extra
+func (k Key) public() Key {
+	if len(k.Extra) == 0 {
+		return k
+	}
+	extra := map[string]json.RawMessage{}
+	for name, value := range k.Extra {
+		extra[name] = value
+	}
+	for _, name := range privateMembers {
+		delete(extra, name)
+	}
+	k.Extra = extra
+	return k
 }
 
 // Empty tells if the struct is empty
@@ -95,46 +338,377 @@ func (k Key) PEM() string {
 	return "-----BEGIN CERTIFICATE-----\n" + k.X5c[0] + "\n-----END CERTIFICATE-----"
 }
 
-// RSA returns the key as an rsa.PublicKey
+// RSA returns the key as an rsa.PublicKey. It panics for keys other than RSA ones, which the certs hold as well
+// since EC and Ed25519 keys are accepted: check Kty, or use PublicKey for any key type
 func (k Key) RSA() *rsa.PublicKey {
-	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	key, err := k.rsaPublicKey()
 	if err != nil {
 		panic(err)
 	}
+	return key
+}
+
+// rsaPublicKey decodes the key as an rsa.PublicKey, returning an error on malformed members
+func (k Key) rsaPublicKey() (*rsa.PublicKey, error) {
+	if k.Kty != "RSA" {
+		return nil, fmt.Errorf("the key %q is not an RSA key but a %q one", k.Kid, k.Kty)
+	}
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
 	e, err := base64.RawURLEncoding.DecodeString(k.E)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if exponent.BitLen() > 31 {
+		return nil, errors.New("RSA exponent too large")
 	}
 	return &rsa.PublicKey{
 		N: new(big.Int).SetBytes(n),
-		E: int(new(big.Int).SetBytes(e).Int64()),
-	}
+		E: int(exponent.Int64()),
+	}, nil
 }
 
-// JSONWebKeys fetches and caches RSA public keys from a given JSON Web Key Store
+// JSONWebKeys fetches and caches the RSA, EC and Ed25519 public keys of a given JSON Web Key Store
 // it currently expects the same shape of the default Auth0 Key Stores: with defined public keys
 // in the X5c fields
 type JSONWebKeys struct {
 	// JWKURL is the URL to the JWK definition, i.e.: https://YOUR_AUTH0_DOMAIN/.well-known/jwks.json
+	// The document is negotiated as application/jwk-set+json, also accepting a single JWK or the OpenID Connect
+	// or OAuth metadata of the issuer, whose jwks_uri is followed or whose inline jwks is read
 	JWKURL string
 
+	// Fetcher fetches the JWK definition in place of JWKURL and its mirrors, i.e. FileFetcher to read it from a
+	// mounted file. When it implements ChangeNotifier RunRefresher refreshes the certs as soon as it changes, and
+	// when it's a StreamingFetcher RunRefresher loads the documents it pushes
+	Fetcher Fetcher
+
 	// DefaultCacheAge is the default cache duration for certs, if the resource does not set a max-age cache header
 	// auth0 suggest about 10 hours, but the keys aren't currently expected to expire
 	// see https://github.com/auth0/node-jwks-rsa#caching
 	DefaultCacheAge time.Duration
 
-	// Client is the HTTP client used while fetching the certs. If unset it will default to a Client with a 10-seconds timeout
+	// Client is the HTTP client used while fetching the certs. If unset it will default to a Client with a 10-seconds timeout,
+	// built once for this instance. Neither Client nor the other settings are ever modified by JSONWebKeys
 	Client *http.Client
 
+	// DialContext and Resolver customize the connections of the default client, i.e. to pin them to an interface,
+	// go through a SOCKS proxy or use an internal DNS. DialContext takes precedence over Resolver. Both are ignored
+	// when Client is set, and read once when the default client is built
+	DialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+	Resolver    *net.Resolver
+
+	// Transport carries the requests of the default client in place of http.DefaultTransport, i.e. the
+	// jwktest.Transport serving a handler in process, so that tests never reach the network. It takes precedence
+	// over DialContext and Resolver, and is ignored when Client is set
+	Transport http.RoundTripper
+
+	// MinTLSVersion is the lowest TLS version accepted when fetching the certs, tls.VersionTLS12 by default, i.e.
+	// tls.VersionTLS13 to require TLS 1.3. The default client doesn't negotiate lower versions, and the certs
+	// fetched through Client or Transport over lower versions are rejected: they are the root of trust of every
+	// token verification
+	MinTLSVersion uint16
+
+	// CipherSuites restricts the TLS 1.2 cipher suites accepted when fetching the certs, enforced as MinTLSVersion.
+	// The TLS 1.3 ones are not configurable. Go's default suites are accepted when empty
+	CipherSuites []uint16
+
+	// PinnedServerSPKI lists the keys the TLS server fetching the certs is expected to use, see SPKIHash, so that a
+	// compromised CA can't serve forged certs: the certs are rejected unless a certificate of the verified chain
+	// holds one of them. Pin a backup key as well, to survive the rotation of the server certificate. Enforced
+	// whatever the client on the fetches of JWKURL and of the Introspection endpoint, which can no longer go over
+	// plain HTTP when set. The discovery documents, the Fetcher sources, UserInfo and RevocationList make requests
+	// of their own, configure their Client to pin their servers
+	PinnedServerSPKI []string
+
+	// UserAgent is sent with the fetches, jwk-go/<version> by default
+	UserAgent string
+
+	// MaxDocumentSize caps the size in bytes of the fetched documents and introspection responses, both as
+	// received and once decompressed, 1 MiB by default. Larger ones fail with ErrDocumentTooLarge
+	MaxDocumentSize int64
+
+	// CorrelationHeader is the name of a header carrying a random ID, unique to each fetch, so that the access logs
+	// of the issuer can be matched with the fetch errors, which report it. No such header is sent by default
+	CorrelationHeader string
+
+	// FetchTimeout bounds each request fetching the certs, whatever the Client timeout, so that a shared client
+	// without timeout can't hang the fetches forever. It replaces the 10 seconds timeout of the default client.
+	// No bound other than the Client one by default
+	FetchTimeout time.Duration
+
+	// Issuer is the expected iss claim of the tokens checked by VerifyToken. If empty the issuer is not checked.
+	// It can hold placeholders matching a single path segment or host label, as in the multitenant Azure AD
+	// issuer: https://login.microsoftonline.com/{tenantid}/v2.0
+	Issuer string
+
+	// IssuerValidator checks the iss claim of the tokens checked by VerifyToken in place of Issuer, i.e. against
+	// an allowlist of tenants, returning an error when it's not trusted
+	IssuerValidator func(iss string) error
+
+	// Audience is the expected aud claim of the tokens checked by VerifyToken. If empty the audience is not checked
+	Audience string
+
+	// AudienceMatch tells how Audience, or the Audiences set by UpdateConfig, are matched against the aud claim:
+	// any of them being enough by default
+	AudienceMatch AudienceMatch
+
+	// RequiredClaims lists the claims the tokens checked by VerifyToken must hold, i.e. sub or azp
+	RequiredClaims []string
+
+	// ClaimValidator runs custom checks on the claims of the tokens checked by VerifyToken, such as tenancy or
+	// business rules, once the registered and required claims are validated. It returns an error to reject them
+	ClaimValidator func(claims map[string]interface{}) error
+
+	// Types lists the typ header values accepted by VerifyToken, i.e. at+jwt to accept only RFC 9068 access
+	// tokens and no ID token. Compared case-insensitively, with or without the application/ prefix. Any typ,
+	// or none, is accepted when empty
+	Types []string
+
+	// ContentTypes lists the cty header values accepted by VerifyToken, compared as Types. Any cty, or none,
+	// is accepted when empty
+	ContentTypes []string
+
+	// Introspection makes VerifyToken check the opaque tokens, which are not JWTs, with RFC 7662 token
+	// introspection. Opaque tokens are rejected when nil
+	Introspection *Introspection
+
+	// Revocation is consulted by VerifyToken once a token is found valid, rejecting it when revoked,
+	// i.e. a RevocationList. Nothing is revoked when nil
+	Revocation RevocationChecker
+
+	// Audit receives a record of every verification, valid or not, for compliance logging of the authentication
+	// decisions, see AuditSink. Nothing is recorded when nil
+	Audit AuditSink
+
+	// VerifiedCacheSize enables caching up to that many tokens verified by VerifyToken, by hash, so that a bearer
+	// token presented over and over is verified once. The claims are still validated on each call, and entries
+	// are dropped at exp or as soon as the certs are refreshed. Disabled by default
+	VerifiedCacheSize int
+
+	// DecryptionKeys maps kids to the private keys decrypting nested JWTs, encrypted to this service once signed:
+	// VerifyToken decrypts their JWE layer before verifying the signed JWT within. The keys are the ones supported
+	// by go-jose, i.e. *rsa.PrivateKey, *ecdsa.PrivateKey or []byte. Encrypted tokens are rejected when empty
+	DecryptionKeys map[string]interface{}
+
+	// ParseMode tells how malformed keys are handled: Lenient (default) skips them, reporting them in Certs.Report,
+	// Strict rejects the whole key set
+	ParseMode ParseMode
+
+	// LenientBase64 accepts key members encoded with padded base64url or standard base64 (and x5c certificates
+	// encoded with base64url), as done by some homegrown issuers. It has no effect in Strict parse mode
+	LenientBase64 bool
+
+	// LenientNumbers accepts an e member sent as a JSON number, and exp, nbf and iat members or the
+	// spiffe_refresh_hint sent as strings, reporting them in Certs.Report.Warnings. It has no effect in Strict
+	// parse mode
+	LenientNumbers bool
+
+	// ThumbprintKids assigns the RFC 7638 SHA-256 thumbprint as kid to the keys missing one, flagging them
+	// with SyntheticKid, so that they are still addressable
+	ThumbprintKids bool
+
+	// DropX5c discards the x5c certificate chains once the keys are validated against them, cutting the memory
+	// held for issuers publishing multi-kilobyte chains, i.e. Azure AD or ADFS. The SHA-1 thumbprint of the leaf
+	// certificate is kept in x5t, so that tokens can still be matched by x5t, but Certificates, CertPool and PEM
+	// no longer return anything
+	DropX5c bool
+
+	// IgnoreCacheControl always caches the certs for DefaultCacheAge, whatever the cache-control header says
+	IgnoreCacheControl bool
+
+	// FetchRetries is the number of times a failed fetch is retried before giving up, none by default
+	FetchRetries int
+
+	// RetryBaseInterval is the wait before the first retry of a failed fetch, doubled at every further attempt
+	// up to RetryMaxInterval. They default to 1 second and 10 times RetryBaseInterval
+	RetryBaseInterval time.Duration
+	RetryMaxInterval  time.Duration
+
+	// RefreshInterval sets a fixed cache duration, whatever the response says, and makes RunRefresher refresh
+	// the certs at that exact cadence. Useful with issuers sending max-age=0 or absurd values
+	RefreshInterval time.Duration
+
+	// FailedRefreshInterval is how long RunRefresher waits before trying again after a failed refresh,
+	// 30 seconds by default
+	FailedRefreshInterval time.Duration
+
+	// MaxRetryAfter caps the delay a 429 response can hold off the fetches for, so that a bogus Retry-After
+	// header can't freeze the keys for days. 20 times FailedRefreshInterval by default
+	MaxRetryAfter time.Duration
+
+	// NoCache fetches the certs on every lookup, for short-lived tools where caching is pointless but
+	// rotations must be seen right away. Concurrent lookups still share a single fetch
+	NoCache bool
+
+	// Redirects restricts the redirects followed while fetching the certs, on top of Client. If nil the redirects
+	// are left to Client, which by default follows up to 10 of them anywhere
+	Redirects *RedirectPolicy
+
+	// MirrorURLs are tried in order when fetching from JWKURL fails, i.e. an internal caching proxy or a copy
+	// on object storage. Certs.Source tells which one served the certs
+	MirrorURLs []string
+
+	// FallbackKeys is a JWKS document, usually embedded at build time, whose keys are served while no fetch has
+	// succeeded yet, i.e. when starting during an outage of the issuer. They are flagged as Fallback in Certs and
+	// kept for FailedRefreshInterval at a time, until a fetch succeeds and replaces them for good
+	FallbackKeys []byte
+
+	// ExpiryJitter randomly shifts the cache duration of each fetch by up to this fraction of it, both ways
+	// (i.e. 0.1 for ±10%), so that a fleet of services started together doesn't refresh in lockstep
+	ExpiryJitter float64
+
+	// OnChange is called, in its own goroutine, whenever a refresh changes the cached key set. The first fetch
+	// isn't reported. See WebhookNotifier for posting the changes to a webhook
+	OnChange func(change KeySetChange)
+
+	// OnKeyExposure is called, in its own goroutine, for each fetched key holding private members, i.e. d or k: a
+	// security incident on the issuer side, as those were published. Such keys are refused whatever KeyFilter
+	// says, and their private members are never cached nor reported
+	OnKeyExposure func(exposure KeyExposure)
+
+	// RefreshUnknownKids refreshes the cached certs when looking up a kid they don't hold, to pick up rotated keys
+	// before the cache expires. A kid still missing afterwards is remembered for MissingKidTTL, 1 minute by
+	// default, during which its lookups fail right away without refreshing. Refreshes for unknown kids are at
+	// least UnknownKidRefreshInterval apart, 10 seconds by default, so that a flood of random kids can't hammer
+	// the issuer either
+	RefreshUnknownKids        bool
+	MissingKidTTL             time.Duration
+	UnknownKidRefreshInterval time.Duration
+
+	// Rotations shares the kids found by RefreshUnknownKids with the rest of a fleet, i.e. over Redis pub/sub:
+	// RunRefresher refreshes right away when another instance publishes a kid missing from the cached certs,
+	// throttled by MissingKidTTL and UnknownKidRefreshInterval like the unknown kids of the tokens. The
+	// subscription is skipped when it fails
+	Rotations RotationBus
+
+	// SPIFFE reads the document as a SPIFFE bundle: the jwt-svid keys are kept in place of the sig ones, and the
+	// spiffe_refresh_hint member, when present, is used as cache duration instead of the response headers
+	SPIFFE bool
+
+	// KeyFilter picks the fetched keys to keep in place of the default filter, which keeps the RSA, EC and Ed25519
+	// signature keys, or the jwt-svid ones with SPIFFE. It can keep the
+	// encryption keys or select on vendor-specific members, but oct keys are refused from remote sources anyway
+	KeyFilter func(key Key) bool
+
 	// cachedCerts holds the latest fetched certs
 	cachedCerts *Certs
 
+	// static holds the certs loaded by FromJSON or FromFile, served in place of any fetch
+	static *Certs
+
+	// verified caches the tokens verified by VerifyToken, when VerifiedCacheSize is set
+	verified verifiedCache
+
 	// certsMutex ensures no data races while reading and storing the JWKs
 	certsMutex sync.RWMutex
+
+	// fetchMutex serializes fetches, without blocking the readers of the cached certs meanwhile
+	fetchMutex sync.Mutex
+
+	// flight is the fetch in progress in NoCache mode, shared by concurrent lookups and guarded by flightMutex
+	flight      *flightCall
+	flightMutex sync.Mutex
+
+	// rateLimitedUntil is when the fetches can resume after a 429 response, guarded by certsMutex
+	rateLimitedUntil time.Time
+
+	// watchers receive the events of Watch, guarded by watchMutex along with expiredCerts and stopWatchRefresher
+	watchers           map[*watcher]struct{}
+	expiredCerts       *Certs
+	stopWatchRefresher context.CancelFunc
+	watchMutex         sync.Mutex
+
+	// status is returned by RefreshStatus, guarded by statusMutex
+	status      RefreshStatus
+	statusMutex sync.Mutex
+
+	// refreshers counts the running RunRefresher loops
+	refreshers int32
+
+	// closed is set by Close, which waits for the workers and cancels the lifecycle context.
+	// closeMutex makes sure no worker starts while closing
+	closed          int32
+	closeMutex      sync.Mutex
+	workers         sync.WaitGroup
+	lifecycleCtx    context.Context
+	cancelLifecycle context.CancelFunc
+	lifecycleOnce   sync.Once
+
+	// missingKids maps the kids found missing after a refresh to when they can trigger a refresh again
+	missingKids  map[string]time.Time
+	missingMutex sync.Mutex
+
+	// lastKidRefresh is when an unknown kid last refreshed the certs, guarded by fetchMutex
+	lastKidRefresh time.Time
+
+	// updatedConfig is the configuration set by UpdateConfig, guarded by certsMutex
+	updatedConfig *Config
+
+	// defaultClient is used when Client is unset, built by defaultClientOnce
+	defaultClient     *http.Client
+	defaultClientOnce sync.Once
+}
+
+// httpClient returns the client used for fetching the certs
+func (j *JSONWebKeys) httpClient() *http.Client {
+	if j.Client != nil {
+		return j.Client
+	}
+	j.defaultClientOnce.Do(func() {
+		timeout := time.Second * 10
+		if j.FetchTimeout > 0 {
+			timeout = j.FetchTimeout
+		}
+		client := &http.Client{Timeout: timeout, Transport: j.Transport}
+		if client.Transport == nil {
+			client.Transport = j.defaultTransport()
+		}
+		j.defaultClient = client
+	})
+	return j.defaultClient
+}
+
+// dialContext returns the dial function of the default client, nil to keep the default one
+func (j *JSONWebKeys) dialContext() func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if j.DialContext != nil {
+		return j.DialContext
+	}
+	if j.Resolver != nil {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Resolver: j.Resolver}
+		return dialer.DialContext
+	}
+	return nil
+}
+
+// GetKeys returns the public keys of the JWK store. The certs are a copy, which the caller is free to change
+func (j *JSONWebKeys) GetKeys(opts ...CallOption) (*Certs, error) {
+	certs, err := j.getKeys(context.Background(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	return certs.clone(), nil
 }
 
-// GetKeys returns RSA public keys from the JWK store
-func (j *JSONWebKeys) GetKeys() (*Certs, error) {
+// getKeys returns the public keys of the JWK store, fetching them with the given context when needed
+func (j *JSONWebKeys) getKeys(ctx context.Context, opts ...CallOption) (*Certs, error) {
+	if j.isClosed() {
+		return nil, ErrClosed
+	}
+	options := newCallOptions(opts)
+	switch {
+	case options.certs != nil:
+		return options.certs, nil
+	case options.cacheBypass:
+		return j.exclusiveFetch(ctx)
+	case j.NoCache:
+		return j.sharedFetch(ctx)
+	case options.forceRefresh:
+		return j.refresh(ctx, true)
+	}
+
 	// Read from cache when defined and fresh
 	j.certsMutex.RLock()
 	certs := j.cachedCerts
@@ -146,55 +720,290 @@ func (j *JSONWebKeys) GetKeys() (*Certs, error) {
 	}
 
 	// Fetch and write cache when not
+	return j.refresh(ctx, false)
+}
+
+// refresh fetches, parses and caches the certs. Unless forced, it returns the certs cached by a concurrent
+// refresh while waiting for its turn, if still fresh
+func (j *JSONWebKeys) refresh(ctx context.Context, force bool) (*Certs, error) {
+	j.fetchMutex.Lock()
+	defer j.fetchMutex.Unlock()
+
+	if !force {
+		j.certsMutex.RLock()
+		certs := j.cachedCerts
+		j.certsMutex.RUnlock()
+		if certs != nil && time.Now().Before(certs.Expiry) {
+			return certs, nil
+		}
+	}
+
+	parsedCerts, err := j.fetchCerts(ctx)
+	if err != nil {
+		if parsedCerts = j.rateLimitedCerts(err); parsedCerts == nil {
+			if parsedCerts = j.fallbackCerts(); parsedCerts == nil {
+				return nil, err
+			}
+		}
+	}
+
+	j.storeCerts(parsedCerts)
+	return parsedCerts, nil
+}
+
+// storeCerts caches the given certs, reporting to OnChange how they differ from the previous ones
+func (j *JSONWebKeys) storeCerts(certs *Certs) {
+	// holding watchMutex keeps the watchers from missing or duplicating a change while subscribing
+	j.watchMutex.Lock()
+	defer j.watchMutex.Unlock()
+
 	j.certsMutex.Lock()
-	defer j.certsMutex.Unlock()
+	previous := j.cachedCerts
+	j.cachedCerts = certs
+	j.certsMutex.Unlock()
+
+	if previous == nil || (j.OnChange == nil && len(j.watchers) == 0) {
+		return
+	}
+	added, removed, changed := Diff(previous, certs)
+	if len(added) == 0 && len(removed) == 0 && len(changed) == 0 {
+		return
+	}
+	change := KeySetChange{
+		Source:  certs.Source,
+		Time:    time.Now(),
+		Added:   added,
+		Removed: removed,
+		Changed: changed,
+	}
+	j.notifyWatchers(watchUpdate{certs: certs, time: change.Time})
+	if j.OnChange != nil {
+		go j.OnChange(change)
+	}
+}
+
+// jitter randomly shifts d by up to the given fraction of it, both ways
+func jitter(d time.Duration, fraction float64) time.Duration {
+	return d + time.Duration((2*rand.Float64()-1)*fraction*float64(d))
+}
+
+// flightCall is a fetch shared by concurrent lookups, done is closed once certs and err are set
+type flightCall struct {
+	done  chan struct{}
+	certs *Certs
+	err   error
+}
+
+// sharedFetch fetches the certs without caching them, joining the fetch already in progress if any.
+// The shared fetch runs with the context of the lookup that started it
+func (j *JSONWebKeys) sharedFetch(ctx context.Context) (*Certs, error) {
+	j.flightMutex.Lock()
+	call := j.flight
+	if call == nil {
+		call = &flightCall{done: make(chan struct{})}
+		j.flight = call
+		j.flightMutex.Unlock()
+
+		call.certs, call.err = j.exclusiveFetch(ctx)
+		j.flightMutex.Lock()
+		j.flight = nil
+		j.flightMutex.Unlock()
+		close(call.done)
+		return call.certs, call.err
+	}
+	j.flightMutex.Unlock()
+
+	select {
+	case <-call.done:
+		return call.certs, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
 
-	res, cacheAge, err := j.fetchJWKS()
+// exclusiveFetch is fetchCerts holding fetchMutex, for the fetches not going through refresh, so that
+// they don't overlap with UpdateConfig
+func (j *JSONWebKeys) exclusiveFetch(ctx context.Context) (*Certs, error) {
+	j.fetchMutex.Lock()
+	defer j.fetchMutex.Unlock()
+	return j.fetchCerts(ctx)
+}
+
+// fetchCerts fetches and parses the certs, without caching them
+func (j *JSONWebKeys) fetchCerts(ctx context.Context) (*Certs, error) {
+	if j.static != nil {
+		return j.static, nil
+	}
+	if err := j.rateLimitedError(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := j.withLifecycle(ctx)
+	defer cancel()
+	body, cacheAge, origin, err := j.fetchWithRetries(ctx)
+	j.recordFetch(err)
 	if err != nil {
 		return nil, err
 	}
-
-	parsedCerts, err := parseCerts(res, cacheAge)
+	headerTTL := cacheAge
+	if j.RefreshInterval > 0 {
+		cacheAge = j.RefreshInterval
+	}
+	certs, err := j.buildCerts(body, cacheAge)
 	if err != nil {
 		return nil, err
 	}
+	certs.Source, certs.Provenance = origin.source, origin.provenance
+	certs.Report.HeaderTTL = headerTTL
+	j.reportExposures(certs)
+	return certs, nil
+}
 
-	j.cachedCerts = parsedCerts
+// buildCerts parses the given JWKS document into certs lasting cacheAge
+func (j *JSONWebKeys) buildCerts(body []byte, cacheAge time.Duration) (*Certs, error) {
+	res, report, err := parseJWKS(body, j.parseOptions())
+	if err != nil {
+		return nil, err
+	}
 
+	filter := acceptSigKey
+	if j.SPIFFE {
+		filter = acceptJWTSVIDKey
+		if res.refreshHint > 0 {
+			cacheAge = time.Duration(res.refreshHint) * time.Second
+		}
+	}
+	if j.KeyFilter != nil {
+		filter = customKeyFilter(j.KeyFilter)
+	}
+	if j.ExpiryJitter > 0 {
+		cacheAge = jitter(cacheAge, j.ExpiryJitter)
+	}
+	parsedCerts, err := filterCerts(res, cacheAge, refusePrivate(filter))
+	if err != nil {
+		return nil, err
+	}
+	parsedCerts.exposures = findExposures(res)
+	parsedCerts.Report.Total, parsedCerts.Report.Errors = report.Total, report.Errors
+	parsedCerts.Report.Warnings = report.Warnings
 	return parsedCerts, nil
 }
 
+// fetchWithRetries fetches the JWKS from the first source answering, retrying the sources up to FetchRetries
+// times with an exponential backoff. It returns the body, its cache age and the URL of the source
+func (j *JSONWebKeys) fetchWithRetries(ctx context.Context) ([]byte, time.Duration, origin, error) {
+	backoff := j.RetryBaseInterval
+	if backoff == 0 {
+		backoff = time.Second
+	}
+	maxBackoff := j.RetryMaxInterval
+	if maxBackoff == 0 {
+		maxBackoff = 10 * backoff
+	}
+
+	for attempt := 0; ; attempt++ {
+		body, cacheAge, origin, err := j.fetchFromSources(ctx)
+		var limited *RateLimitedError
+		if errors.As(err, &limited) {
+			return nil, 0, origin, err
+		}
+		if err == nil || attempt >= j.FetchRetries {
+			return body, cacheAge, origin, err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, 0, origin, err
+		case <-timer.C:
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
 // GetCertificate finds a matching cert for the given JWT
-func (j *JSONWebKeys) GetKey(keyId string) (Key, error) {
+func (j *JSONWebKeys) GetKey(keyId string, opts ...CallOption) (Key, error) {
+	return j.getKey(context.Background(), keyId, opts...)
+}
+
+// getKey finds a matching cert for the given key ID, fetching the certs with the given context when needed
+func (j *JSONWebKeys) getKey(ctx context.Context, keyId string, opts ...CallOption) (Key, error) {
 	var cert Key
-	certs, err := j.GetKeys()
+	certs, err := j.getKeys(ctx, opts...)
 	if err != nil {
 		return cert, err
 	}
 
 	var ok bool
 	if cert, ok = certs.Keys[keyId]; !ok {
-		return cert, errors.New("Unable to find the appropriate key.")
+		options := newCallOptions(opts)
+		if options.cacheBypass || options.forceRefresh {
+			return cert, certs.missingKeyError(keyId)
+		}
+		if certs, err = j.refreshForKid(ctx, keyId, certs); err != nil {
+			return cert, err
+		}
+		if cert, ok = certs.Keys[keyId]; !ok {
+			return cert, certs.missingKeyError(keyId)
+		}
 	}
 
 	return cert, nil
 }
 
-// fetchJWKS fetches and parses the JWKS resource from the given URL
-func (j *JSONWebKeys) fetchJWKS() (*jwks, time.Duration, error) {
-	if j.Client == nil {
-		j.Client = &http.Client{Timeout: time.Second * 10}
+// fetchJWKS fetches the JWKS resource from the given URL, returning its body and cache age
+func (j *JSONWebKeys) fetchJWKS(ctx context.Context, url string) ([]byte, time.Duration, error) {
+	return j.fetchDocument(ctx, url, true)
+}
+
+// fetchDocument fetches the given URL, returning the JWKS it holds and its cache age. When follow is set, a
+// metadata document is unwrapped by fetching its jwks_uri, see unwrapDocument
+func (j *JSONWebKeys) fetchDocument(ctx context.Context, url string, follow bool) (body []byte, cacheAge time.Duration, err error) {
+	if j.FetchTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, j.FetchTimeout)
+		defer cancel()
 	}
-	resp, err := j.Client.Get(j.JWKURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, 0, err
 	}
-	cacheControl := resp.Header.Get("cache-control")
-	if j.DefaultCacheAge == 0 {
-		j.DefaultCacheAge = time.Hour * 10
+	req.Header.Set("Accept", acceptJWKS)
+	req.Header.Set("User-Agent", j.userAgent())
+	if j.CorrelationHeader != "" {
+		id := newCorrelationID()
+		req.Header.Set(j.CorrelationHeader, id)
+		defer func() {
+			if err != nil {
+				err = fmt.Errorf("%s %s: %w", j.CorrelationHeader, id, err)
+			}
+		}()
 	}
-	cacheAge := j.DefaultCacheAge
-	if len(cacheControl) > 0 {
+	// asking for compression explicitly leaves decompression to readBody, whatever the transport does
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	client := j.httpClient()
+	if j.Redirects != nil {
+		client = j.Redirects.client(client)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	if err := j.checkTLS(resp); err != nil {
+		return nil, 0, fmt.Errorf("refusing %s: %w", url, err)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, 0, &RateLimitedError{URL: url, RetryAfter: j.retryAfter(resp)}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, &StatusError{URL: url, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	cacheControl := resp.Header.Get("cache-control")
+	cacheAge = j.defaultCacheAge()
+	if len(cacheControl) > 0 && !j.IgnoreCacheControl {
 		re := regexp.MustCompile("max-age=([0-9]*)")
 		match := re.FindAllStringSubmatch(cacheControl, -1)
 		if len(match) > 0 {
@@ -209,13 +1018,20 @@ func (j *JSONWebKeys) fetchJWKS() (*jwks, time.Duration, error) {
 		}
 	}
 
-	res := &jwks{}
-	err = json.NewDecoder(resp.Body).Decode(&res)
+	body, err = readBody(resp, j.maxDocumentSize())
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, fmt.Errorf("unable to read %s: %w", url, err)
 	}
 
-	return res, cacheAge, nil
+	return j.unwrapDocument(ctx, url, resp.Header.Get("Content-Type"), body, cacheAge, follow)
+}
+
+// defaultCacheAge returns DefaultCacheAge, or its default
+func (j *JSONWebKeys) defaultCacheAge() time.Duration {
+	if j.DefaultCacheAge == 0 {
+		return time.Hour * 10
+	}
+	return j.DefaultCacheAge
 }
 
 // withPEMHeaders adds the PEM headers to the given key
@@ -223,16 +1039,69 @@ func withPEMHeaders(key string) string {
 	return "-----BEGIN CERTIFICATE-----\n" + key + "\n-----END CERTIFICATE-----"
 }
 
-// parseCerts looks for RSA public keys
+// parseCerts looks for signature public keys, reporting the other ones as skipped
 func parseCerts(res *jwks, cacheAge time.Duration) (*Certs, error) {
+	return filterCerts(res, cacheAge, acceptSigKey)
+}
+
+// keyFilter returns the reason why a key is left out of the set, or an empty string when it's kept
+type keyFilter func(key Key) string
+
+// acceptSigKey keeps the RSA, EC and Ed25519 signature keys, EC ones included when on a curve added with
+// RegisterCurve
+func acceptSigKey(key Key) string {
+	switch {
+	case key.Kty == "oct":
+		return "oct keys are only accepted from local key sets"
+	case key.Kty == "OKP" && key.Crv != "Ed25519":
+		return fmt.Sprintf("curve %q is not meant for signatures", key.Crv)
+	case key.Kty != "RSA" && key.Kty != "EC" && key.Kty != "OKP":
+		return fmt.Sprintf("unsupported kty %q", key.Kty)
+	case key.Use != "sig":
+		return fmt.Sprintf("use %q is not sig", key.Use)
+	}
+	return ""
+}
+
+// SignatureKey is the default KeyFilter, keeping the RSA, EC and Ed25519 signature keys, EC ones included when on
+// a curve added with RegisterCurve. It can be combined in a custom KeyFilter
+func SignatureKey(key Key) bool {
+	return acceptSigKey(key) == ""
+}
+
+// customKeyFilter adapts a KeyFilter predicate, still refusing the oct keys
+func customKeyFilter(accept func(key Key) bool) keyFilter {
+	return func(key Key) string {
+		switch {
+		case key.Kty == "oct":
+			return "oct keys are only accepted from local key sets"
+		case !accept(key):
+			return "rejected by KeyFilter"
+		}
+		return ""
+	}
+}
+
+// filterCerts builds the certs out of the keys accepted by filter, reporting the other ones as skipped
+func filterCerts(res *jwks, cacheAge time.Duration, filter keyFilter) (*Certs, error) {
 	keys := map[string]Key{}
+	all := make([]Key, 0, len(res.Keys))
+	report := ParseReport{}
 	for _, key := range res.Keys {
-		if key.Use == "sig" && key.Kty == "RSA" {
-			keys[key.Kid] = key
+		if reason := filter(key); reason != "" {
+			report.Skipped = append(report.Skipped, SkippedKey{Key: key.public(), Reason: reason})
+			all = append(all, key.public())
+			continue
 		}
+		keys[key.Kid] = key
+		all = append(all, key)
 	}
+	report.Total, report.Kept = len(res.Keys), len(keys)
 	return &Certs{
 		Keys:   keys,
 		Expiry: time.Now().Add(cacheAge),
+		Report: report,
+		all:    all,
+		chains: &chainCache{},
 	}, nil
 }
@@ -1,12 +1,28 @@
 package jwk
 
 import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
-	"github.com/pkg/errors"
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
 )
 
 var testKid = "QzQ4QzExMzNENkJCMThDNjNCN0ZEQjQwQkEwNUFFMzY1NDU5QzcxNA"
@@ -76,14 +92,21 @@ func TestGetKeys(t *testing.T) {
 		return
 	}
 
-	if certs != cachedCerts {
-		t.Error("expecting same instance for cached certs")
+	if !certs.Expiry.Equal(cachedCerts.Expiry) {
+		t.Error("expecting the cached certs")
+	}
+
+	delete(certs.Keys, testKid)
+	if _, ok := j.cachedCerts.Get(testKid); !ok {
+		t.Error("expecting the cached certs to be left untouched by changes to a copy")
 	}
 }
 
 func TestGetKeysFromURL(t *testing.T) {
+	server, _ := newTestJWKSServer(t, "", 0)
+	defer server.Close()
 	j := &JSONWebKeys{
-		JWKURL: "https://login.arduino.cc/.well-known/jwks.json",
+		JWKURL: server.URL,
 	}
 
 	certs, err := j.GetKeys()
@@ -98,14 +121,23 @@ func TestGetKeysFromURL(t *testing.T) {
 		return
 	}
 
-	if certs != cachedCerts {
-		t.Error("expecting same instance for cached certs")
+	if !certs.Expiry.Equal(cachedCerts.Expiry) {
+		t.Error("expecting the cached certs")
+	}
+
+	delete(certs.Keys, testKid)
+	if _, ok := j.cachedCerts.Get(testKid); !ok {
+		t.Error("expecting the cached certs to be left untouched by changes to a copy")
 	}
 }
 
 func TestFetchKeysConcurrency(t *testing.T) {
+	server, requests := newTestJWKSServer(t, "", 0)
+	defer server.Close()
+	// the URL doesn't resolve: the requests are served in process by the server handler
 	j := &JSONWebKeys{
-		JWKURL: "https://login.arduino.cc/.well-known/jwks.json",
+		JWKURL:    "https://issuer.invalid/.well-known/jwks.json",
+		Transport: handlerTransport{server.Config.Handler},
 	}
 
 	concurrency := 1000
@@ -128,6 +160,20 @@ func TestFetchKeysConcurrency(t *testing.T) {
 	}
 
 	wg.Wait()
+	if atomic.LoadInt32(requests) != 1 {
+		t.Fatalf("expecting the concurrent lookups to share a single fetch, got %d", atomic.LoadInt32(requests))
+	}
+}
+
+// handlerTransport serves the requests with its handler, as jwktest.Transport, which can't be imported here
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (t handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorder := httptest.NewRecorder()
+	t.handler.ServeHTTP(recorder, req)
+	return recorder.Result(), nil
 }
 
 func TestWithPemHeaders(t *testing.T) {
@@ -154,6 +200,350 @@ func TestGetKey(t *testing.T) {
 	}
 }
 
+func TestGetKeyAllocs(t *testing.T) {
+	testCerts, err := getTestCerts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	j := &JSONWebKeys{cachedCerts: testCerts}
+	allocs := testing.AllocsPerRun(100, func() {
+		j.GetKey(testKid)
+	})
+	if allocs != 0 {
+		t.Fatalf("expecting no allocation for a cached lookup, got %v", allocs)
+	}
+}
+
+func TestToSlice(t *testing.T) {
+	certs := Certs{Keys: map[string]Key{"c": {Kid: "c"}, "a": {Kid: "a"}, "b": {Kid: "b"}, "d": {Kid: "d"}}}
+	for i := 0; i < 10; i++ {
+		keys := certs.ToSlice()
+		if len(keys) != 4 || keys[0].Kid != "a" || keys[1].Kid != "b" || keys[2].Kid != "c" || keys[3].Kid != "d" {
+			t.Fatalf("expecting the keys sorted by kid, got %v", keys)
+		}
+	}
+}
+
+func TestCertsLookups(t *testing.T) {
+	encKey := testKey
+	encKey.Kid, encKey.Use, encKey.Alg = "enc", "enc", "RSA-OAEP"
+	certs := Certs{Keys: map[string]Key{
+		testKid: testKey,
+		"enc":   encKey,
+		"ec":    {Kty: "EC", Kid: "ec", Crv: "P-256", Use: "sig"},
+		"ops":   {Kty: "RSA", Kid: "ops", KeyOps: []string{"verify"}},
+	}}
+	if certs.Len() != 4 {
+		t.Fatalf("expecting 4 keys, got %d", certs.Len())
+	}
+	if keys := certs.KeysForAlg("RS256"); len(keys) != 2 || keys[0].Kid != testKid || keys[1].Kid != "ops" {
+		t.Fatalf("unexpected RS256 keys %v", keys)
+	}
+	if keys := certs.KeysForAlg("ES256"); len(keys) != 1 || keys[0].Kid != "ec" {
+		t.Fatalf("unexpected ES256 keys %v", keys)
+	}
+	if keys := certs.SigningKeys(); len(keys) != 3 {
+		t.Fatalf("expecting all the keys but the enc one, got %v", keys)
+	}
+
+	if key, ok := certs.Get("ec"); !ok || key.Crv != "P-256" {
+		t.Fatalf("unexpected key %v", key)
+	}
+	if _, ok := certs.Get("unknown"); ok {
+		t.Fatal("expecting no key for an unknown kid")
+	}
+	if kids := certs.Kids(); !reflect.DeepEqual(kids, []string{testKid, "ec", "enc", "ops"}) {
+		t.Fatalf("unexpected kids %v", kids)
+	}
+	visited := []string{}
+	certs.Range(func(key Key) bool {
+		visited = append(visited, key.Kid)
+		return len(visited) < 2
+	})
+	if !reflect.DeepEqual(visited, []string{testKid, "ec"}) {
+		t.Fatalf("expecting Range to stop once fn returns false, visited %v", visited)
+	}
+}
+
+func TestMarshalJWKS(t *testing.T) {
+	certs := &Certs{Keys: map[string]Key{
+		"b":     {Kty: "RSA", Kid: "b", N: "AQAB", E: "AQAB"},
+		testKid: testKey,
+		"a":     {Kty: "EC", Kid: "a", Crv: "P-256", X: "AQAB", Y: "AQAB"},
+	}}
+	encoded, err := certs.MarshalJWKS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		again, err := certs.MarshalJWKS()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(again) != string(encoded) {
+			t.Fatal("expecting a deterministic output")
+		}
+	}
+
+	decoded := jwks{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Keys) != 3 || decoded.Keys[0].Kid != testKid || decoded.Keys[1].Kid != "a" || decoded.Keys[2].Kid != "b" {
+		t.Fatalf("unexpected keys order: %s", encoded)
+	}
+	if !reflect.DeepEqual(decoded.Keys[0], testKey) {
+		t.Fatal("round trip mismatch")
+	}
+
+	empty, err := (&Certs{}).MarshalJWKS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(empty) != `{"keys":[]}` {
+		t.Fatalf("unexpected empty set: %s", empty)
+	}
+}
+
+func TestKeyExtraMembers(t *testing.T) {
+	input := `{"kty":"RSA","kid":"k1","n":"AQAB","e":"AQAB","x5t#S256":"abc","exp":1700000000,"d":"secret"}`
+	key := Key{}
+	if err := json.Unmarshal([]byte(input), &key); err != nil {
+		t.Fatal(err)
+	}
+	if key.Kid != "k1" || len(key.Extra) != 3 || string(key.Extra["x5t#S256"]) != `"abc"` || string(key.Extra["exp"]) != "1700000000" {
+		t.Fatalf("unexpected key: %+v", key)
+	}
+
+	encoded, err := json.Marshal(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roundTrip := Key{}
+	if err := json.Unmarshal(encoded, &roundTrip); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(key, roundTrip) {
+		t.Fatalf("round trip mismatch: %s", encoded)
+	}
+
+	published, err := (&Certs{Keys: map[string]Key{"k1": key}}).MarshalJWKS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(published), "secret") || !strings.Contains(string(published), "x5t#S256") {
+		t.Fatalf("expecting only public members: %s", published)
+	}
+	if _, ok := key.Extra["d"]; !ok {
+		t.Fatal("MarshalJWKS must not alter the original key")
+	}
+}
+
+func TestParseCertsSkipped(t *testing.T) {
+	encKey := testKey
+	encKey.Kid, encKey.Use, encKey.Alg = "enc", "enc", "RSA-OAEP"
+	ecKey := Key{Kty: "EC", Kid: "ec", Use: "sig", Crv: "P-256"}
+	agreementKey := Key{Kty: "OKP", Kid: "x25519", Use: "sig", Crv: "X25519"}
+
+	certs, err := parseCerts(&jwks{Keys: []Key{testKey, encKey, ecKey, agreementKey}}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := certs.Keys["ec"]; len(certs.Keys) != 2 || !ok {
+		t.Fatalf("expecting the RSA and EC keys, got %v", certs.Keys)
+	}
+	skipped := certs.Report.Skipped
+	if len(skipped) != 2 || skipped[0].Reason != `use "enc" is not sig` ||
+		skipped[1].Reason != `curve "X25519" is not meant for signatures` {
+		t.Fatalf("unexpected skipped keys: %v", skipped)
+	}
+}
+
+func TestStandardSignatureKeys(t *testing.T) {
+	ecPrivate, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for alg, private := range map[jose.SignatureAlgorithm]crypto.Signer{jose.ES256: ecPrivate, jose.EdDSA: edPrivate} {
+		key, err := FromPublicKey(private.Public())
+		if err != nil {
+			t.Fatal(err)
+		}
+		key.Kid, key.Use = string(alg), "sig"
+		certs, err := parseCerts(&jwks{Keys: []Key{key}}, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signer, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: private},
+			(&jose.SignerOptions{}).WithHeader("kid", key.Kid))
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw := signTestToken(t, signer, jwt.Claims{Subject: "test"})
+		j := &JSONWebKeys{cachedCerts: certs}
+		if _, err := j.VerifySignature(context.Background(), raw); err != nil {
+			t.Fatalf("expecting the %s token to verify by default: %v", alg, err)
+		}
+	}
+}
+
+// newTestJWKSServer serves the test JWKS with the given cache-control header, failing the first requests.
+// It returns the server along with the counter of the requests it received
+func newTestJWKSServer(t *testing.T, cacheControl string, failures int32) (*httptest.Server, *int32) {
+	body, err := ioutil.ReadFile("testdata/jwks.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if cacheControl != "" {
+			w.Header().Set("Cache-Control", cacheControl)
+		}
+		w.Write(body)
+	}))
+	return server, &requests
+}
+
+func TestFetchRetries(t *testing.T) {
+	server, requests := newTestJWKSServer(t, "", 2)
+	defer server.Close()
+
+	j := &JSONWebKeys{JWKURL: server.URL, FetchRetries: 1, RetryBaseInterval: time.Millisecond}
+	if _, err := j.GetKeys(); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Fatalf("expecting the 503 status to be reported, got %v", err)
+	}
+	if atomic.LoadInt32(requests) != 2 {
+		t.Fatalf("expecting 2 requests, got %d", atomic.LoadInt32(requests))
+	}
+
+	atomic.StoreInt32(requests, 0)
+	j = &JSONWebKeys{JWKURL: server.URL, FetchRetries: 2, RetryBaseInterval: time.Millisecond}
+	if _, err := j.GetKeys(); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(requests) != 3 {
+		t.Fatalf("expecting 3 requests, got %d", atomic.LoadInt32(requests))
+	}
+}
+
+func TestIgnoreCacheControl(t *testing.T) {
+	server, _ := newTestJWKSServer(t, "public, max-age=60", 0)
+	defer server.Close()
+
+	j := &JSONWebKeys{JWKURL: server.URL}
+	certs, err := j.GetKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Until(certs.Expiry) > time.Minute {
+		t.Fatalf("expecting the max-age to be honored, got %s", certs.Expiry)
+	}
+
+	j = &JSONWebKeys{JWKURL: server.URL, IgnoreCacheControl: true, DefaultCacheAge: time.Hour}
+	certs, err = j.GetKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Until(certs.Expiry) < 59*time.Minute {
+		t.Fatalf("expecting the max-age to be ignored, got %s", certs.Expiry)
+	}
+}
+
+func TestExpiryJitter(t *testing.T) {
+	server, _ := newTestJWKSServer(t, "", 0)
+	defer server.Close()
+
+	j := &JSONWebKeys{JWKURL: server.URL, IgnoreCacheControl: true, DefaultCacheAge: time.Hour, ExpiryJitter: 0.5}
+	ttls := map[time.Duration]bool{}
+	for i := 0; i < 10; i++ {
+		certs, err := j.GetKeys(CacheBypass())
+		if err != nil {
+			t.Fatal(err)
+		}
+		ttl := time.Until(certs.Expiry).Round(time.Second)
+		if ttl < 30*time.Minute || ttl > 90*time.Minute {
+			t.Fatalf("expecting the jitter to be at most 50%%, got %v", ttl)
+		}
+		ttls[ttl] = true
+	}
+	if len(ttls) < 2 {
+		t.Fatal("expecting the cache durations to be jittered")
+	}
+}
+
+func TestFetchTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	j := &JSONWebKeys{JWKURL: server.URL, Client: &http.Client{}, FetchTimeout: 50 * time.Millisecond}
+	start := time.Now()
+	if _, err := j.GetKeys(); err == nil {
+		t.Fatal("expecting the fetch to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("expecting the fetch to be bounded by FetchTimeout, took %v", elapsed)
+	}
+}
+
+func TestDialContext(t *testing.T) {
+	server, _ := newTestJWKSServer(t, "", 0)
+	defer server.Close()
+
+	var dialed int32
+	j := &JSONWebKeys{
+		// the URL host is resolved by the custom dialer only
+		JWKURL: "http://jwks.internal/",
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			atomic.AddInt32(&dialed, 1)
+			return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+		},
+	}
+	if _, err := j.GetKey(testKid); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&dialed) != 1 {
+		t.Fatalf("expecting the custom dialer to be used, got %d dials", atomic.LoadInt32(&dialed))
+	}
+}
+
+func TestDefaultsNotMutated(t *testing.T) {
+	server, _ := newTestJWKSServer(t, "", 0)
+	defer server.Close()
+
+	// concurrent first uses of a zero-configured instance, checked by the race detector
+	j := &JSONWebKeys{JWKURL: server.URL}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := j.refresh(context.Background(), true); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if j.Client != nil || j.DefaultCacheAge != 0 {
+		t.Fatalf("expecting the settings to be left untouched, got %v and %v", j.Client, j.DefaultCacheAge)
+	}
+	if other := (&JSONWebKeys{}).httpClient(); other == j.httpClient() {
+		t.Fatal("expecting each instance to get its own default client")
+	}
+}
+
 func equalsRSAKeys(a, b map[string]Key, id string) error {
 
 	key, ok := a[id]
@@ -171,3 +561,146 @@ func equalsRSAKeys(a, b map[string]Key, id string) error {
 	}
 	return nil
 }
+
+func TestFetchHeaders(t *testing.T) {
+	var ids []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != "test-agent" {
+			t.Errorf("unexpected User-Agent %q", r.Header.Get("User-Agent"))
+		}
+		ids = append(ids, r.Header.Get("X-Request-Id"))
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	j := &JSONWebKeys{JWKURL: server.URL, UserAgent: "test-agent", CorrelationHeader: "X-Request-Id"}
+	_, err := j.GetKeys()
+	if len(ids) != 1 || len(ids[0]) != 32 {
+		t.Fatalf("expecting a correlation ID, got %v", ids)
+	}
+	if err == nil || !strings.Contains(err.Error(), "X-Request-Id "+ids[0]) {
+		t.Fatalf("expecting the correlation ID to be reported, got %v", err)
+	}
+
+	if ua := (&JSONWebKeys{}).userAgent(); !strings.HasPrefix(ua, "jwk-go/") {
+		t.Fatalf("unexpected default User-Agent %q", ua)
+	}
+}
+
+func TestKeyFilter(t *testing.T) {
+	encKey := testKey
+	encKey.Kid, encKey.Use, encKey.Alg = "enc", "enc", "RSA-OAEP"
+	octKey := Key{Kty: "oct", Kid: "oct", Extra: map[string]json.RawMessage{"k": json.RawMessage(`"c2VjcmV0"`)}}
+	body, err := json.Marshal(jwks{Keys: []Key{testKey, encKey, octKey}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	j := &JSONWebKeys{JWKURL: server.URL, KeyFilter: func(key Key) bool {
+		return SignatureKey(key) || key.Use == "enc"
+	}}
+	certs, err := j.GetKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := certs.Keys["enc"]; !ok || len(certs.Keys) != 2 {
+		t.Fatalf("expecting the sig and enc keys, got %v", certs.Keys)
+	}
+	if len(certs.Report.Skipped) != 1 || certs.Report.Skipped[0].Key.Kid != "oct" {
+		t.Fatalf("expecting the oct key to be skipped, got %+v", certs.Report.Skipped)
+	}
+}
+
+func TestAllKeys(t *testing.T) {
+	encKey := testKey
+	encKey.Kid, encKey.Use, encKey.Alg = "enc", "enc", "RSA-OAEP"
+	wrapKey := Key{Kty: "EC", Kid: "wrap", Crv: "P-256", KeyOps: []string{"wrapKey"}}
+	certs, err := filterCerts(&jwks{Keys: []Key{testKey, encKey, wrapKey}}, time.Hour, acceptSigKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(certs.Keys) != 1 {
+		t.Fatalf("expecting only the sig key to be kept, got %v", certs.Keys)
+	}
+	all := certs.AllKeys()
+	if len(all) != 3 || all[0].Kid != testKid || all[1].Kid != "enc" || all[2].Kid != "wrap" {
+		t.Fatalf("expecting every key in document order, got %v", all)
+	}
+	enc := certs.EncryptionKeys()
+	if len(enc) != 2 || enc[0].Kid != "enc" || enc[1].Kid != "wrap" {
+		t.Fatalf("expecting the enc and wrapKey keys, got %v", enc)
+	}
+
+	byHand := Certs{Keys: map[string]Key{testKid: testKey}}
+	if len(byHand.AllKeys()) != 1 {
+		t.Fatalf("expecting the keys of hand built certs, got %v", byHand.AllKeys())
+	}
+}
+
+func BenchmarkGetKey(b *testing.B) {
+	testCerts, err := getTestCerts()
+	if err != nil {
+		b.Fatal(err)
+	}
+	j := &JSONWebKeys{cachedCerts: testCerts}
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := j.GetKey(testKid); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkGetKeysExpiring(b *testing.B) {
+	body, err := ioutil.ReadFile("testdata/jwks.json")
+	if err != nil {
+		b.Fatal(err)
+	}
+	// the certs expire right away, so that every lookup refreshes them concurrently with the others
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=0")
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	j := &JSONWebKeys{JWKURL: server.URL}
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := j.GetKeys(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkGetKeyMiss(b *testing.B) {
+	testCerts, err := getTestCerts()
+	if err != nil {
+		b.Fatal(err)
+	}
+	j := &JSONWebKeys{cachedCerts: testCerts}
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := j.GetKey("unknown"); err == nil {
+				b.Fatal("expecting an unknown kid to be missing")
+			}
+		}
+	})
+}
+
+func TestKeyRSAOnOtherKeyTypes(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "not an RSA key") {
+			t.Errorf("expecting RSA to panic on an EC key, got %v", r)
+		}
+	}()
+	Key{Kty: "EC", Kid: "ec", Crv: "P-256"}.RSA()
+}
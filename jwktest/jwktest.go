@@ -0,0 +1,149 @@
+// Package jwktest serves key sets and signs tokens in process, so that the code verifying tokens with jwk-go
+// can be tested, race detector included, without reaching any issuer.
+//
+//	server := jwktest.NewServer()
+//	defer server.Close()
+//	keys := &jwk.JSONWebKeys{JWKURL: server.JWKURL(), Issuer: server.URL}
+//	token, _ := server.Token(jwt.Claims{Issuer: server.URL, Subject: "user"})
+//	claims, err := keys.VerifyToken(context.Background(), token)
+package jwktest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/serjlee/jwk-go"
+)
+
+const (
+	// JWKSPath is the path the key set is served at
+	JWKSPath = "/.well-known/jwks.json"
+
+	// DiscoveryPath is the path the OpenID Connect discovery document is served at
+	DiscoveryPath = "/.well-known/openid-configuration"
+
+	// Kid is the kid of the key generated by NewServer
+	Kid = "jwktest"
+)
+
+// Server is an issuer serving its key set and discovery document over HTTP on the loopback interface
+type Server struct {
+	*httptest.Server
+
+	mutex    sync.RWMutex
+	keys     []jwk.Key
+	signer   jose.Signer
+	requests int64
+}
+
+// NewServer starts a Server publishing a freshly generated RSA key, which Token signs with
+func NewServer() *Server {
+	s := &Server{}
+	if err := s.Rotate(Kid); err != nil {
+		panic(err)
+	}
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+// JWKURL returns the URL of the key set
+func (s *Server) JWKURL() string {
+	return s.URL + JWKSPath
+}
+
+// Requests returns the number of requests served so far
+func (s *Server) Requests() int {
+	return int(atomic.LoadInt64(&s.requests))
+}
+
+// SetKeys replaces the published keys. Token keeps signing with the last key generated by Rotate
+func (s *Server) SetKeys(keys ...jwk.Key) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.keys = append([]jwk.Key(nil), keys...)
+}
+
+// Rotate generates a new RSA key with the given kid, published along with the current ones and used by Token
+// from now on
+func (s *Server) Rotate(kid string) error {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return err
+	}
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: privateKey},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", kid),
+	)
+	if err != nil {
+		return err
+	}
+	key, err := jwk.FromPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return err
+	}
+	key.Kid, key.Alg, key.Use = kid, string(jose.RS256), "sig"
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.keys = append(s.keys, key)
+	s.signer = signer
+	return nil
+}
+
+// Token signs a JWT holding the given claims, i.e. a jwt.Claims or a map, with the last key generated by Rotate
+func (s *Server) Token(claims ...interface{}) (string, error) {
+	s.mutex.RLock()
+	builder := jwt.Signed(s.signer)
+	s.mutex.RUnlock()
+	for _, c := range claims {
+		builder = builder.Claims(c)
+	}
+	return builder.CompactSerialize()
+}
+
+// ServeHTTP serves the key set and the discovery document
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&s.requests, 1)
+	var document interface{}
+	switch r.URL.Path {
+	case JWKSPath:
+		s.mutex.RLock()
+		document = map[string][]jwk.Key{"keys": s.keys}
+		s.mutex.RUnlock()
+	case DiscoveryPath:
+		issuer := "http://" + r.Host
+		document = map[string]string{"issuer": issuer, "jwks_uri": issuer + JWKSPath}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(document)
+}
+
+// Transport returns a RoundTripper serving every request with the given handler, i.e. a Server, in process:
+// whatever their URL, requests never reach the network. Set it as JSONWebKeys.Transport
+func Transport(handler http.Handler) http.RoundTripper {
+	return handlerTransport{handler}
+}
+
+// handlerTransport serves the requests with its handler
+type handlerTransport struct {
+	handler http.Handler
+}
+
+// RoundTrip implements http.RoundTripper
+func (t handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorder := httptest.NewRecorder()
+	t.handler.ServeHTTP(recorder, req)
+	resp := recorder.Result()
+	resp.Request = req
+	return resp, nil
+}
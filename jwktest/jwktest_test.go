@@ -0,0 +1,79 @@
+package jwktest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/serjlee/jwk-go"
+)
+
+func TestServer(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	jwkURL, err := jwk.DiscoverJWKURL(nil, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jwkURL != server.JWKURL() {
+		t.Fatalf("unexpected jwks_uri %q", jwkURL)
+	}
+
+	keys := &jwk.JSONWebKeys{JWKURL: jwkURL, Issuer: server.URL, RefreshUnknownKids: true}
+	token, err := server.Token(jwt.Claims{Issuer: server.URL, Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keys.VerifyToken(context.Background(), token); err != nil {
+		t.Fatal(err)
+	}
+
+	// tokens signed after a rotation are verified once the keys are refreshed for the new kid
+	if err := server.Rotate("rotated"); err != nil {
+		t.Fatal(err)
+	}
+	token, err = server.Token(jwt.Claims{Issuer: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keys.VerifyToken(context.Background(), token); err != nil {
+		t.Fatal(err)
+	}
+
+	server.SetKeys()
+	certs, err := keys.GetKeys(jwk.ForceRefresh())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if certs.Len() != 0 {
+		t.Fatalf("expecting no key after SetKeys, got %v", certs.Kids())
+	}
+	if server.Requests() != 4 {
+		t.Fatalf("expecting 4 requests, got %d", server.Requests())
+	}
+}
+
+func TestTransport(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	// the URL doesn't resolve: the requests are served in process
+	keys := &jwk.JSONWebKeys{JWKURL: "https://issuer.invalid" + JWKSPath, Transport: Transport(server)}
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := keys.GetKey(Kid); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if server.Requests() != 1 {
+		t.Fatalf("expecting the concurrent lookups to share a single fetch, got %d", server.Requests())
+	}
+}
@@ -0,0 +1,69 @@
+// Package jwxadapter converts keys between the jwk package and github.com/lestrrat-go/jwx/v2/jwk, for teams
+// migrating between the two libraries or using jwx for signing and jwk for fetching and caching key sets.
+//
+// It lives in its own module so that the jwk package doesn't depend on jwx.
+package jwxadapter
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	jwkgo "github.com/serjlee/jwk-go"
+)
+
+// ToJWX converts a Key to a jwx key, keeping all of its members
+func ToJWX(key jwkgo.Key) (jwk.Key, error) {
+	encoded, err := json.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+	return jwk.ParseKey(encoded)
+}
+
+// FromJWX converts a jwx key to a Key. Private keys are converted to their public half, since Key
+// only models public members
+func FromJWX(key jwk.Key) (jwkgo.Key, error) {
+	public, err := key.PublicKey()
+	if err != nil {
+		return jwkgo.Key{}, err
+	}
+	encoded, err := json.Marshal(public)
+	if err != nil {
+		return jwkgo.Key{}, err
+	}
+	converted := jwkgo.Key{}
+	if err := json.Unmarshal(encoded, &converted); err != nil {
+		return jwkgo.Key{}, err
+	}
+	return converted, nil
+}
+
+// ToJWXSet converts keys, e.g. the ones returned by Certs.ToSlice, to a jwx set
+func ToJWXSet(keys []jwkgo.Key) (jwk.Set, error) {
+	set := jwk.NewSet()
+	for _, key := range keys {
+		converted, err := ToJWX(key)
+		if err != nil {
+			return nil, fmt.Errorf("unable to convert key %q: %w", key.Kid, err)
+		}
+		if err := set.AddKey(converted); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+// FromJWXSet converts all the keys of a jwx set, in set order
+func FromJWXSet(set jwk.Set) ([]jwkgo.Key, error) {
+	keys := make([]jwkgo.Key, 0, set.Len())
+	for i := 0; i < set.Len(); i++ {
+		key, _ := set.Key(i)
+		converted, err := FromJWX(key)
+		if err != nil {
+			return nil, fmt.Errorf("unable to convert key %q: %w", key.KeyID(), err)
+		}
+		keys = append(keys, converted)
+	}
+	return keys, nil
+}
@@ -0,0 +1,72 @@
+package jwxadapter
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	jwkgo "github.com/serjlee/jwk-go"
+)
+
+func TestSetRoundTrip(t *testing.T) {
+	body, err := ioutil.ReadFile("../testdata/jwks.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwxSet, err := jwk.Parse(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := FromJWXSet(jwxSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0].Kty != "RSA" || len(keys[0].X5c) != 1 {
+		t.Fatalf("unexpected keys: %+v", keys)
+	}
+
+	converted, err := ToJWXSet(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := FromJWXSet(converted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(keys, back) {
+		t.Fatalf("round trip mismatch: %+v != %+v", keys, back)
+	}
+}
+
+func TestFromJWXPrivateKey(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwxKey, err := jwk.FromRaw(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwxKey.Set(jwk.KeyIDKey, "ec")
+
+	key, err := FromJWX(jwxKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := jwkgo.FromPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected.Kid = "ec"
+	if !reflect.DeepEqual(key, expected) {
+		t.Fatalf("unexpected key: %+v", key)
+	}
+	if _, ok := key.Extra["d"]; ok {
+		t.Fatal("expecting the private member to be dropped")
+	}
+}
@@ -0,0 +1,163 @@
+package jwk
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"golang.org/x/crypto/scrypt"
+)
+
+// KeyStore persists the keys of a Rotator, so that a restarted publisher keeps signing with and publishing them
+type KeyStore interface {
+	// Load returns the stored keys, none when nothing was saved yet
+	Load() ([]StoredKey, error)
+
+	// Save replaces the stored keys
+	Save(keys []StoredKey) error
+}
+
+// StoredKey is a key managed by a Rotator
+type StoredKey struct {
+	Private crypto.Signer
+	Public  Key
+
+	// Retired is when the key stopped signing, zero for the current key
+	Retired time.Time
+}
+
+// FileKeyStore is a KeyStore keeping the keys in a file, encrypted with AES-256-GCM under a key derived from
+// Passphrase with scrypt
+type FileKeyStore struct {
+	Path       string
+	Passphrase []byte
+}
+
+// sealedKeys is the content of a FileKeyStore file
+type sealedKeys struct {
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// storedKeyJSON is the encrypted representation of a StoredKey
+type storedKeyJSON struct {
+	Private jose.JSONWebKey `json:"private"`
+	Public  Key             `json:"public"`
+	Retired time.Time       `json:"retired"`
+}
+
+// Load implements KeyStore
+func (s *FileKeyStore) Load() ([]StoredKey, error) {
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sealed := sealedKeys{}
+	if err := json.Unmarshal(data, &sealed); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", s.Path, err)
+	}
+	aead, err := s.aead(sealed.Salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce in %s", s.Path)
+	}
+	plaintext, err := aead.Open(nil, sealed.Nonce, sealed.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt %s: wrong passphrase or corrupted file", s.Path)
+	}
+
+	var stored []storedKeyJSON
+	if err := json.Unmarshal(plaintext, &stored); err != nil {
+		return nil, fmt.Errorf("unable to parse the keys of %s: %w", s.Path, err)
+	}
+	keys := make([]StoredKey, 0, len(stored))
+	for _, key := range stored {
+		private, ok := key.Private.Key.(crypto.Signer)
+		if !ok || key.Private.IsPublic() {
+			return nil, fmt.Errorf("the key %q of %s is not a private signing key", key.Public.Kid, s.Path)
+		}
+		if _, err := SigningKey(private, key.Public); err != nil {
+			return nil, fmt.Errorf("invalid key in %s: %w", s.Path, err)
+		}
+		keys = append(keys, StoredKey{Private: private, Public: key.Public, Retired: key.Retired})
+	}
+	return keys, nil
+}
+
+// Save implements KeyStore, replacing the file atomically
+func (s *FileKeyStore) Save(keys []StoredKey) error {
+	stored := make([]storedKeyJSON, 0, len(keys))
+	for _, key := range keys {
+		stored = append(stored, storedKeyJSON{
+			Private: jose.JSONWebKey{Key: key.Private, KeyID: key.Public.Kid, Algorithm: key.Public.Alg, Use: key.Public.Use},
+			Public:  key.Public,
+			Retired: key.Retired,
+		})
+	}
+	plaintext, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+
+	sealed := sealedKeys{Salt: make([]byte, 16)}
+	if _, err := rand.Read(sealed.Salt); err != nil {
+		return err
+	}
+	aead, err := s.aead(sealed.Salt)
+	if err != nil {
+		return err
+	}
+	sealed.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(sealed.Nonce); err != nil {
+		return err
+	}
+	sealed.Ciphertext = aead.Seal(nil, sealed.Nonce, plaintext, nil)
+	data, err := json.Marshal(sealed)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.Path), filepath.Base(s.Path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.Path)
+}
+
+// aead derives the key encrypting the file from Passphrase and the given salt
+func (s *FileKeyStore) aead(salt []byte) (cipher.AEAD, error) {
+	if len(s.Passphrase) == 0 {
+		return nil, errors.New("the key store has no passphrase")
+	}
+	key, err := scrypt.Key(s.Passphrase, salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,56 @@
+package jwk
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileKeyStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jwk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "keys.json")
+
+	now := time.Now()
+	store := &FileKeyStore{Path: path, Passphrase: []byte("secret")}
+	r := &Rotator{Store: store, Now: func() time.Time { return now }}
+	first, err := r.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := r.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), first.X) || strings.Contains(string(data), `"d"`) {
+		t.Error("expecting the keys to be encrypted at rest")
+	}
+
+	restarted := &Rotator{Store: &FileKeyStore{Path: path, Passphrase: []byte("secret")}, Now: r.Now}
+	_, current, err := restarted.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Kid != second.Kid {
+		t.Errorf("expecting the restarted rotator to sign with %q, got %q", second.Kid, current.Kid)
+	}
+	if keys, _ := restarted.Keys(); len(keys) != 2 || keys[0].Kid != first.Kid {
+		t.Errorf("expecting the retired key to stay published, got %v", keys)
+	}
+
+	wrong := &Rotator{Store: &FileKeyStore{Path: path, Passphrase: []byte("wrong")}}
+	if _, _, err := wrong.Current(); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Errorf("expecting a wrong passphrase to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package jwk
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// KidStrategy assigns the kid of a new key, given the keys already published, for publishers following the kid
+// conventions of their ecosystem
+type KidStrategy interface {
+	Kid(key Key, existing []Key) (string, error)
+}
+
+// ThumbprintKid uses the RFC 7638 SHA-256 thumbprint of the key as its kid, the default of jwkctl
+type ThumbprintKid struct{}
+
+// Kid implements KidStrategy
+func (ThumbprintKid) Kid(key Key, existing []Key) (string, error) {
+	return key.Thumbprint()
+}
+
+// UUIDKid uses a random RFC 4122 version 4 UUID as kid
+type UUIDKid struct{}
+
+// Kid implements KidStrategy
+func (UUIDKid) Kid(key Key, existing []Key) (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		return "", err
+	}
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:]), nil
+}
+
+// DateKid uses the current UTC date as kid, suffixed with -2, -3 and so on when several keys are created the same
+// day
+type DateKid struct {
+	// Layout formats the date, 2006-01-02 by default
+	Layout string
+
+	// Now returns the current time, time.Now by default
+	Now func() time.Time
+}
+
+// Kid implements KidStrategy
+func (d DateKid) Kid(key Key, existing []Key) (string, error) {
+	layout, now := d.Layout, time.Now
+	if layout == "" {
+		layout = "2006-01-02"
+	}
+	if d.Now != nil {
+		now = d.Now
+	}
+	date := now().UTC().Format(layout)
+	kid := date
+	for n := 2; hasKid(existing, kid); n++ {
+		kid = date + "-" + strconv.Itoa(n)
+	}
+	return kid, nil
+}
+
+// SequentialKid numbers the keys, using the number following the highest one among the kids with the same prefix
+type SequentialKid struct {
+	// Prefix is prepended to the number, i.e. key- for key-1, key-2 and so on
+	Prefix string
+}
+
+// Kid implements KidStrategy
+func (s SequentialKid) Kid(key Key, existing []Key) (string, error) {
+	last := 0
+	for _, k := range existing {
+		if !strings.HasPrefix(k.Kid, s.Prefix) {
+			continue
+		}
+		if n, err := strconv.Atoi(strings.TrimPrefix(k.Kid, s.Prefix)); err == nil && n > last {
+			last = n
+		}
+	}
+	return s.Prefix + strconv.Itoa(last+1), nil
+}
+
+// hasKid tells if one of the keys has the given kid
+func hasKid(keys []Key, kid string) bool {
+	for _, key := range keys {
+		if key.Kid == kid {
+			return true
+		}
+	}
+	return false
+}
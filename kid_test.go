@@ -0,0 +1,43 @@
+package jwk
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestKidStrategies(t *testing.T) {
+	thumbprint, err := testKey.Thumbprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := func() time.Time { return time.Date(2024, 3, 1, 23, 0, 0, 0, time.FixedZone("CET", 3600)) }
+	existing := []Key{{Kid: "2024-03-01"}, {Kid: "key-2"}, {Kid: "key-10"}, {Kid: "other-42"}}
+
+	for _, tc := range []struct {
+		name     string
+		strategy KidStrategy
+		expected string
+	}{
+		{"thumbprint", ThumbprintKid{}, thumbprint},
+		{"date", DateKid{Now: day}, "2024-03-01-2"},
+		{"date layout", DateKid{Layout: "20060102", Now: day}, "20240301"},
+		{"sequential", SequentialKid{Prefix: "key-"}, "key-11"},
+		{"first sequential", SequentialKid{Prefix: "signing-"}, "signing-1"},
+	} {
+		kid, err := tc.strategy.Kid(testKey, existing)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kid != tc.expected {
+			t.Errorf("unexpected %s kid %q", tc.name, kid)
+		}
+	}
+
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	first, _ := UUIDKid{}.Kid(testKey, nil)
+	second, _ := UUIDKid{}.Kid(testKey, nil)
+	if !uuid.MatchString(first) || first == second {
+		t.Fatalf("expecting random version 4 UUIDs, got %q and %q", first, second)
+	}
+}
@@ -0,0 +1,61 @@
+package jwk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"time"
+)
+
+const (
+	// jwkSetMediaType is the RFC 7517 media type of JWK sets
+	jwkSetMediaType = "application/jwk-set+json"
+
+	// jwkMediaType is the RFC 7517 media type of single JWKs
+	jwkMediaType = "application/jwk+json"
+
+	// acceptJWKS is the Accept header of the fetches, preferring JWK sets over generic JSON documents
+	acceptJWKS = jwkSetMediaType + ", " + jwkMediaType + ";q=0.9, application/json;q=0.8"
+)
+
+// unwrapDocument turns the fetched document into a JWKS according to its media type, or to its members when
+// served as generic JSON: a single JWK is wrapped in a set, and the jwks_uri of OpenID Connect or OAuth metadata
+// is fetched when follow is set, preferred to its inline jwks. Anything else is left to the parser
+func (j *JSONWebKeys) unwrapDocument(ctx context.Context, url, contentType string, body []byte, cacheAge time.Duration, follow bool) ([]byte, time.Duration, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case jwkSetMediaType:
+		return body, cacheAge, nil
+	case jwkMediaType:
+		return wrapKey(body), cacheAge, nil
+	}
+
+	doc := struct {
+		Keys    json.RawMessage `json:"keys"`
+		Kty     json.RawMessage `json:"kty"`
+		JWKSURI string          `json:"jwks_uri"`
+		JWKS    json.RawMessage `json:"jwks"`
+	}{}
+	if cleaned, _, err := cleanDocument(body, j.maxDocumentSize()); err != nil || json.Unmarshal(cleaned, &doc) != nil || doc.Keys != nil {
+		return body, cacheAge, nil
+	}
+	switch {
+	case doc.Kty != nil:
+		return wrapKey(body), cacheAge, nil
+	case doc.JWKSURI != "" && follow:
+		body, cacheAge, err := j.fetchDocument(ctx, doc.JWKSURI, false)
+		if err != nil {
+			return nil, 0, fmt.Errorf("unable to follow the jwks_uri of %s: %w", url, err)
+		}
+		return body, cacheAge, nil
+	case doc.JWKS != nil:
+		return doc.JWKS, cacheAge, nil
+	}
+	return body, cacheAge, nil
+}
+
+// wrapKey wraps a single JWK in a JWKS
+func wrapKey(key []byte) []byte {
+	return append(append([]byte(`{"keys":[`), key...), "]}"...)
+}
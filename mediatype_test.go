@@ -0,0 +1,61 @@
+package jwk
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMediaTypeNegotiation(t *testing.T) {
+	set, err := ioutil.ReadFile("testdata/jwks.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := json.Marshal(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var accept string
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	serve := func(contentType, body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			accept = r.Header.Get("Accept")
+			w.Header().Set("Content-Type", contentType)
+			w.Write([]byte(body))
+		}
+	}
+	mux.Handle("/set", serve("application/jwk-set+json", string(set)))
+	mux.Handle("/key", serve("application/jwk+json; charset=utf-8", string(key)))
+	mux.Handle("/untyped-key", serve("application/json", string(key)))
+	mux.Handle("/metadata", serve("application/json", `{"issuer":"`+server.URL+`","jwks_uri":"`+server.URL+`/set"}`))
+	mux.Handle("/inline", serve("application/json", `{"issuer":"`+server.URL+`","jwks":`+string(set)+`}`))
+	mux.Handle("/loop", serve("application/json", `{"jwks_uri":"`+server.URL+`/loop"}`))
+
+	for _, path := range []string{"/set", "/key", "/untyped-key", "/metadata", "/inline"} {
+		t.Run(path, func(t *testing.T) {
+			j := &JSONWebKeys{JWKURL: server.URL + path}
+			if _, err := j.GetKey(testKid); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(accept, "application/jwk-set+json,") {
+				t.Fatalf("expecting JWK sets to be preferred, got Accept %q", accept)
+			}
+		})
+	}
+
+	// a jwks_uri is followed once at most, the metadata found there holding no key
+	j := &JSONWebKeys{JWKURL: server.URL + "/loop"}
+	certs, err := j.GetKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if certs.Len() != 0 {
+		t.Fatalf("expecting no key, got %v", certs.Kids())
+	}
+}
@@ -0,0 +1,42 @@
+package jwk
+
+import (
+	"context"
+	"time"
+)
+
+// origin describes where a JWKS document comes from
+type origin struct {
+	// source is the URL of the document, or the description of the Fetcher
+	source string
+
+	// provenance maps the kids to the source of their key, when merged from several ones
+	provenance map[string]string
+}
+
+// fetchFromSources fetches the JWKS with Fetcher, when set, or from JWKURL, then from each of the mirrors in order
+// until one succeeds, returning the body, its cache age and its origin. When all fail the errors are reported
+// together
+func (j *JSONWebKeys) fetchFromSources(ctx context.Context) ([]byte, time.Duration, origin, error) {
+	if j.Fetcher != nil {
+		return j.fetchFromFetcher(ctx)
+	}
+	config := j.config()
+	sources := append([]string{config.JWKURL}, config.MirrorURLs...)
+
+	failures := make([]error, 0, len(sources))
+	for _, source := range sources {
+		body, cacheAge, err := j.fetchJWKS(ctx, source)
+		if err == nil {
+			return body, cacheAge, origin{source: source}, nil
+		}
+		failures = append(failures, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if len(failures) == 1 {
+		return nil, 0, origin{}, failures[0]
+	}
+	return nil, 0, origin{}, &SourcesError{Errs: failures}
+}
@@ -0,0 +1,34 @@
+package jwk
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestMirrorURLs(t *testing.T) {
+	primary, primaryRequests := newTestJWKSServer(t, "", 1000)
+	defer primary.Close()
+	mirror, _ := newTestJWKSServer(t, "", 0)
+	defer mirror.Close()
+
+	j := &JSONWebKeys{JWKURL: primary.URL, MirrorURLs: []string{mirror.URL}}
+	certs, err := j.GetKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if certs.Source != mirror.URL || atomic.LoadInt32(primaryRequests) != 1 {
+		t.Fatalf("expecting the mirror to serve the certs after the primary failed, got %q", certs.Source)
+	}
+
+	j = &JSONWebKeys{JWKURL: mirror.URL, MirrorURLs: []string{primary.URL}}
+	if certs, err = j.GetKeys(); err != nil || certs.Source != mirror.URL {
+		t.Fatalf("expecting the primary to serve the certs, got %q and %v", certs.Source, err)
+	}
+
+	j = &JSONWebKeys{JWKURL: primary.URL, MirrorURLs: []string{primary.URL + "/mirror"}}
+	_, err = j.GetKeys()
+	if err == nil || !strings.Contains(err.Error(), "all the JWKS sources failed") || !strings.Contains(err.Error(), "/mirror") {
+		t.Fatalf("expecting all the failures to be reported, got %v", err)
+	}
+}
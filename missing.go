@@ -0,0 +1,98 @@
+package jwk
+
+import (
+	"context"
+	"time"
+)
+
+// maxMissingKids bounds the kids remembered as missing, so that a flood of random kids can't grow it forever
+const maxMissingKids = 1024
+
+// refreshForKid refreshes the given certs, in which kid was looked up in vain, returning the certs to look it up
+// again in. Nothing is fetched when RefreshUnknownKids is unset, when kid is known to be missing, when a
+// concurrent refresh already replaced the certs or when another unknown kid refreshed them too recently
+func (j *JSONWebKeys) refreshForKid(ctx context.Context, kid string, stale *Certs) (*Certs, error) {
+	if !j.RefreshUnknownKids || j.NoCache || kid == "" || j.knownMissing(kid) {
+		return stale, nil
+	}
+	return j.fetchForKid(ctx, kid, stale, true)
+}
+
+// fetchForKid fetches the certs looking for kid, unless a concurrent refresh already replaced the stale ones or
+// another unknown kid refreshed them too recently. A kid found is published to Rotations when publish is set
+func (j *JSONWebKeys) fetchForKid(ctx context.Context, kid string, stale *Certs, publish bool) (*Certs, error) {
+	j.fetchMutex.Lock()
+	defer j.fetchMutex.Unlock()
+
+	j.certsMutex.RLock()
+	certs := j.cachedCerts
+	j.certsMutex.RUnlock()
+	if certs == stale || certs == nil {
+		if certs != nil && time.Since(j.lastKidRefresh) < j.unknownKidRefreshInterval() {
+			// another unknown kid refreshed the certs too recently
+			return certs, nil
+		}
+		j.lastKidRefresh = time.Now()
+		fresh, err := j.fetchCerts(ctx)
+		if err != nil {
+			j.rememberMissing(kid)
+			return nil, err
+		}
+		j.storeCerts(fresh)
+		certs = fresh
+		if _, ok := fresh.Keys[kid]; ok && publish && j.Rotations != nil {
+			go j.Rotations.Publish(j.lifecycle(), kid)
+		}
+	}
+
+	if _, ok := certs.Keys[kid]; !ok {
+		j.rememberMissing(kid)
+	}
+	return certs, nil
+}
+
+// knownMissing tells whether kid was recently found missing after a refresh
+func (j *JSONWebKeys) knownMissing(kid string) bool {
+	j.missingMutex.Lock()
+	defer j.missingMutex.Unlock()
+	expiry, ok := j.missingKids[kid]
+	return ok && time.Now().Before(expiry)
+}
+
+// rememberMissing records kid as missing for MissingKidTTL
+func (j *JSONWebKeys) rememberMissing(kid string) {
+	ttl := j.MissingKidTTL
+	if ttl == 0 {
+		ttl = time.Minute
+	}
+
+	j.missingMutex.Lock()
+	defer j.missingMutex.Unlock()
+	now := time.Now()
+	if j.missingKids == nil {
+		j.missingKids = map[string]time.Time{}
+	}
+	if _, ok := j.missingKids[kid]; !ok && len(j.missingKids) >= maxMissingKids {
+		// drop the expired kids, or else the one expiring first, keeping the rest of the negative cache
+		var oldest string
+		for missing, expiry := range j.missingKids {
+			if !now.Before(expiry) {
+				delete(j.missingKids, missing)
+			} else if oldest == "" || expiry.Before(j.missingKids[oldest]) {
+				oldest = missing
+			}
+		}
+		if len(j.missingKids) >= maxMissingKids {
+			delete(j.missingKids, oldest)
+		}
+	}
+	j.missingKids[kid] = now.Add(ttl)
+}
+
+// unknownKidRefreshInterval returns UnknownKidRefreshInterval, or its default
+func (j *JSONWebKeys) unknownKidRefreshInterval() time.Duration {
+	if j.UnknownKidRefreshInterval == 0 {
+		return 10 * time.Second
+	}
+	return j.UnknownKidRefreshInterval
+}
@@ -0,0 +1,94 @@
+package jwk
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRefreshUnknownKids(t *testing.T) {
+	server, requests := newTestJWKSServer(t, "max-age=3600", 0)
+	defer server.Close()
+
+	// the cached certs predate the rotation to testKid
+	j := &JSONWebKeys{
+		JWKURL:                    server.URL,
+		RefreshUnknownKids:        true,
+		UnknownKidRefreshInterval: time.Nanosecond,
+		cachedCerts:               &Certs{Keys: map[string]Key{}, Expiry: time.Now().Add(time.Hour)},
+	}
+	if _, err := j.GetKey(testKid); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(requests) != 1 {
+		t.Fatalf("expecting a refresh, got %d requests", atomic.LoadInt32(requests))
+	}
+
+	for i := 0; i < 5; i++ {
+		if _, err := j.GetKey("revoked"); err == nil {
+			t.Fatal("expecting an unknown kid to be reported")
+		}
+	}
+	if atomic.LoadInt32(requests) != 2 {
+		t.Fatalf("expecting a single refresh for the unknown kid, got %d requests", atomic.LoadInt32(requests))
+	}
+	if _, err := j.GetKey("other"); err == nil {
+		t.Fatal("expecting an unknown kid to be reported")
+	}
+	if atomic.LoadInt32(requests) != 3 {
+		t.Fatalf("expecting a refresh for another unknown kid, got %d requests", atomic.LoadInt32(requests))
+	}
+
+	j.MissingKidTTL = time.Millisecond
+	j.rememberMissing("revoked")
+	time.Sleep(5 * time.Millisecond)
+	if _, err := j.GetKey("revoked"); err == nil {
+		t.Fatal("expecting an unknown kid to be reported")
+	}
+	if atomic.LoadInt32(requests) != 4 {
+		t.Fatalf("expecting a refresh once the kid is no longer known missing, got %d requests", atomic.LoadInt32(requests))
+	}
+}
+
+func TestUnknownKidRefreshInterval(t *testing.T) {
+	server, requests := newTestJWKSServer(t, "max-age=3600", 0)
+	defer server.Close()
+
+	j := &JSONWebKeys{JWKURL: server.URL, RefreshUnknownKids: true}
+	if _, err := j.GetKey(testKid); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		if _, err := j.GetKey(fmt.Sprintf("random-%d", i)); err == nil {
+			t.Fatal("expecting an unknown kid to be reported")
+		}
+	}
+	if atomic.LoadInt32(requests) != 2 {
+		t.Fatalf("expecting a single refresh for a flood of unknown kids, got %d requests", atomic.LoadInt32(requests))
+	}
+
+	j.UnknownKidRefreshInterval = time.Millisecond
+	time.Sleep(5 * time.Millisecond)
+	if _, err := j.GetKey("random-19"); err == nil {
+		t.Fatal("expecting an unknown kid to be reported")
+	}
+	if atomic.LoadInt32(requests) != 3 {
+		t.Fatalf("expecting kids skipped by the interval to refresh later, got %d requests", atomic.LoadInt32(requests))
+	}
+}
+
+func TestRememberMissingBounded(t *testing.T) {
+	j := &JSONWebKeys{MissingKidTTL: 30 * time.Second}
+	j.rememberMissing("first")
+	j.MissingKidTTL = 0
+	for i := 0; i < maxMissingKids+10; i++ {
+		j.rememberMissing(fmt.Sprint(i))
+	}
+	if len(j.missingKids) != maxMissingKids {
+		t.Fatalf("expecting %d missing kids, got %d", maxMissingKids, len(j.missingKids))
+	}
+	if j.knownMissing("first") || !j.knownMissing(fmt.Sprint(maxMissingKids+9)) {
+		t.Fatal("expecting only the kids expiring first to be evicted")
+	}
+}
@@ -0,0 +1,41 @@
+package jwk
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-jose/go-jose/v3"
+)
+
+// decryptNested decrypts the JWE layer of a nested JWT with DecryptionKeys, returning the signed JWT it holds.
+// The JWE must declare the JWT content type, as required by RFC 7519 section 5.2
+func (j *JSONWebKeys) decryptNested(raw string) (string, error) {
+	jwe, err := jose.ParseEncrypted(raw)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse encrypted token: %w", err)
+	}
+	header := jwe.Header
+	if header.Algorithm == string(jose.RSA1_5) {
+		return "", fmt.Errorf("token encryption alg %q is not allowed", header.Algorithm)
+	}
+	if cty, _ := header.ExtraHeaders[jose.HeaderContentType].(string); normalizeMediaType(cty) != "jwt" {
+		return "", fmt.Errorf("encrypted token cty %q is not JWT", cty)
+	}
+
+	candidates := []interface{}{}
+	if key, ok := j.DecryptionKeys[header.KeyID]; ok {
+		candidates = append(candidates, key)
+	} else if header.KeyID == "" {
+		for _, key := range j.DecryptionKeys {
+			candidates = append(candidates, key)
+		}
+	} else {
+		return "", fmt.Errorf("no decryption key with kid %q", header.KeyID)
+	}
+	for _, key := range candidates {
+		if plaintext, err := jwe.Decrypt(key); err == nil {
+			return string(plaintext), nil
+		}
+	}
+	return "", errors.New("unable to decrypt token")
+}
@@ -0,0 +1,57 @@
+package jwk
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+func TestVerifyNestedToken(t *testing.T) {
+	signer, j := newTestSigner(t, "test")
+	decryptionKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.DecryptionKeys = map[string]interface{}{"enc": decryptionKey}
+	inner := signTestToken(t, signer, jwt.Claims{Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour))})
+	encrypt := func(kid string, options *jose.EncrypterOptions) string {
+		encrypter, err := jose.NewEncrypter(jose.A256GCM,
+			jose.Recipient{Algorithm: jose.RSA_OAEP_256, Key: &decryptionKey.PublicKey, KeyID: kid}, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		jwe, err := encrypter.Encrypt([]byte(inner))
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, err := jwe.CompactSerialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+
+	claims, err := j.VerifyToken(context.Background(), encrypt("enc", (&jose.EncrypterOptions{}).WithContentType("JWT")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims["scope"] != "read" {
+		t.Fatalf("unexpected claims %v", claims)
+	}
+
+	invalid := map[string]string{
+		"is not JWT":        encrypt("enc", &jose.EncrypterOptions{}),
+		"no decryption key": encrypt("other", (&jose.EncrypterOptions{}).WithContentType("JWT")),
+	}
+	for expected, raw := range invalid {
+		if _, err := j.VerifyToken(context.Background(), raw); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expecting %q, got %v", expected, err)
+		}
+	}
+}
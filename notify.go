@@ -0,0 +1,97 @@
+package jwk
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// webhookSignatureHeader carries the HMAC-SHA256 of the payload posted by WebhookNotifier
+const webhookSignatureHeader = "X-JWK-Signature"
+
+// WebhookNotifier posts the changes of a key set to a webhook, as a JSON payload listing the added, removed and
+// changed kids. Its Notify method is meant to be set as JSONWebKeys.OnChange
+type WebhookNotifier struct {
+	// URL is the webhook the payloads are posted to
+	URL string
+
+	// Secret signs the payloads: their HMAC-SHA256 is sent hex-encoded in the X-JWK-Signature header,
+	// as sha256=<hex>. The payloads are not signed if empty
+	Secret []byte
+
+	// Client is the HTTP client posting the payloads. If unset it will default to a Client with a 10-seconds timeout
+	Client *http.Client
+
+	// OnError is called with the errors of Notify, which are dropped if unset
+	OnError func(err error)
+}
+
+// webhookPayload is the JSON document posted by WebhookNotifier
+type webhookPayload struct {
+	Source  string    `json:"source"`
+	Time    time.Time `json:"time"`
+	Added   []string  `json:"added"`
+	Removed []string  `json:"removed"`
+	Changed []string  `json:"changed"`
+}
+
+// Notify posts the given change, reporting the failures to OnError
+func (n *WebhookNotifier) Notify(change KeySetChange) {
+	if err := n.Post(context.Background(), change); err != nil && n.OnError != nil {
+		n.OnError(err)
+	}
+}
+
+// Post posts the given change to the webhook, expecting a 2xx response
+func (n *WebhookNotifier) Post(ctx context.Context, change KeySetChange) error {
+	payload, err := json.Marshal(webhookPayload{
+		Source:  change.Source,
+		Time:    change.Time.UTC(),
+		Added:   changedKids(change.Added),
+		Removed: changedKids(change.Removed),
+		Changed: changedKids(change.Changed),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(n.Secret) > 0 {
+		mac := hmac.New(sha256.New, n.Secret)
+		mac.Write(payload)
+		req.Header.Set(webhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	client := n.Client
+	if client == nil {
+		client = &http.Client{Timeout: time.Second * 10}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to post key set change: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &StatusError{URL: n.URL, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	return nil
+}
+
+// changedKids returns the kids of the given keys, never nil so that they are encoded as empty JSON arrays
+func changedKids(keys []Key) []string {
+	kids := make([]string, 0, len(keys))
+	for _, key := range keys {
+		kids = append(kids, key.Kid)
+	}
+	return kids
+}
@@ -0,0 +1,65 @@
+package jwk
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	jwksServer, _ := newTestJWKSServer(t, "", 0)
+	defer jwksServer.Close()
+
+	payloads := make(chan webhookPayload, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write(body)
+		if r.Header.Get("X-JWK-Signature") != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+			t.Errorf("unexpected signature %q", r.Header.Get("X-JWK-Signature"))
+		}
+		payload := webhookPayload{}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Error(err)
+		}
+		payloads <- payload
+	}))
+	defer webhook.Close()
+
+	notifier := &WebhookNotifier{URL: webhook.URL, Secret: []byte("secret"), OnError: func(err error) { t.Error(err) }}
+	j := &JSONWebKeys{
+		JWKURL:      jwksServer.URL,
+		OnChange:    notifier.Notify,
+		cachedCerts: &Certs{Keys: map[string]Key{"old": {Kid: "old", Kty: "RSA"}}, Expiry: time.Now().Add(time.Hour)},
+	}
+	if _, err := j.GetKeys(ForceRefresh()); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case payload := <-payloads:
+		if payload.Source != jwksServer.URL || !reflect.DeepEqual(payload.Added, []string{testKid}) ||
+			!reflect.DeepEqual(payload.Removed, []string{"old"}) || len(payload.Changed) != 0 {
+			t.Fatalf("unexpected payload %+v", payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expecting the change to be posted")
+	}
+
+	// unchanged key set, nothing posted
+	if _, err := j.GetKeys(ForceRefresh()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case payload := <-payloads:
+		t.Fatalf("unexpected payload %+v", payload)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
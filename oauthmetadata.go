@@ -0,0 +1,68 @@
+package jwk
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// oauthMetadataPath is the RFC 8414 well-known path of the OAuth authorization server metadata
+const oauthMetadataPath = "/.well-known/oauth-authorization-server"
+
+// DiscoverOAuthJWKURL reads the jwks_uri from the RFC 8414 OAuth authorization server metadata of the given issuer,
+// for authorization servers not implementing OpenID Connect. If client is nil a Client with a 10-seconds timeout
+// is used
+func DiscoverOAuthJWKURL(client *http.Client, issuer string) (string, error) {
+	metadata, _, err := discoverOAuth(client, issuer)
+	if err != nil {
+		return "", err
+	}
+	if metadata.JWKSURI == "" {
+		return "", errors.New("authorization server metadata does not define a jwks_uri")
+	}
+	return metadata.JWKSURI, nil
+}
+
+// FromOAuthDiscovery builds a JSONWebKeys for the given issuer from its RFC 8414 OAuth authorization server
+// metadata, as FromDiscovery does from the OpenID Connect discovery document
+func FromOAuthDiscovery(client *http.Client, issuer string) (*JSONWebKeys, error) {
+	metadata, metadataURL, err := discoverOAuth(client, issuer)
+	if err != nil {
+		return nil, err
+	}
+	return fromMetadata(client, issuer, metadataURL, metadata)
+}
+
+// oauthMetadataURL returns the URL of the OAuth authorization server metadata of the given issuer: unlike the
+// OpenID Connect one, the well-known path goes between the host and the path of the issuer
+func oauthMetadataURL(issuer string) (string, error) {
+	u, err := url.Parse(issuer)
+	if err != nil {
+		return "", fmt.Errorf("invalid issuer: %w", err)
+	}
+	if u.Scheme != "https" && u.Scheme != "http" || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("invalid issuer %q", issuer)
+	}
+	u.Path = oauthMetadataPath + strings.TrimSuffix(u.Path, "/")
+	u.RawPath = ""
+	return u.String(), nil
+}
+
+// discoverOAuth fetches the OAuth authorization server metadata of the given issuer, returning it along with its
+// URL. As required by RFC 8414, the metadata must declare that very issuer
+func discoverOAuth(client *http.Client, issuer string) (providerMetadata, string, error) {
+	metadataURL, err := oauthMetadataURL(issuer)
+	if err != nil {
+		return providerMetadata{}, "", err
+	}
+	metadata, err := fetchMetadata(client, metadataURL)
+	if err != nil {
+		return metadata, "", err
+	}
+	if metadata.Issuer != issuer {
+		return metadata, "", fmt.Errorf("authorization server metadata declares issuer %q instead of %q", metadata.Issuer, issuer)
+	}
+	return metadata, metadataURL, nil
+}
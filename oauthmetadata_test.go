@@ -0,0 +1,81 @@
+package jwk
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOAuthMetadataURL(t *testing.T) {
+	for _, tc := range []struct {
+		issuer   string
+		expected string
+	}{
+		{"https://example.com", "https://example.com/.well-known/oauth-authorization-server"},
+		{"https://example.com/", "https://example.com/.well-known/oauth-authorization-server"},
+		{"https://example.com/issuer1", "https://example.com/.well-known/oauth-authorization-server/issuer1"},
+		{"https://example.com:8443/tenants/a/", "https://example.com:8443/.well-known/oauth-authorization-server/tenants/a"},
+		{"https://example.com/?tenant=a", ""},
+		{"example.com", ""},
+	} {
+		u, err := oauthMetadataURL(tc.issuer)
+		if tc.expected == "" && err == nil {
+			t.Fatalf("expecting an error for issuer %q", tc.issuer)
+		}
+		if tc.expected != "" && (err != nil || u != tc.expected) {
+			t.Fatalf("unexpected URL %q (%v) for issuer %q", u, err, tc.issuer)
+		}
+	}
+}
+
+func TestFromOAuthDiscovery(t *testing.T) {
+	set, err := ioutil.ReadFile("testdata/jwks.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case oauthMetadataPath + "/tenant":
+			w.Write([]byte(`{"issuer":"` + server.URL + `/tenant","jwks_uri":"` + server.URL + `/tenant/jwks.json"}`))
+		case oauthMetadataPath + "/inline":
+			w.Write([]byte(`{"issuer":"` + server.URL + `/inline","jwks":` + string(set) + `}`))
+		case oauthMetadataPath + "/impostor":
+			w.Write([]byte(`{"issuer":"https://example.com","jwks_uri":"` + server.URL + `/tenant/jwks.json"}`))
+		case "/tenant/jwks.json":
+			w.Write(set)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	jwkURL, err := DiscoverOAuthJWKURL(nil, server.URL+"/tenant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jwkURL != server.URL+"/tenant/jwks.json" {
+		t.Fatalf("unexpected jwks_uri %q", jwkURL)
+	}
+
+	for _, issuer := range []string{server.URL + "/tenant", server.URL + "/inline"} {
+		j, err := FromOAuthDiscovery(nil, issuer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if j.Issuer != issuer {
+			t.Fatalf("unexpected issuer %q", j.Issuer)
+		}
+		if _, err := j.GetKey(testKid); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := FromOAuthDiscovery(nil, server.URL+"/impostor"); err == nil {
+		t.Fatal("expecting an error for metadata declaring another issuer")
+	}
+	if _, err := FromOAuthDiscovery(nil, server.URL+"/missing"); err == nil {
+		t.Fatal("expecting an error for missing metadata")
+	}
+}
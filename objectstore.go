@@ -0,0 +1,56 @@
+package jwk
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// objectCache remembers the last object downloaded by a fetcher along with its ETag, so that the next fetches
+// are conditional GETs not downloading it again while unchanged
+type objectCache struct {
+	mutex sync.Mutex
+	etag  string
+	body  []byte
+}
+
+// get sends the request with the ETag of the cached object, if any, returning the cached object on a 304
+// Not Modified response. The object may not be larger than the MaxDocumentSize of the JSONWebKeys fetching it
+func (c *objectCache) get(client *http.Client, req *http.Request) ([]byte, error) {
+	c.mutex.Lock()
+	etag, cached := c.etag, c.body
+	c.mutex.Unlock()
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		if etag != "" {
+			return cached, nil
+		}
+	case http.StatusOK:
+		body, err := readBody(resp, contextMaxDocumentSize(req.Context()))
+		if err != nil {
+			return nil, err
+		}
+		c.mutex.Lock()
+		c.etag, c.body = resp.Header.Get("ETag"), body
+		c.mutex.Unlock()
+		return body, nil
+	}
+	return nil, &StatusError{URL: req.URL.String(), StatusCode: resp.StatusCode, Status: resp.Status}
+}
+
+// objectClient returns the given client, or a default one with a 10-seconds timeout
+func objectClient(client *http.Client) *http.Client {
+	if client == nil {
+		return &http.Client{Timeout: 10 * time.Second}
+	}
+	return client
+}
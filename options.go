@@ -0,0 +1,63 @@
+package jwk
+
+// CallOption tunes a single key lookup or token verification, leaving the configuration of the JSONWebKeys
+// untouched
+type CallOption func(*callOptions)
+
+// callOptions holds the settings of a single key lookup
+type callOptions struct {
+	forceRefresh  bool
+	cacheBypass   bool
+	audiences     []string
+	audienceMatch AudienceMatch
+
+	ignoreKeyBinding bool
+
+	// certs are the certs resolved by VerifyTokens for its whole batch, used instead of the cache when set
+	certs *Certs
+}
+
+// newCallOptions applies the given options
+func newCallOptions(opts []CallOption) callOptions {
+	// returning early keeps the options, which escape to the option functions, off the heap of plain lookups
+	if len(opts) == 0 {
+		return callOptions{}
+	}
+	options := callOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// ForceRefresh fetches the certs again even when the cached ones are fresh, caching the result,
+// i.e. for an admin endpoint resyncing the keys
+func ForceRefresh() CallOption {
+	return func(o *callOptions) {
+		o.forceRefresh = true
+	}
+}
+
+// CacheBypass fetches the certs for this lookup only: the cache is neither read nor updated
+func CacheBypass() CallOption {
+	return func(o *callOptions) {
+		o.cacheBypass = true
+	}
+}
+
+// ExpectAudience overrides the audiences expected by VerifyToken and how they are matched, i.e. for a route
+// accepting only the tokens issued for its resource indicator
+func ExpectAudience(match AudienceMatch, audiences ...string) CallOption {
+	return func(o *callOptions) {
+		o.audiences = append([]string{}, audiences...)
+		o.audienceMatch = match
+	}
+}
+
+// IgnoreKeyBinding lets VerifySDJWT accept a presentation ending with a key binding JWT without checking it, for
+// callers verifying the holder binding on their own. Such presentations are rejected otherwise
+func IgnoreKeyBinding() CallOption {
+	return func(o *callOptions) {
+		o.ignoreKeyBinding = true
+	}
+}
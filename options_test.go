@@ -0,0 +1,89 @@
+package jwk
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCallOptions(t *testing.T) {
+	server, requests := newTestJWKSServer(t, "", 0)
+	defer server.Close()
+
+	j := &JSONWebKeys{JWKURL: server.URL}
+	certs, err := j.GetKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bypassed, err := j.GetKeys(CacheBypass())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bypassed.Expiry.Equal(certs.Expiry) || atomic.LoadInt32(requests) != 2 {
+		t.Fatalf("expecting a fetch, got %d requests", atomic.LoadInt32(requests))
+	}
+	if cached, _ := j.GetKeys(); !cached.Expiry.Equal(certs.Expiry) {
+		t.Fatal("expecting the cache to be left untouched by CacheBypass")
+	}
+
+	if _, err := GetKey(context.Background(), testKid, ForceRefresh()); err != errNoDefault {
+		t.Fatalf("expecting %v, got %v", errNoDefault, err)
+	}
+	if _, err := j.GetKey(testKid, ForceRefresh()); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(requests) != 3 {
+		t.Fatalf("expecting a fetch, got %d requests", atomic.LoadInt32(requests))
+	}
+	if cached, _ := j.GetKeys(); cached.Expiry.Equal(certs.Expiry) {
+		t.Fatal("expecting the cache to be updated by ForceRefresh")
+	}
+}
+
+func TestNoCache(t *testing.T) {
+	server, requests := newTestJWKSServer(t, "max-age=3600", 0)
+	defer server.Close()
+
+	j := &JSONWebKeys{JWKURL: server.URL, NoCache: true}
+	for i := 0; i < 3; i++ {
+		if _, err := j.GetKey(testKid); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if atomic.LoadInt32(requests) != 3 {
+		t.Fatalf("expecting a fetch per lookup, got %d requests", atomic.LoadInt32(requests))
+	}
+
+	// lookups running while a fetch is in progress share it
+	release := make(chan struct{})
+	var blockedRequests int32
+	blocking := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&blockedRequests, 1)
+		<-release
+		http.ServeFile(w, r, "testdata/jwks.json")
+	}))
+	defer blocking.Close()
+
+	j = &JSONWebKeys{JWKURL: blocking.URL, NoCache: true}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := j.GetKeys(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if n := atomic.LoadInt32(&blockedRequests); n != 1 {
+		t.Fatalf("expecting a single shared fetch, got %d requests", n)
+	}
+}
@@ -0,0 +1,274 @@
+package jwk
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseMode tells how malformed keys are handled while parsing a key set
+type ParseMode int
+
+const (
+	// Lenient skips malformed keys, reporting them in the ParseReport
+	Lenient ParseMode = iota
+	// Strict rejects the whole key set as soon as one of its keys is malformed,
+	// useful for publishers validating their own output
+	Strict
+)
+
+// ParseReport describes the outcome of fetching and parsing a key set, for logs and metrics
+type ParseReport struct {
+	// Total is the number of keys in the document
+	Total int
+
+	// Kept is the number of keys in the set, the other ones being listed in Errors or Skipped
+	Kept int
+
+	// HeaderTTL is the cache duration derived from the response headers, before RefreshInterval, the SPIFFE
+	// refresh hint or ExpiryJitter apply. Zero when the certs were not fetched
+	HeaderTTL time.Duration
+
+	// Errors lists the keys skipped because malformed, in document order
+	Errors []KeyError
+
+	// Skipped lists the well-formed keys left out of the set because not usable for signature verification,
+	// in document order
+	Skipped []SkippedKey
+
+	// Warnings describes the encoding issues fixed before decoding the document, i.e. a byte order mark added by
+	// a gateway, worth logging as the issuer may fix them
+	Warnings []string
+}
+
+// SkippedKey is a key left out of a key set, with the reason why
+type SkippedKey struct {
+	Key    Key
+	Reason string
+}
+
+// KeyError describes a malformed key of a key set
+type KeyError struct {
+	// Index is the position of the key in the document
+	Index int
+	// Kid is the key ID, if it could be read
+	Kid string
+	// Err is the reason why the key was rejected
+	Err error
+}
+
+// Error implements the error interface
+func (e KeyError) Error() string {
+	return fmt.Sprintf("key %d (kid %q): %v", e.Index, e.Kid, e.Err)
+}
+
+// parseOptions tunes how a key set is parsed
+type parseOptions struct {
+	mode           ParseMode
+	lenientBase64  bool
+	thumbprintKids bool
+	dropX5c        bool
+	lenientNumbers bool
+	maxSize        int64
+}
+
+// parseOptions returns the parse options configured on j
+func (j *JSONWebKeys) parseOptions() parseOptions {
+	return parseOptions{
+		mode:           j.ParseMode,
+		lenientBase64:  j.LenientBase64 && j.ParseMode != Strict,
+		thumbprintKids: j.ThumbprintKids,
+		dropX5c:        j.DropX5c,
+		lenientNumbers: j.LenientNumbers && j.ParseMode != Strict,
+		maxSize:        j.maxDocumentSize(),
+	}
+}
+
+// parseJWKS decodes a JWKS document, checking each key on its own: malformed keys are either skipped
+// and reported or, in strict mode, make the whole document fail
+func parseJWKS(data []byte, opts parseOptions) (*jwks, ParseReport, error) {
+	report := ParseReport{}
+	data, warnings, err := cleanDocument(data, opts.maxSize)
+	if err != nil {
+		return nil, report, fmt.Errorf("unable to decode key set: %w", err)
+	}
+	report.Warnings = warnings
+	doc := struct {
+		Keys        []json.RawMessage `json:"keys"`
+		RefreshHint json.RawMessage   `json:"spiffe_refresh_hint"`
+	}{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, report, fmt.Errorf("unable to decode key set: %w", err)
+	}
+
+	res := &jwks{Keys: []Key{}}
+	if doc.RefreshHint != nil {
+		hint, err := readInt(doc.RefreshHint, opts.lenientNumbers)
+		if err != nil {
+			return nil, report, fmt.Errorf("unable to decode key set: spiffe_refresh_hint: %w", err)
+		}
+		res.refreshHint = hint
+	}
+	report.Total = len(doc.Keys)
+	for i, raw := range doc.Keys {
+		if opts.lenientNumbers {
+			var fixed []string
+			if raw, fixed = normalizeNumbers(raw); len(fixed) > 0 {
+				report.Warnings = append(report.Warnings, fmt.Sprintf("key %d: numeric members %s in the wrong type", i, strings.Join(fixed, ", ")))
+			}
+		}
+		key := Key{}
+		err := json.Unmarshal(raw, &key)
+		if err == nil {
+			if opts.lenientBase64 {
+				key = normalizeBase64(key)
+			}
+			err = checkMembers(key)
+		}
+		if err != nil {
+			keyErr := KeyError{Index: i, Kid: readKid(raw), Err: err}
+			if opts.mode == Strict {
+				return nil, report, fmt.Errorf("malformed key set: %w", keyErr)
+			}
+			report.Errors = append(report.Errors, keyErr)
+			continue
+		}
+		if opts.thumbprintKids && key.Kid == "" {
+			if thumbprint, err := key.Thumbprint(); err == nil {
+				key.Kid, key.SyntheticKid = thumbprint, true
+			}
+		}
+		if opts.dropX5c {
+			key = dropX5c(key)
+		}
+		res.Keys = append(res.Keys, key)
+	}
+	return res, report, nil
+}
+
+// checkMembers validates the key when its type is a supported one, leaving the others to the key filter
+func checkMembers(key Key) error {
+	if _, ok := requiredMembers[key.Kty]; key.Kty != "" && !ok {
+		return nil
+	}
+	return key.Validate()
+}
+
+// dropX5c releases the x5c chain of a validated key, keeping the SHA-1 thumbprint of its leaf certificate
+// in x5t, when not declared already, so that the key can still be looked up by x5t
+func dropX5c(key Key) Key {
+	if len(key.X5c) == 0 {
+		return key
+	}
+	if key.X5t == "" {
+		if der, err := base64.StdEncoding.DecodeString(key.X5c[0]); err == nil {
+			sum := sha1.Sum(der)
+			key.X5t = base64.RawURLEncoding.EncodeToString(sum[:])
+		}
+	}
+	key.X5c = nil
+	return key
+}
+
+// timeMembers are the JWK members holding NumericDates, which some issuers send as strings
+var timeMembers = []string{"exp", "nbf", "iat"}
+
+// normalizeNumbers fixes the members of the raw key with the wrong JSON type: an e sent as a number, re-encoded
+// as base64url, and the time members sent as strings, turned into numbers. It returns the fixed key along with
+// the names of the members fixed, leaving it untouched when it can't be decoded
+func normalizeNumbers(raw json.RawMessage) (json.RawMessage, []string) {
+	members := map[string]json.RawMessage{}
+	if err := json.Unmarshal(raw, &members); err != nil {
+		return raw, nil
+	}
+	var fixed []string
+	if e, ok := members["e"]; ok {
+		exponent, ok := new(big.Int).SetString(string(e), 10)
+		if ok && exponent.Sign() > 0 {
+			members["e"], _ = json.Marshal(base64.RawURLEncoding.EncodeToString(exponent.Bytes()))
+			fixed = append(fixed, "e")
+		}
+	}
+	for _, name := range timeMembers {
+		value, ok := members[name]
+		if !ok {
+			continue
+		}
+		var s string
+		if json.Unmarshal(value, &s) != nil {
+			continue
+		}
+		if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+			members[name] = json.RawMessage(s)
+			fixed = append(fixed, name)
+		}
+	}
+	if len(fixed) == 0 {
+		return raw, nil
+	}
+	normalized, err := json.Marshal(members)
+	if err != nil {
+		return raw, nil
+	}
+	return normalized, fixed
+}
+
+// readInt decodes a JSON integer, also accepting one held in a string when lenient
+func readInt(raw json.RawMessage, lenient bool) (int64, error) {
+	var value int64
+	err := json.Unmarshal(raw, &value)
+	if err != nil && lenient {
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			return strconv.ParseInt(s, 10, 64)
+		}
+	}
+	return value, err
+}
+
+// normalizeBase64 re-encodes the members of the key that were not using their canonical encoding:
+// base64url without padding for key members, standard base64 for x5c
+func normalizeBase64(key Key) Key {
+	for _, member := range []*string{&key.N, &key.E, &key.X, &key.Y} {
+		*member = reencode(*member, base64.RawURLEncoding, base64.URLEncoding, base64.StdEncoding)
+	}
+	if len(key.X5c) > 0 {
+		x5c := make([]string, len(key.X5c))
+		for i, cert := range key.X5c {
+			x5c[i] = reencode(cert, base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding)
+		}
+		key.X5c = x5c
+	}
+	return key
+}
+
+// reencode tries decoding value with each encoding in order, returning it encoded with the first one.
+// The value is returned untouched when it's already canonical or can't be decoded at all
+func reencode(value string, canonical *base64.Encoding, alternatives ...*base64.Encoding) string {
+	if value == "" {
+		return value
+	}
+	if _, err := canonical.DecodeString(value); err == nil {
+		return value
+	}
+	for _, encoding := range alternatives {
+		if decoded, err := encoding.DecodeString(value); err == nil {
+			return canonical.EncodeToString(decoded)
+		}
+	}
+	return value
+}
+
+// readKid does its best to read the kid of a key that could not be decoded
+func readKid(raw json.RawMessage) string {
+	key := struct {
+		Kid string `json:"kid"`
+	}{}
+	json.Unmarshal(raw, &key)
+	return key.Kid
+}
@@ -0,0 +1,81 @@
+//go:build go1.18
+// +build go1.18
+
+package jwk
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// fuzzSeeds are malformed documents the parser must survive: truncated base64, enormous exponents and deeply
+// nested members
+var fuzzSeeds = []string{
+	malformedJWKS,
+	`{"keys":[{"kty":"RSA","kid":"truncated","use":"sig","n":"3k837HFk6YVZbO2nfEU2OeMJ","e":"AQ"}]}`,
+	`{"keys":[{"kty":"RSA","kid":"exponent","use":"sig","n":"AQAB","e":"` + strings.Repeat("_w", 512) + `"}]}`,
+	`{"keys":[{"kty":"RSA","kid":"x5c","use":"sig","n":"AQAB","e":"AQAB","x5c":[` + strings.Repeat("[", 1000) + `]}]}`,
+	`{"keys":[{"kty":"EC","kid":"ec","crv":"P-256","x":"AQAB","y":"AQAB","x5c":["MIIB"]}]}`,
+	`{"keys":[{"kty":"OKP","crv":"Ed25519","x":"` + strings.Repeat("A", 43) + `"}]}`,
+	`{"keys":[{"kty":"oct","k":"c2VjcmV0"}],"spiffe_refresh_hint":-1}`,
+	`{"keys":null}`,
+	`{"keys":[null,1,"",{}]}`,
+}
+
+func FuzzParseJWKS(f *testing.F) {
+	if body, err := ioutil.ReadFile("testdata/jwks.json"); err == nil {
+		f.Add(body)
+	}
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, opts := range []parseOptions{{}, {mode: Strict}, {lenientBase64: true, thumbprintKids: true}} {
+			res, _, err := parseJWKS(data, opts)
+			if err != nil {
+				continue
+			}
+			certs, err := filterCerts(res, 0, acceptLocalKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, key := range certs.AllKeys() {
+				key.PublicKey()
+				key.Certificates()
+				key.Thumbprint()
+				key.verificationKey()
+			}
+		}
+	})
+}
+
+func FuzzKeyUnmarshalJSON(f *testing.F) {
+	f.Add([]byte(`{"kty":"RSA","kid":"a","use":"sig","n":"AQAB","e":"AQAB","x5t#S256":"abc","vendor":{"a":[1]}}`))
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		key := Key{}
+		if err := json.Unmarshal(data, &key); err != nil {
+			return
+		}
+		encoded, err := json.Marshal(key)
+		if err != nil {
+			t.Fatalf("unable to encode a decoded key: %v", err)
+		}
+		decoded := Key{}
+		if err := json.Unmarshal(encoded, &decoded); err != nil {
+			t.Fatalf("unable to decode an encoded key: %v", err)
+		}
+		reencoded, err := json.Marshal(decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(encoded, reencoded) {
+			t.Fatalf("unstable encoding %s != %s", encoded, reencoded)
+		}
+	})
+}
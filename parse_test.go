@@ -0,0 +1,264 @@
+package jwk
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var malformedJWKS = `{"keys":[
+	{"kty":"RSA","kid":"good","use":"sig","n":"AQAB","e":"AQAB"},
+	{"kty":"RSA","kid":"bad-n","use":"sig","n":"not base64!","e":"AQAB"},
+	{"kty":"RSA","kid":"numeric-e","use":"sig","n":"AQAB","e":65537},
+	{"kid":"no-kty"},
+	{"kty":"oct","kid":"unsupported","k":"AQAB"}
+]}`
+
+func TestParseJWKSLenient(t *testing.T) {
+	res, report, err := parseJWKS([]byte(malformedJWKS), parseOptions{mode: Lenient})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Keys) != 2 || res.Keys[0].Kid != "good" || res.Keys[1].Kid != "unsupported" {
+		t.Fatalf("unexpected keys: %v", res.Keys)
+	}
+	expected := []KeyError{{Index: 1, Kid: "bad-n"}, {Index: 2, Kid: "numeric-e"}, {Index: 3, Kid: "no-kty"}}
+	if len(report.Errors) != len(expected) {
+		t.Fatalf("unexpected report: %v", report.Errors)
+	}
+	for i, e := range expected {
+		if report.Errors[i].Index != e.Index || report.Errors[i].Kid != e.Kid || report.Errors[i].Err == nil {
+			t.Errorf("unexpected error %d: %v", i, report.Errors[i])
+		}
+	}
+}
+
+func TestParseJWKSStrict(t *testing.T) {
+	if _, _, err := parseJWKS([]byte(malformedJWKS), parseOptions{mode: Strict}); err == nil {
+		t.Fatal("expecting strict mode to reject the key set")
+	}
+
+	body, err := ioutil.ReadFile("testdata/jwks.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, report, err := parseJWKS(body, parseOptions{mode: Strict})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Keys) != 1 || len(report.Errors) != 0 {
+		t.Fatalf("unexpected result: %v %v", res.Keys, report)
+	}
+}
+
+func TestGetKeysParseMode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=120")
+		w.Write([]byte(malformedJWKS))
+	}))
+	defer server.Close()
+
+	j := &JSONWebKeys{JWKURL: server.URL}
+	certs, err := j.getKeys(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(certs.Keys) != 1 || len(certs.Report.Errors) != 3 {
+		t.Fatalf("unexpected certs: %v %v", certs.Keys, certs.Report)
+	}
+	if certs.Report.Total != 5 || certs.Report.Kept != 1 || certs.Report.HeaderTTL != 120*time.Second {
+		t.Fatalf("unexpected report totals: %+v", certs.Report)
+	}
+
+	if len(certs.Report.Skipped) != 1 || certs.Report.Skipped[0].Key.Kid != "unsupported" || certs.Report.Skipped[0].Reason != "oct keys are only accepted from local key sets" {
+		t.Fatalf("unexpected skipped keys: %v", certs.Report.Skipped)
+	}
+	_, err = j.GetKey("unsupported")
+	if err == nil || !strings.Contains(err.Error(), "oct keys are only accepted from local key sets") {
+		t.Fatalf("expecting the skip reason in the error, got %v", err)
+	}
+	_, err = j.GetKey("bad-n")
+	if err == nil || !strings.Contains(err.Error(), "malformed") {
+		t.Fatalf("expecting the parse error in the error, got %v", err)
+	}
+
+	j = &JSONWebKeys{JWKURL: server.URL, ParseMode: Strict}
+	if _, err := j.GetKeys(); err == nil {
+		t.Fatal("expecting strict mode to reject the key set")
+	}
+}
+
+func TestLenientBase64(t *testing.T) {
+	// the test key with a padded standard base64 modulus and a base64url certificate
+	n, err := base64.RawURLEncoding.DecodeString(testKey.N)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x5c, err := base64.StdEncoding.DecodeString(testX5c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sloppy := `{"keys":[{"kty":"RSA","kid":"sloppy","use":"sig","n":"` + base64.StdEncoding.EncodeToString(n) +
+		`","e":"AQAB","x5c":["` + base64.RawURLEncoding.EncodeToString(x5c) + `"]}]}`
+
+	res, report, err := parseJWKS([]byte(sloppy), parseOptions{lenientBase64: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Errors) != 0 || len(res.Keys) != 1 || res.Keys[0].N != testKey.N || res.Keys[0].X5c[0] != testX5c {
+		t.Fatalf("expecting the key to be normalized: %v %v", res.Keys, report)
+	}
+
+	res, report, err = parseJWKS([]byte(sloppy), parseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Keys) != 0 || len(report.Errors) != 1 {
+		t.Fatalf("expecting the key to be rejected without lenient base64: %v %v", res.Keys, report)
+	}
+
+	j := &JSONWebKeys{ParseMode: Strict, LenientBase64: true}
+	if _, _, err := parseJWKS([]byte(sloppy), j.parseOptions()); err == nil {
+		t.Fatal("expecting strict mode to reject the key set")
+	}
+}
+
+func TestLenientNumbers(t *testing.T) {
+	numeric := `{"spiffe_refresh_hint":"300","keys":[{"kty":"RSA","kid":"numeric-e","use":"sig","n":"` + testKey.N + `","e":65537,"exp":"1700000000"}]}`
+
+	res, report, err := parseJWKS([]byte(numeric), parseOptions{lenientNumbers: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Keys) != 1 || res.Keys[0].E != "AQAB" || string(res.Keys[0].Extra["exp"]) != "1700000000" || res.refreshHint != 300 {
+		t.Fatalf("expecting the numbers to be fixed, got %+v (refresh hint %d)", res.Keys, res.refreshHint)
+	}
+	if len(report.Warnings) != 1 || report.Warnings[0] != "key 0: numeric members e, exp in the wrong type" {
+		t.Fatalf("unexpected warnings %q", report.Warnings)
+	}
+
+	// Strict mode keeps rejecting them, whatever the flag
+	j := &JSONWebKeys{ParseMode: Strict, LenientNumbers: true}
+	if _, _, err := parseJWKS([]byte(numeric), j.parseOptions()); err == nil {
+		t.Fatal("expecting strict mode to reject the key set")
+	}
+	res, report, err = parseJWKS([]byte(strings.Replace(numeric, `"300"`, `300`, 1)), parseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Keys) != 0 || len(report.Errors) != 1 {
+		t.Fatalf("expecting the numeric e to be rejected by default, got %+v", res.Keys)
+	}
+}
+
+func TestThumbprintKids(t *testing.T) {
+	noKid := `{"keys":[{"kty":"RSA","use":"sig","n":"` + testKey.N + `","e":"AQAB"},{"kty":"RSA","kid":"k1","use":"sig","n":"AQAB","e":"AQAB"}]}`
+	thumbprint, err := Key{Kty: "RSA", N: testKey.N, E: "AQAB"}.Thumbprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, _, err := parseJWKS([]byte(noKid), parseOptions{thumbprintKids: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Keys[0].Kid != thumbprint || !res.Keys[0].SyntheticKid {
+		t.Fatalf("expecting a synthetic thumbprint kid, got %+v", res.Keys[0])
+	}
+	if res.Keys[1].Kid != "k1" || res.Keys[1].SyntheticKid {
+		t.Fatalf("expecting the original kid to be kept, got %+v", res.Keys[1])
+	}
+
+	res, _, err = parseJWKS([]byte(noKid), parseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Keys[0].Kid != "" || res.Keys[0].SyntheticKid {
+		t.Fatalf("expecting no kid by default, got %+v", res.Keys[0])
+	}
+}
+
+func TestDropX5c(t *testing.T) {
+	body, err := json.Marshal(jwks{Keys: []Key{testKey}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := base64.StdEncoding.DecodeString(testX5c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha1.Sum(der)
+	x5t := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	j := &JSONWebKeys{DropX5c: true}
+	certs, err := j.buildCerts(body, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dropped, ok := certs.Get(testKid)
+	if !ok || dropped.X5c != nil || dropped.X5t != x5t || dropped.PEM() != "" {
+		t.Fatalf("expecting the x5c chain to be replaced by its thumbprint, got %+v", dropped)
+	}
+	if found, ok := findByX5t(certs, x5t); !ok || found.Kid != testKid {
+		t.Fatal("expecting the key to be found by x5t")
+	}
+
+	// the declared x5t is kept as is
+	declared := testKey
+	declared.X5t = "declared"
+	certs, err = j.buildCerts([]byte(`{"keys":[`+mustMarshal(t, declared)+`]}`), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dropped, _ := certs.Get(testKid); dropped.X5c != nil || dropped.X5t != "declared" {
+		t.Fatalf("expecting the declared x5t to be kept, got %+v", dropped)
+	}
+
+	// the chain is still checked against the key before being dropped
+	mismatched := testKey
+	mismatched.N = base64.RawURLEncoding.EncodeToString([]byte("another modulus"))
+	certs, err = j.buildCerts([]byte(`{"keys":[`+mustMarshal(t, mismatched)+`]}`), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if certs.Len() != 0 || len(certs.Report.Errors) != 1 {
+		t.Fatalf("expecting the mismatched chain to be rejected, got %+v", certs.Report)
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) string {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(encoded)
+}
+
+func BenchmarkParseLargeJWKS(b *testing.B) {
+	keys := make([]Key, 1000)
+	for i := range keys {
+		keys[i] = testKey
+		keys[i].Kid = fmt.Sprintf("key-%d", i)
+	}
+	body, err := json.Marshal(jwks{Keys: keys})
+	if err != nil {
+		b.Fatal(err)
+	}
+	j := &JSONWebKeys{}
+	b.SetBytes(int64(len(body)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := j.buildCerts(body, time.Hour); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package jwk
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// uma2ConfigurationPath is the well-known path of the UMA 2.0 authorization server metadata
+const uma2ConfigurationPath = "/.well-known/uma2-configuration"
+
+// Discovery finds the metadata of issuers publishing only some of the well-known documents, probing in order
+// the OpenID Connect discovery document, the RFC 8414 OAuth authorization server metadata, their variants with
+// the well-known path on the other side of the issuer path, and the UMA 2.0 metadata. The location that worked
+// is remembered per issuer, and probed first afterwards. Its methods are safe for concurrent use, e.g. as
+// Registry.New
+type Discovery struct {
+	// Client fetches the metadata, a Client with a 10-seconds timeout when nil. It's also the Client of the
+	// JSONWebKeys built by Keys
+	Client *http.Client
+
+	mutex     sync.Mutex
+	locations map[string]string
+}
+
+// JWKURL returns the jwks_uri of the given issuer
+func (d *Discovery) JWKURL(issuer string) (string, error) {
+	metadata, _, err := d.discover(issuer)
+	if err != nil {
+		return "", err
+	}
+	if metadata.JWKSURI == "" {
+		return "", errors.New("metadata document does not define a jwks_uri")
+	}
+	return metadata.JWKSURI, nil
+}
+
+// Keys builds a JSONWebKeys for the given issuer, see FromDiscovery
+func (d *Discovery) Keys(issuer string) (*JSONWebKeys, error) {
+	metadata, metadataURL, err := d.discover(issuer)
+	if err != nil {
+		return nil, err
+	}
+	return fromMetadata(d.Client, issuer, metadataURL, metadata)
+}
+
+// Location returns the URL of the metadata document of the given issuer, once found
+func (d *Discovery) Location(issuer string) (string, bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	location, ok := d.locations[issuer]
+	return location, ok
+}
+
+// discover probes the well-known locations of the given issuer until one serves its metadata, starting from
+// the one remembered, returning the metadata along with its URL
+func (d *Discovery) discover(issuer string) (providerMetadata, string, error) {
+	locations, err := wellKnownLocations(issuer)
+	if err != nil {
+		return providerMetadata{}, "", err
+	}
+	if known, ok := d.Location(issuer); ok {
+		locations = append([]string{known}, locations...)
+	}
+
+	failures := make([]string, 0, len(locations))
+	tried := map[string]bool{}
+	for _, location := range locations {
+		if tried[location] {
+			continue
+		}
+		tried[location] = true
+		metadata, err := fetchMetadata(d.Client, location)
+		if err == nil {
+			err = checkProbedMetadata(issuer, metadata)
+		}
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", location, err))
+			continue
+		}
+		d.mutex.Lock()
+		if d.locations == nil {
+			d.locations = map[string]string{}
+		}
+		d.locations[issuer] = location
+		d.mutex.Unlock()
+		return metadata, location, nil
+	}
+	return providerMetadata{}, "", fmt.Errorf("no metadata found for issuer %q: %s", issuer, strings.Join(failures, "; "))
+}
+
+// checkProbedMetadata makes sure the probed metadata defines a key set and, when declaring an issuer, the given
+// one, so that unrelated documents served at a well-known path are skipped
+func checkProbedMetadata(issuer string, metadata providerMetadata) error {
+	if metadata.Issuer != "" && strings.TrimSuffix(metadata.Issuer, "/") != strings.TrimSuffix(issuer, "/") {
+		return fmt.Errorf("declares issuer %q", metadata.Issuer)
+	}
+	if metadata.JWKSURI == "" && (len(metadata.JWKS) == 0 || string(metadata.JWKS) == "null") {
+		return errors.New("defines neither jwks_uri nor jwks")
+	}
+	return nil
+}
+
+// wellKnownLocations returns the URLs probed by Discovery for the given issuer, in order
+func wellKnownLocations(issuer string) ([]string, error) {
+	oauthURL, err := oauthMetadataURL(issuer)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(issuer)
+	if err != nil {
+		return nil, err
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+	u.RawPath = ""
+	insertedOIDC := *u
+	insertedOIDC.Path = discoveryPath + path
+
+	trimmed := strings.TrimSuffix(issuer, "/")
+	return []string{
+		discoveryURL(issuer),
+		oauthURL,
+		insertedOIDC.String(),
+		trimmed + oauthMetadataPath,
+		trimmed + uma2ConfigurationPath,
+	}, nil
+}
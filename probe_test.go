@@ -0,0 +1,84 @@
+package jwk
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWellKnownLocations(t *testing.T) {
+	locations, err := wellKnownLocations("https://example.com/tenant/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"https://example.com/tenant/.well-known/openid-configuration",
+		"https://example.com/.well-known/oauth-authorization-server/tenant",
+		"https://example.com/.well-known/openid-configuration/tenant",
+		"https://example.com/tenant/.well-known/oauth-authorization-server",
+		"https://example.com/tenant/.well-known/uma2-configuration",
+	}
+	if len(locations) != len(expected) {
+		t.Fatalf("unexpected locations %v", locations)
+	}
+	for i := range expected {
+		if locations[i] != expected[i] {
+			t.Fatalf("unexpected location %d: %q", i, locations[i])
+		}
+	}
+}
+
+func TestDiscoveryProbing(t *testing.T) {
+	set, err := ioutil.ReadFile("testdata/jwks.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var probes int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&probes, 1)
+		switch r.URL.Path {
+		case "/tenant" + discoveryPath:
+			// an unrelated document, skipped
+			w.Write([]byte(`{"issuer":"https://example.com"}`))
+		case "/tenant" + uma2ConfigurationPath:
+			w.Write([]byte(`{"issuer":"` + server.URL + `/tenant","jwks_uri":"` + server.URL + `/jwks.json"}`))
+		case "/jwks.json":
+			w.Write(set)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	d := &Discovery{}
+	issuer := server.URL + "/tenant"
+	j, err := d.Keys(issuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j.JWKURL != server.URL+"/jwks.json" || j.Issuer != issuer {
+		t.Fatalf("unexpected JWKURL %q or Issuer %q", j.JWKURL, j.Issuer)
+	}
+	if _, err := j.GetKey(testKid); err != nil {
+		t.Fatal(err)
+	}
+	if location, ok := d.Location(issuer); !ok || location != issuer+uma2ConfigurationPath {
+		t.Fatalf("expecting the UMA location to be remembered, got %q", location)
+	}
+
+	// the remembered location is probed first
+	atomic.StoreInt32(&probes, 0)
+	if jwkURL, err := d.JWKURL(issuer); err != nil || jwkURL != server.URL+"/jwks.json" {
+		t.Fatalf("unexpected jwks_uri %q: %v", jwkURL, err)
+	}
+	if atomic.LoadInt32(&probes) != 1 {
+		t.Fatalf("expecting a single probe, got %d", atomic.LoadInt32(&probes))
+	}
+
+	if _, err := d.Keys(server.URL + "/missing"); err == nil {
+		t.Fatal("expecting an error when no location serves metadata")
+	}
+}
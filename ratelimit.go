@@ -0,0 +1,96 @@
+package jwk
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimitedError is returned when the JWKS endpoint answered 429 Too Many Requests, or while waiting for the
+// delay it asked for: no fetch happens until then
+type RateLimitedError struct {
+	URL string
+	// RetryAfter is the delay from the Retry-After header, or FailedRefreshInterval when missing
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("rate limited fetching %s, retry after %v", e.URL, e.RetryAfter)
+}
+
+// RateLimitedUntil returns when the last rate limiting by the JWKS endpoint ends, zero if it never happened.
+// Until then no fetch is made and the cached certs, if any, keep being served
+func (j *JSONWebKeys) RateLimitedUntil() time.Time {
+	j.certsMutex.RLock()
+	defer j.certsMutex.RUnlock()
+	return j.rateLimitedUntil
+}
+
+// rateLimitedError returns the error to report while rate limited, nil when not
+func (j *JSONWebKeys) rateLimitedError() error {
+	until := j.RateLimitedUntil()
+	if wait := time.Until(until); wait > 0 {
+		return &RateLimitedError{URL: j.config().JWKURL, RetryAfter: wait}
+	}
+	return nil
+}
+
+// rateLimitedCerts handles a rate limiting reported by err, if any: it holds off the fetches for the requested
+// delay, returning the cached certs extended until then. It returns nil otherwise, or when nothing is cached
+func (j *JSONWebKeys) rateLimitedCerts(err error) *Certs {
+	var limited *RateLimitedError
+	if !errors.As(err, &limited) {
+		return nil
+	}
+	until := time.Now().Add(limited.RetryAfter)
+
+	j.certsMutex.Lock()
+	defer j.certsMutex.Unlock()
+	if until.After(j.rateLimitedUntil) {
+		j.rateLimitedUntil = until
+	}
+	if j.cachedCerts == nil || j.cachedCerts.Fallback {
+		return nil
+	}
+	extended := *j.cachedCerts
+	if extended.Expiry.Before(until) {
+		extended.Expiry = until
+	}
+	return &extended
+}
+
+// retryAfter parses the Retry-After header of a response, either a number of seconds or an HTTP date,
+// falling back to FailedRefreshInterval. The delay is capped to MaxRetryAfter
+func (j *JSONWebKeys) retryAfter(resp *http.Response) time.Duration {
+	limit := j.maxRetryAfter()
+	value := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil && seconds >= 0 {
+		if seconds > int64(limit/time.Second) {
+			return limit
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		wait := time.Until(date)
+		if wait > limit {
+			return limit
+		}
+		if wait > 0 {
+			return wait
+		}
+		return 0
+	}
+	return j.failedRefreshInterval()
+}
+
+// maxRetryAfter returns MaxRetryAfter, or its default when unset
+func (j *JSONWebKeys) maxRetryAfter() time.Duration {
+	if j.MaxRetryAfter > 0 {
+		return j.MaxRetryAfter
+	}
+	return 20 * j.failedRefreshInterval()
+}
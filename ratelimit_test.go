@@ -0,0 +1,102 @@
+package jwk
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimited(t *testing.T) {
+	body, err := ioutil.ReadFile("testdata/jwks.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) > 1 {
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Cache-Control", "max-age=10")
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	j := &JSONWebKeys{JWKURL: server.URL, FetchRetries: 3, RetryBaseInterval: time.Millisecond}
+	if _, err := j.GetKeys(); err != nil {
+		t.Fatal(err)
+	}
+
+	certs, err := j.GetKeys(ForceRefresh())
+	if err != nil {
+		t.Fatalf("expecting the cached certs to be served while rate limited, got %v", err)
+	}
+	if ttl := time.Until(certs.Expiry); ttl < 110*time.Second {
+		t.Fatalf("expecting the cached certs to be extended by Retry-After, got %v", ttl)
+	}
+	if until := time.Until(j.RateLimitedUntil()); until < 110*time.Second || until > 120*time.Second {
+		t.Fatalf("unexpected rate limiting end in %v", until)
+	}
+	if state := j.RefreshStatus().State; state != RefreshRateLimited {
+		t.Fatalf("expecting the status to report the rate limiting, got %s", state)
+	}
+
+	if _, err := j.GetKeys(ForceRefresh()); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&requests) != 2 {
+		t.Fatalf("expecting neither retries nor fetches while rate limited, got %d requests", atomic.LoadInt32(&requests))
+	}
+}
+
+func TestRateLimitedWithoutCache(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Retry-After", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	j := &JSONWebKeys{JWKURL: server.URL}
+	for i := 0; i < 3; i++ {
+		_, err := j.GetKeys()
+		var limited *RateLimitedError
+		if !errors.As(err, &limited) {
+			t.Fatalf("expecting a RateLimitedError, got %v", err)
+		}
+		if limited.RetryAfter < 55*time.Second || limited.RetryAfter > time.Minute {
+			t.Fatalf("unexpected Retry-After %v", limited.RetryAfter)
+		}
+	}
+	if atomic.LoadInt32(&requests) != 1 {
+		t.Fatalf("expecting a single request, got %d", atomic.LoadInt32(&requests))
+	}
+}
+
+func TestRetryAfterCapped(t *testing.T) {
+	j := &JSONWebKeys{FailedRefreshInterval: time.Second}
+	for value, expected := range map[string]time.Duration{
+		"5":                             5 * time.Second,
+		"86400":                         20 * time.Second,
+		"99999999999999999999":          time.Second,
+		"9223372036854775807":           20 * time.Second,
+		"Fri, 31 Dec 9999 23:59:59 GMT": 20 * time.Second,
+		"soon":                          time.Second,
+	} {
+		resp := &http.Response{Header: http.Header{"Retry-After": {value}}}
+		if wait := j.retryAfter(resp); wait != expected {
+			t.Errorf("expecting Retry-After %q to wait %v, got %v", value, expected, wait)
+		}
+	}
+
+	j.MaxRetryAfter = time.Hour
+	if wait := j.retryAfter(&http.Response{Header: http.Header{"Retry-After": {"86400"}}}); wait != time.Hour {
+		t.Errorf("expecting MaxRetryAfter to cap the delay, got %v", wait)
+	}
+}
@@ -0,0 +1,58 @@
+package jwk
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// compactPattern matches compact JWS and JWE, capturing their protected header
+	compactPattern = regexp.MustCompile(`\b(eyJ[A-Za-z0-9_-]*)((?:\.[A-Za-z0-9_-]*){2,4})`)
+
+	// credentialsPattern matches the credentials of Authorization header values and token parameters
+	credentialsPattern = regexp.MustCompile(`(?i)\b(bearer\s+|basic\s+|dpop\s+|(?:access_|refresh_|id_)?token=)[A-Za-z0-9._~+/-]+=*`)
+
+	// privateMemberPattern matches the private members of JWKs
+	privateMemberPattern = regexp.MustCompile(`"(` + strings.Join(privateMembers, "|") + `)"\s*:\s*("[^"]*"|\[[^\]]*\])`)
+)
+
+// redacted replaces the secrets masked by Redact
+const redacted = "[redacted]"
+
+// Redact masks the secrets the given text may hold, so that it can be logged or returned in an error: compact
+// tokens, of which only the protected header is kept, the credentials of Authorization header values and the
+// private members of JWKs
+func Redact(text string) string {
+	text = credentialsPattern.ReplaceAllString(text, "${1}"+redacted)
+	text = compactPattern.ReplaceAllString(text, "$1."+redacted)
+	return privateMemberPattern.ReplaceAllString(text, `"$1":"`+redacted+`"`)
+}
+
+// RedactError returns an error whose message is redacted with Redact, wrapping err so that errors.Is and
+// errors.As still see it. It returns err itself when there's nothing to redact
+func RedactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	message := err.Error()
+	if scrubbed := Redact(message); scrubbed != message {
+		return &redactedError{message: scrubbed, err: err}
+	}
+	return err
+}
+
+// redactedError is an error whose message was redacted
+type redactedError struct {
+	message string
+	err     error
+}
+
+// Error implements the error interface
+func (e *redactedError) Error() string {
+	return e.message
+}
+
+// Unwrap returns the original error
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,54 @@
+package jwk
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// RedirectPolicy restricts the redirects followed while fetching the certs
+type RedirectPolicy struct {
+	// MaxRedirects is the number of redirects followed at most: 10 when zero, as done by http.Client,
+	// and none when negative
+	MaxRedirects int
+
+	// SameHost only follows the redirects to the host of the original request
+	SameHost bool
+
+	// AllowDowngrade follows the redirects from https to http, which are rejected otherwise
+	AllowDowngrade bool
+}
+
+// client returns a copy of the given client following the policy, then its own CheckRedirect if any, leaving
+// the original one untouched
+func (p *RedirectPolicy) client(client *http.Client) *http.Client {
+	c := *client
+	check := client.CheckRedirect
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if err := p.checkRedirect(req, via); err != nil {
+			return err
+		}
+		if check != nil {
+			return check(req, via)
+		}
+		return nil
+	}
+	return &c
+}
+
+// checkRedirect implements http.Client.CheckRedirect
+func (p *RedirectPolicy) checkRedirect(req *http.Request, via []*http.Request) error {
+	limit := p.MaxRedirects
+	if limit == 0 {
+		limit = 10
+	}
+	if len(via) > limit {
+		return fmt.Errorf("stopped after %d redirects", len(via)-1)
+	}
+	if p.SameHost && req.URL.Host != via[0].URL.Host {
+		return fmt.Errorf("redirect to another host %q", req.URL.Host)
+	}
+	if !p.AllowDowngrade && via[len(via)-1].URL.Scheme == "https" && req.URL.Scheme != "https" {
+		return fmt.Errorf("redirect downgrading to %s", req.URL.Scheme)
+	}
+	return nil
+}
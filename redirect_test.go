@@ -0,0 +1,80 @@
+package jwk
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRedirectPolicy(t *testing.T) {
+	target, _ := newTestJWKSServer(t, "", 0)
+	defer target.Close()
+	redirecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/twice" {
+			http.Redirect(w, r, "/once", http.StatusFound)
+			return
+		}
+		http.Redirect(w, r, target.URL, http.StatusFound)
+	}))
+	defer redirecting.Close()
+
+	j := &JSONWebKeys{JWKURL: redirecting.URL + "/twice"}
+	if _, err := j.GetKeys(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		policy RedirectPolicy
+		err    string
+	}{
+		"no redirects":   {RedirectPolicy{MaxRedirects: -1}, "stopped after 0 redirects"},
+		"too many":       {RedirectPolicy{MaxRedirects: 1}, "stopped after 1 redirects"},
+		"same host only": {RedirectPolicy{SameHost: true}, "redirect to another host"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			policy := test.policy
+			j := &JSONWebKeys{JWKURL: redirecting.URL + "/twice", Redirects: &policy}
+			if _, err := j.GetKeys(); err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expecting %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestRedirectPolicyClientCheck(t *testing.T) {
+	target, _ := newTestJWKSServer(t, "", 0)
+	defer target.Close()
+	redirecting := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer redirecting.Close()
+
+	checked := 0
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		checked++
+		return errors.New("refused by the client")
+	}}
+	j := &JSONWebKeys{JWKURL: redirecting.URL, Client: client, Redirects: &RedirectPolicy{}}
+	if _, err := j.GetKeys(); err == nil || !strings.Contains(err.Error(), "refused by the client") {
+		t.Fatalf("expecting the client redirect policy to apply, got %v", err)
+	}
+
+	j = &JSONWebKeys{JWKURL: redirecting.URL, Client: client, Redirects: &RedirectPolicy{SameHost: true}}
+	if _, err := j.GetKeys(); err == nil || !strings.Contains(err.Error(), "another host") || checked != 1 {
+		t.Fatalf("expecting the policy to apply first, got %v after %d checks", err, checked)
+	}
+}
+
+func TestRedirectPolicyDowngrade(t *testing.T) {
+	policy := &RedirectPolicy{}
+	from, _ := http.NewRequest(http.MethodGet, "https://example.com/jwks.json", nil)
+	to, _ := http.NewRequest(http.MethodGet, "http://example.com/jwks.json", nil)
+	if err := policy.checkRedirect(to, []*http.Request{from}); err == nil {
+		t.Fatal("expecting the downgrade to be rejected")
+	}
+	policy.AllowDowngrade = true
+	if err := policy.checkRedirect(to, []*http.Request{from}); err != nil {
+		t.Fatal(err)
+	}
+}
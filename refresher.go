@@ -0,0 +1,104 @@
+package jwk
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// minRefreshInterval keeps RunRefresher from spinning on certs that expire right away, e.g. with max-age=0
+const minRefreshInterval = time.Second
+
+// RunRefresher keeps the certs fresh in the background, so that callers never wait for a fetch: it fetches them
+// right away, then again shortly before they expire, or every RefreshInterval when set. A failed refresh is tried
+// again after FailedRefreshInterval, while the previous certs keep being served until their expiry.
+// It blocks until ctx is done or Close is called, returning ctx.Err() or ErrClosed, so it's usually started with:
+// go j.RunRefresher(ctx)
+func (j *JSONWebKeys) RunRefresher(ctx context.Context) error {
+	return j.runRefresher(ctx, true)
+}
+
+// runRefresher runs the refresher, whose first refresh keeps the cached certs while fresh unless force is set
+func (j *JSONWebKeys) runRefresher(ctx context.Context, force bool) error {
+	if !j.startWorker() {
+		return ErrClosed
+	}
+	defer j.workers.Done()
+	atomic.AddInt32(&j.refreshers, 1)
+	defer atomic.AddInt32(&j.refreshers, -1)
+	defer j.recordSchedule(time.Time{}, 0)
+	subscriptions, cancel := context.WithCancel(ctx)
+	defer cancel()
+	rotations := j.subscribeRotations(subscriptions)
+	changes := j.subscribeChanges(subscriptions)
+	documents := j.subscribeDocuments(subscriptions)
+
+	for {
+		wait := j.failedRefreshInterval()
+		certs, err := j.refresh(ctx, force)
+		force = true
+		if err == nil {
+			wait = time.Until(certs.Expiry)
+			if wait -= wait / 10; wait < minRefreshInterval {
+				wait = minRefreshInterval
+			}
+			if j.RefreshInterval > 0 && !certs.Fallback {
+				wait = j.RefreshInterval
+			}
+		} else {
+			j.reportExpiry()
+		}
+		// no point in waking up while rate limited
+		if limited := time.Until(j.RateLimitedUntil()); limited > wait {
+			wait = limited
+		}
+		backoff := time.Duration(0)
+		if err != nil {
+			backoff = wait
+		}
+		j.recordSchedule(time.Now().Add(wait), backoff)
+
+		timer := time.NewTimer(wait)
+	waiting:
+		for {
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-j.lifecycle().Done():
+				timer.Stop()
+				return ErrClosed
+			case <-timer.C:
+				break waiting
+			case body, ok := <-documents:
+				if !ok {
+					documents = nil
+					continue
+				}
+				j.loadDocument(body)
+			case _, ok := <-changes:
+				if !ok {
+					changes = nil
+					continue
+				}
+				timer.Stop()
+				break waiting
+			case kid, ok := <-rotations:
+				if !ok {
+					rotations = nil
+				} else if kid != "" && !j.holdsKid(kid) && !j.knownMissing(kid) {
+					// throttled like the unknown kids of the tokens, so that bogus kids can't hammer the issuer
+					j.fetchForKid(ctx, kid, j.cachedSnapshot(), false)
+				}
+			}
+		}
+	}
+}
+
+// failedRefreshInterval returns FailedRefreshInterval, or its default
+func (j *JSONWebKeys) failedRefreshInterval() time.Duration {
+	if j.FailedRefreshInterval == 0 {
+		return 30 * time.Second
+	}
+	return j.FailedRefreshInterval
+}
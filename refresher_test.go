@@ -0,0 +1,74 @@
+package jwk
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitFor polls the given condition for up to a second
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRunRefresher(t *testing.T) {
+	server, requests := newTestJWKSServer(t, "", 1)
+	defer server.Close()
+
+	j := &JSONWebKeys{JWKURL: server.URL, FailedRefreshInterval: time.Millisecond}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- j.RunRefresher(ctx)
+	}()
+
+	// the first fetch fails, the refresher tries again right after
+	waitFor(t, func() bool {
+		j.certsMutex.RLock()
+		defer j.certsMutex.RUnlock()
+		return j.cachedCerts != nil
+	})
+	if atomic.LoadInt32(requests) != 2 {
+		t.Fatalf("expecting 2 requests, got %d", atomic.LoadInt32(requests))
+	}
+
+	// cached certs are served without any further request
+	if _, err := j.GetKey(testKid); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(requests) != 2 {
+		t.Fatalf("expecting 2 requests, got %d", atomic.LoadInt32(requests))
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("expecting context.Canceled, got %v", err)
+	}
+}
+
+func TestRefreshInterval(t *testing.T) {
+	server, requests := newTestJWKSServer(t, "max-age=0", 0)
+	defer server.Close()
+
+	j := &JSONWebKeys{JWKURL: server.URL, RefreshInterval: 50 * time.Millisecond}
+	certs, err := j.GetKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl := time.Until(certs.Expiry); ttl <= 0 || ttl > 50*time.Millisecond {
+		t.Fatalf("expecting RefreshInterval to override max-age, got %v", ttl)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go j.RunRefresher(ctx)
+	// the 1-second floor of header-driven refreshes doesn't apply
+	waitFor(t, func() bool { return atomic.LoadInt32(requests) >= 4 })
+}
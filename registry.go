@@ -0,0 +1,155 @@
+package jwk
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+// Registry maps issuers to the JSONWebKeys verifying their tokens, for services trusting several identity
+// providers. Lookups read an immutable snapshot of the map, replaced atomically by the writers, so that they
+// neither lock nor write any shared memory, however many goroutines verify tokens at once
+type Registry struct {
+	// New builds the JSONWebKeys of an issuer the first time its tokens are verified, i.e. with DiscoverJWKURL.
+	// The tokens of unknown issuers are rejected when nil
+	New func(issuer string) (*JSONWebKeys, error)
+
+	// Audit records the tokens rejected before reaching the JSONWebKeys of an issuer, i.e. those of unknown
+	// issuers, the others being recorded by the Audit sink of the JSONWebKeys verifying them
+	Audit AuditSink
+
+	// snapshot holds the current map[string]*JSONWebKeys, never modified once stored
+	snapshot atomic.Value
+
+	// writeMutex serializes the writers, which copy the snapshot
+	writeMutex sync.Mutex
+
+	swaps     uint64
+	writeWait int64
+}
+
+// RegistryStats reports the contention of a Registry. The lookups aren't counted, as a counter shared by all
+// of them would be the contention point
+type RegistryStats struct {
+	// Issuers is the number of registered issuers
+	Issuers int
+
+	// Swaps counts the snapshots replaced by Add, Remove and the issuers built by New
+	Swaps uint64
+
+	// WriteWait is how long the writers waited for each other overall
+	WriteWait time.Duration
+}
+
+// Get returns the JSONWebKeys of the given issuer, if registered
+func (r *Registry) Get(issuer string) (*JSONWebKeys, bool) {
+	keys, ok := r.load()[issuer]
+	return keys, ok
+}
+
+// Add registers the JSONWebKeys of the given issuer, replacing the previous one, if any
+func (r *Registry) Add(issuer string, keys *JSONWebKeys) {
+	r.update(func(issuers map[string]*JSONWebKeys) {
+		issuers[issuer] = keys
+	})
+}
+
+// Remove unregisters the given issuer, returning its JSONWebKeys so that the caller can close them
+func (r *Registry) Remove(issuer string) (*JSONWebKeys, bool) {
+	var removed *JSONWebKeys
+	var ok bool
+	r.update(func(issuers map[string]*JSONWebKeys) {
+		if removed, ok = issuers[issuer]; ok {
+			delete(issuers, issuer)
+		}
+	})
+	return removed, ok
+}
+
+// Stats returns the current contention metrics
+func (r *Registry) Stats() RegistryStats {
+	return RegistryStats{
+		Issuers:   len(r.load()),
+		Swaps:     atomic.LoadUint64(&r.swaps),
+		WriteWait: time.Duration(atomic.LoadInt64(&r.writeWait)),
+	}
+}
+
+// VerifyToken verifies the given token with the JSONWebKeys of its issuer, built with New when not registered
+// yet, see JSONWebKeys.VerifyToken. Errors are redacted, see RedactError
+func (r *Registry) VerifyToken(ctx context.Context, raw string, opts ...CallOption) (map[string]interface{}, error) {
+	start := time.Now()
+	keys, err := r.tokenKeys(raw)
+	if err != nil {
+		err = RedactError(err)
+		sendAudit(ctx, r.Audit, start, raw, nil, err)
+		return nil, err
+	}
+	return keys.VerifyToken(ctx, raw, opts...)
+}
+
+// tokenKeys returns the JSONWebKeys of the issuer of the given token
+func (r *Registry) tokenKeys(raw string) (*JSONWebKeys, error) {
+	token, err := jwt.ParseSigned(raw)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse token: %w", err)
+	}
+	unverified := jwt.Claims{}
+	if err := token.UnsafeClaimsWithoutVerification(&unverified); err != nil {
+		return nil, fmt.Errorf("unable to decode token claims: %w", err)
+	}
+	return r.issuerKeys(unverified.Issuer)
+}
+
+// issuerKeys returns the JSONWebKeys of the given issuer, building them with New when not registered yet
+func (r *Registry) issuerKeys(issuer string) (*JSONWebKeys, error) {
+	if keys, ok := r.Get(issuer); ok {
+		return keys, nil
+	}
+	if r.New == nil {
+		return nil, fmt.Errorf("unknown issuer %q", issuer)
+	}
+
+	var keys *JSONWebKeys
+	var err error
+	r.update(func(issuers map[string]*JSONWebKeys) {
+		// a concurrent lookup may have built them while waiting
+		var ok bool
+		if keys, ok = issuers[issuer]; !ok {
+			if keys, err = r.New(issuer); err == nil {
+				issuers[issuer] = keys
+			}
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to build the keys of issuer %q: %w", issuer, err)
+	}
+	return keys, nil
+}
+
+// load returns the current snapshot
+func (r *Registry) load() map[string]*JSONWebKeys {
+	issuers, _ := r.snapshot.Load().(map[string]*JSONWebKeys)
+	return issuers
+}
+
+// update applies fn to a copy of the snapshot, replacing it afterwards
+func (r *Registry) update(fn func(issuers map[string]*JSONWebKeys)) {
+	start := time.Now()
+	r.writeMutex.Lock()
+	defer r.writeMutex.Unlock()
+	atomic.AddInt64(&r.writeWait, int64(time.Since(start)))
+
+	current := r.load()
+	issuers := make(map[string]*JSONWebKeys, len(current)+1)
+	for issuer, keys := range current {
+		issuers[issuer] = keys
+	}
+	fn(issuers)
+	r.snapshot.Store(issuers)
+	atomic.AddUint64(&r.swaps, 1)
+}
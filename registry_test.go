@@ -0,0 +1,206 @@
+package jwk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+func TestRegistry(t *testing.T) {
+	first, firstKeys := newTestSigner(t, "first")
+	second, secondKeys := newTestSigner(t, "second")
+
+	r := &Registry{}
+	r.Add("https://first.example.com/", firstKeys)
+	r.Add("https://second.example.com/", secondKeys)
+
+	for _, tc := range []struct {
+		issuer string
+		token  string
+		err    string
+	}{
+		{"first", signTestToken(t, first, jwt.Claims{Issuer: "https://first.example.com/"}), ""},
+		{"second", signTestToken(t, second, jwt.Claims{Issuer: "https://second.example.com/"}), ""},
+		{"swapped", signTestToken(t, second, jwt.Claims{Issuer: "https://first.example.com/"}), "key"},
+		{"unknown", signTestToken(t, first, jwt.Claims{Issuer: "https://other.example.com/"}), "unknown issuer"},
+		{"garbage", "garbage", "unable to parse token"},
+	} {
+		t.Run(tc.issuer, func(t *testing.T) {
+			_, err := r.VerifyToken(context.Background(), tc.token)
+			if tc.err == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+				t.Fatalf("expecting an error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+
+	if removed, ok := r.Remove("https://first.example.com/"); !ok || removed != firstKeys {
+		t.Fatal("expecting the first issuer to be removed")
+	}
+	if _, ok := r.Get("https://first.example.com/"); ok {
+		t.Fatal("expecting the first issuer to be gone")
+	}
+	if stats := r.Stats(); stats.Issuers != 1 || stats.Swaps != 3 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestRegistryNew(t *testing.T) {
+	signer, keys := newTestSigner(t, "test")
+	var mutex sync.Mutex
+	built := 0
+	r := &Registry{New: func(issuer string) (*JSONWebKeys, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if issuer != "https://issuer.example.com/" {
+			return nil, errors.New("untrusted")
+		}
+		built++
+		return keys, nil
+	}}
+
+	raw := signTestToken(t, signer, jwt.Claims{Issuer: "https://issuer.example.com/"})
+	var wg sync.WaitGroup
+	errs := make(chan error, 32)
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := r.VerifyToken(context.Background(), raw)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if built != 1 {
+		t.Fatalf("expecting the keys to be built once, got %d", built)
+	}
+
+	untrusted := signTestToken(t, signer, jwt.Claims{Issuer: "https://other.example.com/"})
+	if _, err := r.VerifyToken(context.Background(), untrusted); err == nil || !strings.Contains(err.Error(), "untrusted") {
+		t.Fatalf("expecting the New error, got %v", err)
+	}
+	if _, ok := r.Get("https://other.example.com/"); ok {
+		t.Fatal("expecting the failed issuer not to be registered")
+	}
+}
+
+// issuerLookup is the contract shared by the registry structures compared by BenchmarkRegistryLookup
+type issuerLookup interface {
+	get(issuer string) (*JSONWebKeys, bool)
+	add(issuer string, keys *JSONWebKeys)
+}
+
+type snapshotLookup struct{ r Registry }
+
+func (s *snapshotLookup) get(issuer string) (*JSONWebKeys, bool) { return s.r.Get(issuer) }
+func (s *snapshotLookup) add(issuer string, keys *JSONWebKeys)   { s.r.Add(issuer, keys) }
+
+type syncMapLookup struct{ m sync.Map }
+
+func (s *syncMapLookup) get(issuer string) (*JSONWebKeys, bool) {
+	keys, ok := s.m.Load(issuer)
+	if !ok {
+		return nil, false
+	}
+	return keys.(*JSONWebKeys), true
+}
+func (s *syncMapLookup) add(issuer string, keys *JSONWebKeys) { s.m.Store(issuer, keys) }
+
+type stripedLookup struct {
+	stripes [64]struct {
+		sync.RWMutex
+		issuers map[string]*JSONWebKeys
+	}
+}
+
+func (s *stripedLookup) stripe(issuer string) int {
+	h := fnv.New32a()
+	h.Write([]byte(issuer))
+	return int(h.Sum32() % uint32(len(s.stripes)))
+}
+
+func (s *stripedLookup) get(issuer string) (*JSONWebKeys, bool) {
+	stripe := &s.stripes[s.stripe(issuer)]
+	stripe.RLock()
+	defer stripe.RUnlock()
+	keys, ok := stripe.issuers[issuer]
+	return keys, ok
+}
+
+func (s *stripedLookup) add(issuer string, keys *JSONWebKeys) {
+	stripe := &s.stripes[s.stripe(issuer)]
+	stripe.Lock()
+	defer stripe.Unlock()
+	if stripe.issuers == nil {
+		stripe.issuers = map[string]*JSONWebKeys{}
+	}
+	stripe.issuers[issuer] = keys
+}
+
+// BenchmarkRegistryLookup compares the structures a Registry could use, run with -cpu to vary the parallelism
+func BenchmarkRegistryLookup(b *testing.B) {
+	issuers := make([]string, 64)
+	for i := range issuers {
+		issuers[i] = fmt.Sprintf("https://issuer-%d.example.com/", i)
+	}
+	for _, bc := range []struct {
+		name   string
+		lookup func() issuerLookup
+	}{
+		{"Snapshot", func() issuerLookup { return &snapshotLookup{} }},
+		{"SyncMap", func() issuerLookup { return &syncMapLookup{} }},
+		{"Striped", func() issuerLookup { return &stripedLookup{} }},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			lookup := bc.lookup()
+			for _, issuer := range issuers {
+				lookup.add(issuer, &JSONWebKeys{})
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					if _, ok := lookup.get(issuers[i%len(issuers)]); !ok {
+						b.Fatal("missing issuer")
+					}
+					i++
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkRegistryVerifyToken(b *testing.B) {
+	signer, keys := newTestSigner(b, "test")
+	r := &Registry{}
+	r.Add("https://issuer.example.com/", keys)
+	raw := signTestToken(b, signer, jwt.Claims{
+		Issuer: "https://issuer.example.com/",
+		Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	})
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := r.VerifyToken(context.Background(), raw); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package jwk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultRevocationRefresh is how often a RevocationList is fetched when RefreshInterval is not set
+const defaultRevocationRefresh = time.Minute
+
+// RevocationChecker tells whether a token, whose signature and claims are valid, has been revoked
+type RevocationChecker interface {
+	Revoked(ctx context.Context, claims map[string]interface{}) (bool, error)
+}
+
+// RevocationList is a RevocationChecker fetching the jti values of the revoked tokens from a URL serving
+// them as a JSON array of strings
+type RevocationList struct {
+	// URL serves the revoked jti values
+	URL string
+
+	// Client is the HTTP client fetching URL. If nil a Client with a 10-seconds timeout is used
+	Client *http.Client
+
+	// RefreshInterval is how long the list is used before being fetched again, one minute by default.
+	// When the fetch fails the previous list keeps being used, tokens are only rejected while none was fetched
+	RefreshInterval time.Duration
+
+	// revoked holds the fetched jti values, until expiry, guarded by mutex along with flight
+	revoked map[string]bool
+	expiry  time.Time
+	mutex   sync.Mutex
+
+	// flight is the fetch in progress, done is closed once err is set
+	flight *revocationFetch
+}
+
+// revocationFetch is a fetch of the list shared by concurrent checks
+type revocationFetch struct {
+	done chan struct{}
+	err  error
+}
+
+// Revoked tells whether the jti claim is in the list, fetching it when stale. Tokens without jti can't be
+// revoked this way. The fetch happens outside of the lock: concurrent checks keep using the previous list
+// meanwhile, or wait for the same fetch when none was fetched yet
+func (l *RevocationList) Revoked(ctx context.Context, claims map[string]interface{}) (bool, error) {
+	jti, _ := claims["jti"].(string)
+	l.mutex.Lock()
+	if !time.Now().After(l.expiry) {
+		revoked := l.revoked
+		l.mutex.Unlock()
+		return jti != "" && revoked[jti], nil
+	}
+
+	call := l.flight
+	if call == nil {
+		call = &revocationFetch{done: make(chan struct{})}
+		l.flight = call
+		l.mutex.Unlock()
+
+		fetched, err := l.fetch(ctx)
+		refresh := l.RefreshInterval
+		if refresh <= 0 {
+			refresh = defaultRevocationRefresh
+		}
+		l.mutex.Lock()
+		if err == nil {
+			l.revoked = fetched
+		}
+		if l.revoked != nil {
+			// without any list the next check fetches again, rather than letting every token through
+			l.expiry = time.Now().Add(refresh)
+		}
+		l.flight = nil
+		call.err = err
+		close(call.done)
+	} else if l.revoked == nil {
+		l.mutex.Unlock()
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+		l.mutex.Lock()
+	}
+	revoked := l.revoked
+	l.mutex.Unlock()
+
+	if revoked == nil {
+		// the fetch failed, and no previous list was fetched
+		return false, call.err
+	}
+	return jti != "" && revoked[jti], nil
+}
+
+// fetch downloads the revoked jti values
+func (l *RevocationList) fetch(ctx context.Context) (map[string]bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build revocation list request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	client := l.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch revocation list: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{URL: l.URL, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	body, err := readBody(resp, defaultMaxDocumentSize)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read revocation list: %w", err)
+	}
+	ids := []string{}
+	if err := json.Unmarshal(body, &ids); err != nil {
+		return nil, fmt.Errorf("unable to decode revocation list: %w", err)
+	}
+	revoked := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		revoked[id] = true
+	}
+	return revoked, nil
+}
@@ -0,0 +1,116 @@
+package jwk
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+func TestRevocationList(t *testing.T) {
+	var failing int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`["revoked-id"]`))
+	}))
+	defer server.Close()
+
+	signer, j := newTestSigner(t, "test")
+	list := &RevocationList{URL: server.URL, RefreshInterval: time.Millisecond}
+	j.Revocation = list
+	sign := func(id string) string {
+		return signTestToken(t, signer, jwt.Claims{ID: id, Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour))})
+	}
+
+	if _, err := j.VerifyToken(context.Background(), sign("valid-id")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.VerifyToken(context.Background(), sign("revoked-id")); err == nil || !strings.Contains(err.Error(), "revoked") {
+		t.Fatalf("expecting the token to be revoked, got %v", err)
+	}
+
+	// a failing refresh keeps the previous list
+	atomic.StoreInt32(&failing, 1)
+	time.Sleep(2 * time.Millisecond)
+	if _, err := j.VerifyToken(context.Background(), sign("revoked-id")); err == nil || !strings.Contains(err.Error(), "revoked") {
+		t.Fatalf("expecting the previous list to be used, got %v", err)
+	}
+
+	// without any list, the tokens are rejected
+	j.Revocation = &RevocationList{URL: server.URL}
+	if _, err := j.VerifyToken(context.Background(), sign("valid-id")); err == nil || !strings.Contains(err.Error(), "unable to check token revocation") {
+		t.Fatalf("expecting the fetch error, got %v", err)
+	}
+}
+
+func TestRevocationListSlowRefresh(t *testing.T) {
+	var requests int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) > 1 {
+			<-release
+		}
+		w.Write([]byte(`["revoked-id"]`))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	list := &RevocationList{URL: server.URL, RefreshInterval: time.Millisecond}
+	claims := map[string]interface{}{"jti": "revoked-id"}
+	if revoked, err := list.Revoked(context.Background(), claims); err != nil || !revoked {
+		t.Fatalf("expecting the jti to be revoked, got %v", err)
+	}
+
+	// the first check past the interval refreshes the list, the other ones keep using the previous one
+	time.Sleep(2 * time.Millisecond)
+	go list.Revoked(context.Background(), claims)
+	for atomic.LoadInt32(&requests) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	done := make(chan bool)
+	go func() {
+		revoked, _ := list.Revoked(context.Background(), claims)
+		done <- revoked
+	}()
+	select {
+	case revoked := <-done:
+		if !revoked {
+			t.Fatal("expecting the previous list to be used")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expecting the checks not to wait for the refresh")
+	}
+	if atomic.LoadInt32(&requests) != 2 {
+		t.Fatalf("expecting a single refresh, got %d requests", atomic.LoadInt32(&requests))
+	}
+}
+
+func TestRevocationListFirstFetchFailing(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`["revoked"]`))
+	}))
+	defer server.Close()
+
+	list := &RevocationList{URL: server.URL, RefreshInterval: time.Hour}
+	claims := map[string]interface{}{"jti": "revoked"}
+	if _, err := list.Revoked(context.Background(), claims); err == nil {
+		t.Fatal("expecting the failed fetch to be reported")
+	}
+	revoked, err := list.Revoked(context.Background(), claims)
+	if err != nil || !revoked {
+		t.Fatalf("expecting the next check to fetch the list again rather than failing open, got %v, %v", revoked, err)
+	}
+}
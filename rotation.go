@@ -0,0 +1,44 @@
+package jwk
+
+import "context"
+
+// RotationBus shares the key rotations found by an instance with the rest of a fleet, so that they refresh right
+// away rather than waiting out their cache. It's usually backed by the pub/sub of a shared store, such as Redis
+type RotationBus interface {
+	// Publish announces a kid just found by a refresh. Its errors are dropped
+	Publish(ctx context.Context, kid string) error
+
+	// Subscribe delivers the kids published by the whole fleet until ctx is done
+	Subscribe(ctx context.Context) (<-chan string, error)
+}
+
+// subscribeRotations subscribes to Rotations, returning a nil channel, never ready, when unset or failing so
+// that the refresher sticks to its schedule
+func (j *JSONWebKeys) subscribeRotations(ctx context.Context) <-chan string {
+	if j.Rotations == nil {
+		return nil
+	}
+	rotations, err := j.Rotations.Subscribe(ctx)
+	if err != nil {
+		return nil
+	}
+	return rotations
+}
+
+// cachedSnapshot returns the cached certs, nil when none
+func (j *JSONWebKeys) cachedSnapshot() *Certs {
+	j.certsMutex.RLock()
+	defer j.certsMutex.RUnlock()
+	return j.cachedCerts
+}
+
+// holdsKid tells whether the cached certs hold the given kid
+func (j *JSONWebKeys) holdsKid(kid string) bool {
+	j.certsMutex.RLock()
+	defer j.certsMutex.RUnlock()
+	if j.cachedCerts == nil {
+		return false
+	}
+	_, ok := j.cachedCerts.Keys[kid]
+	return ok
+}
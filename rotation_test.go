@@ -0,0 +1,106 @@
+package jwk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// memoryBus is a RotationBus delivering the kids to the subscribers of the process
+type memoryBus struct {
+	mutex       sync.Mutex
+	subscribers []chan string
+}
+
+func (b *memoryBus) Publish(ctx context.Context, kid string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for _, subscriber := range b.subscribers {
+		subscriber <- kid
+	}
+	return nil
+}
+
+func (b *memoryBus) Subscribe(ctx context.Context) (<-chan string, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	subscriber := make(chan string, 10)
+	b.subscribers = append(b.subscribers, subscriber)
+	return subscriber, nil
+}
+
+func TestRotations(t *testing.T) {
+	oldKey := testKey
+	oldKey.Kid = "old"
+	var body atomic.Value
+	encode := func(keys ...Key) []byte {
+		encoded, err := json.Marshal(jwks{Keys: keys})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return encoded
+	}
+	body.Store(encode(oldKey))
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write(body.Load().([]byte))
+	}))
+	defer server.Close()
+
+	bus := &memoryBus{}
+	finder := &JSONWebKeys{JWKURL: server.URL, RefreshUnknownKids: true, Rotations: bus}
+	follower := &JSONWebKeys{JWKURL: server.URL, RefreshInterval: time.Hour, Rotations: bus}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go follower.RunRefresher(ctx)
+	if _, err := finder.GetKeys(); err != nil {
+		t.Fatal(err)
+	}
+	for !follower.holdsKid("old") {
+		time.Sleep(time.Millisecond)
+	}
+
+	body.Store(encode(oldKey, testKey))
+	if _, err := finder.GetKey(testKid); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !follower.holdsKid(testKid) {
+		if time.Now().After(deadline) {
+			t.Fatal("expecting the follower to refresh on the published kid")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if atomic.LoadInt32(&requests) != 4 {
+		t.Fatalf("expecting 4 requests, got %d", atomic.LoadInt32(&requests))
+	}
+}
+
+func TestRotationsThrottled(t *testing.T) {
+	server, requests := newTestJWKSServer(t, "", 0)
+	defer server.Close()
+
+	bus := &memoryBus{}
+	follower := &JSONWebKeys{JWKURL: server.URL, RefreshInterval: time.Hour, Rotations: bus}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go follower.RunRefresher(ctx)
+	waitFor(t, func() bool { return follower.holdsKid(testKid) })
+
+	// a single refresh for a bogus kid published over and over, none for another one published right after
+	for i := 0; i < 5; i++ {
+		bus.Publish(ctx, "bogus")
+	}
+	bus.Publish(ctx, "other")
+	waitFor(t, func() bool { return len(bus.subscribers[0]) == 0 })
+	time.Sleep(20 * time.Millisecond)
+	if n := atomic.LoadInt32(requests); n != 2 {
+		t.Fatalf("expecting the published kids to be throttled, got %d requests", n)
+	}
+}
@@ -0,0 +1,218 @@
+package jwk
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+)
+
+// Rotator manages the signing keys of an issuer: tokens are signed with its current key, while the public keys
+// of the current and retired ones are published. Retired keys are verify-only, published for GracePeriod so that
+// the tokens they signed keep verifying until they expire
+type Rotator struct {
+	// Generate creates the private keys, ECDSA P-256 ones when nil
+	Generate func() (crypto.Signer, error)
+
+	// Kids names the new keys, ThumbprintKid when nil
+	Kids KidStrategy
+
+	// GracePeriod is how long a retired key stays published, 24 hours by default. It should exceed the lifetime of
+	// the tokens signed with the key plus the cache age of their verifiers
+	GracePeriod time.Duration
+
+	// Now returns the current time, time.Now by default
+	Now func() time.Time
+
+	// Store persists the keys, loaded on first use and saved on each rotation. Keys are lost on restart when nil
+	Store KeyStore
+
+	mutex sync.RWMutex
+
+	// keys holds the managed keys, the current one last
+	keys []StoredKey
+
+	// loaded tells whether the keys were loaded from Store
+	loaded bool
+}
+
+// Rotate generates a new signing key, retiring the current one, and returns its public JWK
+func (r *Rotator) Rotate() (Key, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err := r.load(); err != nil {
+		return Key{}, err
+	}
+	return r.rotate()
+}
+
+// load reads the keys from Store on first use, holding the write lock
+func (r *Rotator) load() error {
+	if r.loaded {
+		return nil
+	}
+	if r.Store != nil {
+		keys, err := r.Store.Load()
+		if err != nil {
+			return fmt.Errorf("unable to load the keys: %w", err)
+		}
+		r.keys = keys
+	}
+	r.loaded = true
+	return nil
+}
+
+// rotate generates a new signing key, holding the write lock
+func (r *Rotator) rotate() (Key, error) {
+	generate := r.Generate
+	if generate == nil {
+		generate = func() (crypto.Signer, error) {
+			return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		}
+	}
+	private, err := generate()
+	if err != nil {
+		return Key{}, err
+	}
+	public, err := AccountKey(private)
+	if err != nil {
+		return Key{}, err
+	}
+	public.Use = "sig"
+	var kids KidStrategy = ThumbprintKid{}
+	if r.Kids != nil {
+		kids = r.Kids
+	}
+	now := r.now()
+	keys := r.published(now)
+	if public.Kid, err = kids.Kid(public, keys); err != nil {
+		return Key{}, err
+	}
+	if hasKid(keys, public.Kid) {
+		return Key{}, errors.New("the new key has the kid of a published key")
+	}
+
+	rotated := make([]StoredKey, 0, len(r.keys)+1)
+	for _, key := range r.keys {
+		if key.Retired.IsZero() {
+			key.Retired = now
+		}
+		if now.Sub(key.Retired) < r.gracePeriod() {
+			rotated = append(rotated, key)
+		}
+	}
+	rotated = append(rotated, StoredKey{Private: private, Public: public})
+	if r.Store != nil {
+		if err := r.Store.Save(rotated); err != nil {
+			return Key{}, fmt.Errorf("unable to save the keys: %w", err)
+		}
+	}
+	r.keys = rotated
+	return public, nil
+}
+
+// Current returns the private key signing tokens along with its public JWK, generating it on first use
+func (r *Rotator) Current() (crypto.Signer, Key, error) {
+	if err := r.init(); err != nil {
+		return nil, Key{}, err
+	}
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	current := r.keys[len(r.keys)-1]
+	return current.Private, current.Public, nil
+}
+
+// init loads the keys and generates the first one when there's none
+func (r *Rotator) init() error {
+	r.mutex.RLock()
+	ready := r.loaded && len(r.keys) > 0
+	r.mutex.RUnlock()
+	if ready {
+		return nil
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err := r.load(); err != nil {
+		return err
+	}
+	if len(r.keys) == 0 {
+		_, err := r.rotate()
+		return err
+	}
+	return nil
+}
+
+// Signer returns a jose.Signer using the current key, see NewSigner. Get a new one after each rotation
+func (r *Rotator) Signer(opts *jose.SignerOptions) (jose.Signer, error) {
+	private, public, err := r.Current()
+	if err != nil {
+		return nil, err
+	}
+	return NewSigner(private, public, opts)
+}
+
+// Keys returns the published public keys: the current one and those retired less than GracePeriod ago
+func (r *Rotator) Keys() ([]Key, error) {
+	if err := r.init(); err != nil {
+		return nil, err
+	}
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.published(r.now()), nil
+}
+
+// published returns the public keys still published at the given time
+func (r *Rotator) published(now time.Time) []Key {
+	keys := make([]Key, 0, len(r.keys))
+	for _, key := range r.keys {
+		if key.Retired.IsZero() || now.Sub(key.Retired) < r.gracePeriod() {
+			keys = append(keys, key.Public)
+		}
+	}
+	return keys
+}
+
+// ServeHTTP serves the published keys as a JWKS document
+func (r *Rotator) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	keys, err := r.Keys()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	certs, err := parseCerts(&jwks{Keys: keys}, 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	document, err := certs.MarshalJWKS()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/jwk-set+json")
+	w.Write(document)
+}
+
+// gracePeriod returns GracePeriod, or its default when unset
+func (r *Rotator) gracePeriod() time.Duration {
+	if r.GracePeriod > 0 {
+		return r.GracePeriod
+	}
+	return 24 * time.Hour
+}
+
+// now returns the current time according to Now
+func (r *Rotator) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,53 @@
+package jwk
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+func TestRotatorGracePeriod(t *testing.T) {
+	now := time.Now()
+	r := &Rotator{GracePeriod: time.Hour, Kids: SequentialKid{Prefix: "key-"}, Now: func() time.Time { return now }}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	signer, err := r.Signer((&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.Signed(signer).Claims(jwt.Claims{Subject: "user"}).CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(30 * time.Minute)
+	j := &JSONWebKeys{JWKURL: server.URL}
+	if _, err := j.VerifyToken(context.Background(), token); err != nil {
+		t.Fatalf("expecting the retired key to verify during the grace period, got %v", err)
+	}
+	if _, current, _ := r.Current(); current.Kid != "key-2" {
+		t.Errorf("unexpected current key %q", current.Kid)
+	}
+	if keys, _ := r.Keys(); len(keys) != 2 {
+		t.Errorf("expecting both keys to be published, got %v", keys)
+	}
+
+	now = now.Add(time.Hour)
+	if keys, _ := r.Keys(); len(keys) != 1 || keys[0].Kid != "key-2" {
+		t.Errorf("expecting the retired key to be dropped after the grace period, got %v", keys)
+	}
+	if _, err := r.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if keys, _ := r.Keys(); len(keys) != 2 || keys[1].Kid != "key-3" {
+		t.Errorf("unexpected keys after the second rotation: %v", keys)
+	}
+}
@@ -0,0 +1,144 @@
+package jwk
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// emptyPayloadHash is the hex-encoded SHA-256 of an empty payload, as sent by the signed GET requests
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3Fetcher reads the JWKS document from an Amazon S3 object, i.e. a mirror of the JWKS of the issuer kept in a
+// bucket of the organization. The object is only downloaded again once its ETag changes
+type S3Fetcher struct {
+	Bucket string
+	Key    string
+	Region string
+
+	// AccessKeyID, SecretAccessKey and SessionToken sign the requests with AWS Signature Version 4. They default
+	// to the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables, the requests
+	// being anonymous when no key is found, as allowed by public buckets
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// Endpoint replaces the regional endpoint of the bucket, i.e. for an S3 compatible storage. The object is
+	// addressed by path under it
+	Endpoint string
+
+	// Client is the HTTP client of the requests. If unset it will default to a Client with a 10-seconds timeout
+	Client *http.Client
+
+	// CacheAge is how long the document is cached, DefaultCacheAge of JSONWebKeys when zero
+	CacheAge time.Duration
+
+	cache objectCache
+}
+
+// Fetch implements Fetcher
+func (s *S3Fetcher) Fetch(ctx context.Context) ([]byte, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(), nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to build S3 request: %w", err)
+	}
+	if accessKeyID, secret, token := s.credentials(); accessKeyID != "" {
+		if token != "" {
+			req.Header.Set("X-Amz-Security-Token", token)
+		}
+		signV4(req, accessKeyID, secret, s.Region, "s3", time.Now())
+	}
+
+	body, err := s.cache.get(objectClient(s.Client), req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to fetch %s: %w", s, err)
+	}
+	return body, s.CacheAge, nil
+}
+
+// String describes the source of the certs
+func (s *S3Fetcher) String() string {
+	return "s3://" + s.Bucket + "/" + s.Key
+}
+
+// objectURL returns the URL of the object, on the virtual-hosted endpoint of the bucket unless Endpoint is set
+func (s *S3Fetcher) objectURL() string {
+	if s.Endpoint != "" {
+		return strings.TrimSuffix(s.Endpoint, "/") + "/" + escapeObjectKey(s.Bucket) + "/" + escapeObjectKey(s.Key)
+	}
+	return "https://" + s.Bucket + ".s3." + s.Region + ".amazonaws.com/" + escapeObjectKey(s.Key)
+}
+
+// credentials returns the configured credentials, or the ones of the environment
+func (s *S3Fetcher) credentials() (accessKeyID, secret, token string) {
+	if s.AccessKeyID != "" {
+		return s.AccessKeyID, s.SecretAccessKey, s.SessionToken
+	}
+	return os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"), os.Getenv("AWS_SESSION_TOKEN")
+}
+
+// signV4 signs the given bodiless request with AWS Signature Version 4, covering its host and all of its headers
+func signV4(req *http.Request, accessKeyID, secret, region, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	canonicalHeaders := ""
+	for _, name := range names {
+		canonicalHeaders += name + ":" + headers[name] + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		emptyPayloadHash,
+	}, "\n")
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hashed[:])
+
+	key := []byte("AWS4" + secret)
+	for _, part := range []string{date, region, service, "aws4_request", stringToSign} {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(part))
+		key = mac.Sum(nil)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKeyID, scope, signedHeaders, hex.EncodeToString(key)))
+}
+
+// escapeObjectKey escapes an object key as expected by S3 and GCS, keeping its slashes and the unreserved
+// characters of RFC 3986
+func escapeObjectKey(key string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~/", c) >= 0 {
+			escaped.WriteByte(c)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", c)
+		}
+	}
+	return escaped.String()
+}
@@ -0,0 +1,187 @@
+package jwk
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// VerifySDJWT verifies an SD-JWT presentation (<issuer-signed JWT>~<disclosure>~...~), see
+// https://datatracker.ietf.org/doc/draft-ietf-oauth-selective-disclosure-jwt/
+// The issuer-signed JWT is verified as by VerifyToken, then the disclosures are matched against its digests and
+// resolved into the returned claims. RequiredClaims, ClaimValidator and Revocation see the resolved claims.
+// Key binding JWTs are not checked, so the presentations ending with one are rejected unless IgnoreKeyBinding
+// leaves the holder binding to the caller. Errors are redacted, see RedactError
+func (j *JSONWebKeys) VerifySDJWT(ctx context.Context, presentation string, opts ...CallOption) (map[string]interface{}, error) {
+	start := time.Now()
+	claims, err := j.verifySDJWT(ctx, presentation, opts)
+	err = RedactError(err)
+	j.audit(ctx, start, strings.SplitN(presentation, "~", 2)[0], claims, err)
+	return claims, err
+}
+
+// verifySDJWT verifies the SD-JWT presentation, see VerifySDJWT
+func (j *JSONWebKeys) verifySDJWT(ctx context.Context, presentation string, opts []CallOption) (map[string]interface{}, error) {
+	parts := strings.Split(presentation, "~")
+	if len(parts) < 2 {
+		return nil, errors.New("malformed SD-JWT: missing ~ separator")
+	}
+	if parts[len(parts)-1] != "" && !newCallOptions(opts).ignoreKeyBinding {
+		return nil, errors.New("SD-JWT key binding JWT can't be verified, see IgnoreKeyBinding")
+	}
+	registered, claims, err := j.verifiedClaims(ctx, parts[0], opts)
+	if err != nil {
+		return nil, err
+	}
+	if alg, ok := claims["_sd_alg"]; ok && alg != "sha-256" {
+		return nil, fmt.Errorf("unsupported SD-JWT _sd_alg %v", alg)
+	}
+
+	disclosures := map[string]disclosure{}
+	for _, encoded := range parts[1 : len(parts)-1] {
+		d, err := decodeDisclosure(encoded)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := disclosures[d.digest]; ok {
+			return nil, errors.New("malformed SD-JWT: duplicate disclosure")
+		}
+		disclosures[d.digest] = d
+	}
+	resolver := &disclosureResolver{disclosures: disclosures, used: map[string]bool{}}
+	resolved, err := resolver.resolve(claims)
+	if err != nil {
+		return nil, err
+	}
+	if len(resolver.used) != len(disclosures) {
+		return nil, errors.New("malformed SD-JWT: disclosure not referenced by the issuer-signed JWT")
+	}
+	resolvedClaims := resolved.(map[string]interface{})
+	delete(resolvedClaims, "_sd_alg")
+
+	if err := j.validateClaims(ctx, registered, resolvedClaims, opts); err != nil {
+		return nil, err
+	}
+	return resolvedClaims, nil
+}
+
+// disclosure is a decoded SD-JWT disclosure: an object property when name is set, an array element otherwise
+type disclosure struct {
+	digest  string
+	name    string
+	value   interface{}
+	element bool
+}
+
+// decodeDisclosure decodes a base64url-encoded disclosure, computing its digest
+func decodeDisclosure(encoded string) (disclosure, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return disclosure{}, fmt.Errorf("malformed SD-JWT disclosure: %w", err)
+	}
+	members := []interface{}{}
+	if err := json.Unmarshal(decoded, &members); err != nil {
+		return disclosure{}, fmt.Errorf("malformed SD-JWT disclosure: %w", err)
+	}
+	sum := sha256.Sum256([]byte(encoded))
+	d := disclosure{digest: base64.RawURLEncoding.EncodeToString(sum[:])}
+	switch len(members) {
+	case 2:
+		d.value, d.element = members[1], true
+	case 3:
+		name, ok := members[1].(string)
+		if !ok || name == "_sd" || name == "..." {
+			return disclosure{}, fmt.Errorf("malformed SD-JWT disclosure: invalid claim name %v", members[1])
+		}
+		d.name, d.value = name, members[2]
+	default:
+		return disclosure{}, errors.New("malformed SD-JWT disclosure: expecting 2 or 3 members")
+	}
+	return d, nil
+}
+
+// disclosureResolver replaces the digests of the issuer-signed claims with the disclosed values
+type disclosureResolver struct {
+	disclosures map[string]disclosure
+	used        map[string]bool
+}
+
+// use returns the disclosure of the given digest, if any, making sure it's referenced once
+func (r *disclosureResolver) use(digest interface{}, element bool) (disclosure, bool, error) {
+	s, _ := digest.(string)
+	d, ok := r.disclosures[s]
+	if !ok {
+		// digests without disclosure are undisclosed claims, or decoys
+		return d, false, nil
+	}
+	if r.used[s] {
+		return d, false, errors.New("malformed SD-JWT: digest referenced twice")
+	}
+	if d.element != element {
+		return d, false, errors.New("malformed SD-JWT: disclosure kind does not match its digest")
+	}
+	r.used[s] = true
+	return d, true, nil
+}
+
+// resolve returns a copy of the given value with its disclosed claims and elements in place of their digests
+func (r *disclosureResolver) resolve(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		resolved := map[string]interface{}{}
+		for name, member := range v {
+			if name == "_sd" {
+				continue
+			}
+			member, err := r.resolve(member)
+			if err != nil {
+				return nil, err
+			}
+			resolved[name] = member
+		}
+		digests, _ := v["_sd"].([]interface{})
+		for _, digest := range digests {
+			d, ok, err := r.use(digest, false)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			if _, exists := resolved[d.name]; exists {
+				return nil, fmt.Errorf("malformed SD-JWT: disclosed claim %q already exists", d.name)
+			}
+			if resolved[d.name], err = r.resolve(d.value); err != nil {
+				return nil, err
+			}
+		}
+		return resolved, nil
+	case []interface{}:
+		resolved := []interface{}{}
+		for _, element := range v {
+			if object, ok := element.(map[string]interface{}); ok && len(object) == 1 && object["..."] != nil {
+				d, ok, err := r.use(object["..."], true)
+				if err != nil {
+					return nil, err
+				}
+				if !ok {
+					continue
+				}
+				element = d.value
+			}
+			element, err := r.resolve(element)
+			if err != nil {
+				return nil, err
+			}
+			resolved = append(resolved, element)
+		}
+		return resolved, nil
+	default:
+		return value, nil
+	}
+}
@@ -0,0 +1,84 @@
+package jwk
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+// testDisclosure encodes a disclosure, returning it along with its digest
+func testDisclosure(json string) (string, string) {
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(json))
+	sum := sha256.Sum256([]byte(encoded))
+	return encoded, base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func TestVerifySDJWT(t *testing.T) {
+	signer, j := newTestSigner(t, "test")
+	email, emailDigest := testDisclosure(`["salt1","email","user@example.com"]`)
+	country, countryDigest := testDisclosure(`["salt2","US"]`)
+	address, addressDigest := testDisclosure(`["salt3","address",{"_sd":["` + emailDigest + `"]}]`)
+	unreferenced, _ := testDisclosure(`["salt4","role","admin"]`)
+	_, hiddenDigest := testDisclosure(`["salt5","DE"]`)
+
+	issued, err := jwt.Signed(signer).Claims(map[string]interface{}{
+		"sub":           "user",
+		"exp":           time.Now().Add(time.Hour).Unix(),
+		"_sd_alg":       "sha-256",
+		"_sd":           []string{addressDigest, "decoy-digest"},
+		"nationalities": []interface{}{map[string]string{"...": countryDigest}, map[string]string{"...": hiddenDigest}},
+	}).CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := j.VerifySDJWT(context.Background(), issued+"~"+address+"~"+email+"~"+country+"~")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"sub":           "user",
+		"exp":           claims["exp"],
+		"address":       map[string]interface{}{"email": "user@example.com"},
+		"nationalities": []interface{}{"US"},
+	}
+	if !reflect.DeepEqual(claims, expected) {
+		t.Fatalf("unexpected claims %v", claims)
+	}
+
+	// undisclosed claims stay hidden
+	claims, err = j.VerifySDJWT(context.Background(), issued+"~")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := claims["address"]; ok || len(claims["nationalities"].([]interface{})) != 0 {
+		t.Fatalf("expecting nothing disclosed, got %v", claims)
+	}
+
+	// a key binding JWT is never taken as verified
+	withKeyBinding := issued + "~" + country + "~" + issued
+	if _, err := j.VerifySDJWT(context.Background(), withKeyBinding); err == nil || !strings.Contains(err.Error(), "key binding") {
+		t.Fatalf("expecting the key binding JWT to be rejected, got %v", err)
+	}
+	if _, err := j.VerifySDJWT(context.Background(), withKeyBinding, IgnoreKeyBinding()); err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := map[string]string{
+		"not referenced": issued + "~" + unreferenced + "~",
+		"duplicate":      issued + "~" + country + "~" + country + "~",
+		"separator":      issued,
+		"malformed":      issued + "~not base64!~",
+	}
+	for name, presentation := range invalid {
+		if _, err := j.VerifySDJWT(context.Background(), presentation); err == nil || !strings.Contains(err.Error(), "SD-JWT") {
+			t.Errorf("%s: expecting an SD-JWT error, got %v", name, err)
+		}
+	}
+}
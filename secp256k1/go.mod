@@ -0,0 +1,18 @@
+module github.com/serjlee/jwk-go/secp256k1
+
+go 1.18
+
+require (
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.2.0
+	github.com/serjlee/jwk-go v0.0.0
+)
+
+require (
+	github.com/fxamacker/cbor/v2 v2.5.0 // indirect
+	github.com/go-jose/go-jose/v3 v3.0.5 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
+	golang.org/x/crypto v0.19.0 // indirect
+	golang.org/x/sys v0.17.0 // indirect
+)
+
+replace github.com/serjlee/jwk-go => ../
@@ -0,0 +1,18 @@
+// Package secp256k1 adds the secp256k1 curve and the ES256K algorithm of RFC 8812 to jwk-go, for the identity
+// providers signing with it. It's a separate module, so that the jwk package doesn't depend on an external curve
+// implementation, and it's enabled by importing it for its side effects:
+//
+//	import _ "github.com/serjlee/jwk-go/secp256k1"
+package secp256k1
+
+import (
+	"crypto"
+	_ "crypto/sha256"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	jwk "github.com/serjlee/jwk-go"
+)
+
+func init() {
+	jwk.RegisterCurve("secp256k1", secp256k1.S256(), "ES256K", crypto.SHA256)
+}
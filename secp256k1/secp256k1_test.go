@@ -0,0 +1,80 @@
+package secp256k1
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	jwk "github.com/serjlee/jwk-go"
+)
+
+// signES256K signs the given claims with an ES256K compact JWS
+func signES256K(t *testing.T, privateKey *ecdsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "ES256K", "kid": kid, "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	sum := sha256.Sum256([]byte(input))
+	r, s, err := ecdsa.Sign(rand.Reader, privateKey, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestES256K(t *testing.T) {
+	privateKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaKey := privateKey.ToECDSA()
+
+	key, err := jwk.FromPublicKey(&ecdsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.Crv != "secp256k1" {
+		t.Fatalf("unexpected crv %q", key.Crv)
+	}
+	key.Kid, key.Use, key.Alg = "k1", "sig", "ES256K"
+	if err := key.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{"keys": []jwk.Key{key}})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	keys := &jwk.JSONWebKeys{JWKURL: server.URL}
+	raw := signES256K(t, ecdsaKey, "k1", map[string]interface{}{"sub": "did:example:123"})
+	claims, err := keys.VerifyToken(context.Background(), raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims["sub"] != "did:example:123" {
+		t.Fatalf("unexpected claims %v", claims)
+	}
+	if _, err := keys.VerifySignature(context.Background(), raw); err != nil {
+		t.Fatal(err)
+	}
+
+	other, _ := secp256k1.GeneratePrivateKey()
+	forged := signES256K(t, other.ToECDSA(), "k1", map[string]interface{}{"sub": "did:example:123"})
+	if _, err := keys.VerifyToken(context.Background(), forged); err == nil {
+		t.Fatal("expecting a token signed by another key to be rejected")
+	}
+}
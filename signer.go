@@ -0,0 +1,45 @@
+package jwk
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+
+	"github.com/go-jose/go-jose/v3"
+)
+
+// SigningKey returns the private key of a published JWK, i.e. one made by jwkctl generate or rotate, as a
+// jose.SigningKey: tokens signed with it carry the kid of the JWK, and its alg, or the one suiting the key when unset
+func SigningKey(private crypto.Signer, public Key) (jose.SigningKey, error) {
+	if public.Kid == "" {
+		return jose.SigningKey{}, errors.New("the public key has no kid")
+	}
+	key, err := AccountKey(private)
+	if err != nil {
+		return jose.SigningKey{}, err
+	}
+	thumbprint, err := key.Thumbprint()
+	if err != nil {
+		return jose.SigningKey{}, err
+	}
+	if published, err := public.Thumbprint(); err != nil || published != thumbprint {
+		return jose.SigningKey{}, fmt.Errorf("the private key doesn't match the public key %q", public.Kid)
+	}
+	alg := public.Alg
+	if alg == "" {
+		alg = key.Alg
+	}
+	return jose.SigningKey{
+		Algorithm: jose.SignatureAlgorithm(alg),
+		Key:       jose.JSONWebKey{Key: private, KeyID: public.Kid, Algorithm: alg},
+	}, nil
+}
+
+// NewSigner returns a jose.Signer using the private key of a published JWK, see SigningKey
+func NewSigner(private crypto.Signer, public Key, opts *jose.SignerOptions) (jose.Signer, error) {
+	key, err := SigningKey(private, public)
+	if err != nil {
+		return nil, err
+	}
+	return jose.NewSigner(key, opts)
+}
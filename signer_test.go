@@ -0,0 +1,61 @@
+package jwk
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+)
+
+func TestNewSigner(t *testing.T) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := FromPublicKey(private.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSigner(private, public, nil); err == nil {
+		t.Fatal("expecting an error for a public key without kid")
+	}
+	public.Use = "sig"
+	public.Kid, _ = SequentialKid{Prefix: "key-"}.Kid(public, nil)
+
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+	if _, err := NewSigner(other, public, nil); err == nil {
+		t.Fatal("expecting an error for a private key not matching the public one")
+	}
+
+	signer, err := NewSigner(private, public, (&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jws, err := signer.Sign([]byte(`{"sub":"me"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := jws.CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := jose.ParseSigned(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header := parsed.Signatures[0].Protected; header.KeyID != "key-1" || header.Algorithm != "RS256" {
+		t.Fatalf("unexpected header %+v", header)
+	}
+
+	certs, err := parseCerts(&jwks{Keys: []Key{public}}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j := &JSONWebKeys{cachedCerts: certs}
+	if _, err := j.VerifySignature(context.Background(), raw); err != nil {
+		t.Fatalf("expecting the token to verify against the published key: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package jwk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+// SPIFFEBundles maps SPIFFE trust domain names (i.e. example.org) to the JSONWebKeys fetching their bundle
+// endpoint, which should have SPIFFE set. Only the https_web endpoint profile is supported,
+// see https://github.com/spiffe/spiffe/blob/main/standards/SPIFFE_Trust_Domain_and_Bundle.md
+type SPIFFEBundles map[string]*JSONWebKeys
+
+// VerifyJWTSVID checks the given JWT-SVID against the bundle of the trust domain of its subject, making sure it
+// is not expired and it's meant for the given audience. It returns the SPIFFE ID of the subject and all the
+// claims of the token, see https://github.com/spiffe/spiffe/blob/main/standards/JWT-SVID.md
+// Errors are redacted, see RedactError, and the verification is recorded by the Audit sink of the bundle, if found
+func (b SPIFFEBundles) VerifyJWTSVID(ctx context.Context, raw string, audience string) (string, map[string]interface{}, error) {
+	start := time.Now()
+	bundle, id, claims, err := b.verifyJWTSVID(ctx, raw, audience)
+	err = RedactError(err)
+	if bundle != nil {
+		bundle.audit(ctx, start, raw, claims, err)
+	}
+	return id, claims, err
+}
+
+// verifyJWTSVID verifies the JWT-SVID, see VerifyJWTSVID, returning the bundle of its trust domain when found
+func (b SPIFFEBundles) verifyJWTSVID(ctx context.Context, raw string, audience string) (*JSONWebKeys, string, map[string]interface{}, error) {
+	token, err := jwt.ParseSigned(raw)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("unable to parse token: %w", err)
+	}
+
+	unverified := jwt.Claims{}
+	if err := token.UnsafeClaimsWithoutVerification(&unverified); err != nil {
+		return nil, "", nil, fmt.Errorf("unable to decode token claims: %w", err)
+	}
+	trustDomain, err := spiffeTrustDomain(unverified.Subject)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	bundle, ok := b[trustDomain]
+	if !ok || bundle == nil {
+		return nil, "", nil, fmt.Errorf("no bundle for trust domain %q", trustDomain)
+	}
+
+	key, err := bundle.GetKeyForToken(ctx, token)
+	if err != nil {
+		return bundle, "", nil, err
+	}
+	publicKey, err := key.PublicKey()
+	if err != nil {
+		return bundle, "", nil, fmt.Errorf("malformed key: %w", err)
+	}
+
+	registered := jwt.Claims{}
+	claims := map[string]interface{}{}
+	if err := token.Claims(publicKey, &registered, &claims); err != nil {
+		return bundle, "", nil, fmt.Errorf("invalid token signature: %w", err)
+	}
+	if registered.Expiry == nil {
+		return bundle, "", nil, errors.New("invalid token claims: missing exp")
+	}
+	expected := jwt.Expected{Audience: jwt.Audience{audience}, Time: time.Now()}
+	if err := registered.Validate(expected); err != nil {
+		return bundle, "", nil, fmt.Errorf("invalid token claims: %w", err)
+	}
+
+	return bundle, registered.Subject, claims, nil
+}
+
+// spiffeTrustDomain returns the trust domain of the given SPIFFE ID
+func spiffeTrustDomain(id string) (string, error) {
+	u, err := url.Parse(id)
+	if err != nil || u.Scheme != "spiffe" || u.Host == "" {
+		return "", fmt.Errorf("invalid SPIFFE ID %q", id)
+	}
+	if u.User != nil || u.Port() != "" || u.RawQuery != "" || u.Fragment != "" || u.Host != strings.ToLower(u.Host) {
+		return "", fmt.Errorf("invalid SPIFFE ID %q", id)
+	}
+	return u.Host, nil
+}
+
+// acceptJWTSVIDKey keeps the RSA and EC keys meant for JWT-SVID verification
+func acceptJWTSVIDKey(key Key) string {
+	switch {
+	case key.Kty != "RSA" && key.Kty != "EC":
+		return fmt.Sprintf("unsupported kty %q", key.Kty)
+	case key.Use != "jwt-svid":
+		return fmt.Sprintf("use %q is not jwt-svid", key.Use)
+	}
+	return ""
+}
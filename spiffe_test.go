@@ -0,0 +1,131 @@
+package jwk
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+// newTestSPIFFEBundle serves a SPIFFE bundle holding a JWT-SVID EC key and an X509-SVID key,
+// returning a signer for the former
+func newTestSPIFFEBundle(t *testing.T) (*httptest.Server, jose.Signer) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.ES256, Key: privateKey},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "svid"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := FromPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key.Kid, key.Use = "svid", "jwt-svid"
+	x509Key := testKey
+	x509Key.Use = "x509-svid"
+
+	body, err := json.Marshal(map[string]interface{}{
+		"keys":                []Key{key, x509Key},
+		"spiffe_sequence":     12,
+		"spiffe_refresh_hint": 300,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=10")
+		w.Write(body)
+	}))
+	return server, signer
+}
+
+func TestSPIFFEBundle(t *testing.T) {
+	server, _ := newTestSPIFFEBundle(t)
+	defer server.Close()
+
+	j := &JSONWebKeys{JWKURL: server.URL, SPIFFE: true}
+	certs, err := j.GetKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := certs.Keys["svid"]; !ok || len(certs.Keys) != 1 {
+		t.Fatalf("expecting only the jwt-svid key, got %v", certs.Keys)
+	}
+	if len(certs.Report.Skipped) != 1 || certs.Report.Skipped[0].Reason != `use "x509-svid" is not jwt-svid` {
+		t.Fatalf("unexpected skipped keys: %+v", certs.Report.Skipped)
+	}
+	if ttl := time.Until(certs.Expiry); ttl < 290*time.Second || ttl > 300*time.Second {
+		t.Fatalf("expecting the refresh hint to be used as cache duration, got %v", ttl)
+	}
+}
+
+func TestVerifyJWTSVID(t *testing.T) {
+	server, signer := newTestSPIFFEBundle(t)
+	defer server.Close()
+
+	bundles := SPIFFEBundles{"example.org": {JWKURL: server.URL, SPIFFE: true}}
+	sign := func(claims jwt.Claims) string {
+		raw, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+	expiry := jwt.NewNumericDate(time.Now().Add(time.Hour))
+
+	id, _, err := bundles.VerifyJWTSVID(context.Background(), sign(jwt.Claims{
+		Subject:  "spiffe://example.org/workload",
+		Audience: jwt.Audience{"backend"},
+		Expiry:   expiry,
+	}), "backend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "spiffe://example.org/workload" {
+		t.Fatalf("unexpected SPIFFE ID %q", id)
+	}
+
+	tests := map[string]struct {
+		claims jwt.Claims
+		err    string
+	}{
+		"wrong audience": {
+			jwt.Claims{Subject: "spiffe://example.org/workload", Audience: jwt.Audience{"other"}, Expiry: expiry},
+			"invalid token claims",
+		},
+		"missing exp": {
+			jwt.Claims{Subject: "spiffe://example.org/workload", Audience: jwt.Audience{"backend"}},
+			"missing exp",
+		},
+		"unknown trust domain": {
+			jwt.Claims{Subject: "spiffe://other.org/workload", Audience: jwt.Audience{"backend"}, Expiry: expiry},
+			`no bundle for trust domain "other.org"`,
+		},
+		"not a SPIFFE ID": {
+			jwt.Claims{Subject: "https://example.org/workload", Audience: jwt.Audience{"backend"}, Expiry: expiry},
+			"invalid SPIFFE ID",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := bundles.VerifyJWTSVID(context.Background(), sign(test.claims), "backend")
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expecting %q, got %v", test.err, err)
+			}
+		})
+	}
+}
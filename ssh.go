@@ -0,0 +1,26 @@
+package jwk
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SSH returns the key as an SSH public key, e.g. to check it against an SSH certificate authority
+func (k Key) SSH() (ssh.PublicKey, error) {
+	publicKey, err := k.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return ssh.NewPublicKey(publicKey)
+}
+
+// FromSSHPublicKey builds a Key from an RSA, ECDSA or Ed25519 SSH public key.
+// As with FromPublicKey, Kid, Alg and Use are left to the caller
+func FromSSHPublicKey(publicKey ssh.PublicKey) (Key, error) {
+	cryptoKey, ok := publicKey.(ssh.CryptoPublicKey)
+	if !ok {
+		return Key{}, fmt.Errorf("unsupported SSH key type %s", publicKey.Type())
+	}
+	return FromPublicKey(cryptoKey.CryptoPublicKey())
+}
@@ -0,0 +1,52 @@
+package jwk
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSSH(t *testing.T) {
+	sshKey, err := testKey.SSH()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sshKey.Type() != ssh.KeyAlgoRSA {
+		t.Fatalf("unexpected SSH key type %s", sshKey.Type())
+	}
+
+	// parse it back from the authorized_keys format
+	parsed, _, _, _, err := ssh.ParseAuthorizedKey(ssh.MarshalAuthorizedKey(sshKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := FromSSHPublicKey(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.N != testKey.N || key.E != testKey.E {
+		t.Fatal("round trip mismatch")
+	}
+
+	edPublicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edKey, err := FromPublicKey(edPublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshKey, err = edKey.SSH()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sshKey.Type() != ssh.KeyAlgoED25519 {
+		t.Fatalf("unexpected SSH key type %s", sshKey.Type())
+	}
+
+	if _, err := (Key{Kty: "oct"}).SSH(); err == nil {
+		t.Fatal("expecting an error for an unsupported key")
+	}
+}
@@ -0,0 +1,46 @@
+package jwk
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// staticCacheAge is the cache duration of the keys loaded by FromJSON and FromFile, which never expire
+const staticCacheAge = 100 * 365 * 24 * time.Hour
+
+// FromJSON builds a JSONWebKeys serving the keys of the given JWKS document, which is never fetched nor refreshed.
+// Along with the signature keys accepted from a URL, it keeps the oct ones, holding the secrets of the HS256,
+// HS384 and HS512 tokens of internal services: those are refused from remote sources, where they would be public
+func FromJSON(data []byte) (*JSONWebKeys, error) {
+	j := &JSONWebKeys{}
+	res, report, err := parseJWKS(data, j.parseOptions())
+	if err != nil {
+		return nil, err
+	}
+	certs, err := filterCerts(res, staticCacheAge, acceptLocalKey)
+	if err != nil {
+		return nil, err
+	}
+	certs.Report.Total, certs.Report.Errors = report.Total, report.Errors
+	certs.Report.Warnings = report.Warnings
+	j.static, j.cachedCerts = certs, certs
+	return j, nil
+}
+
+// FromFile builds a JSONWebKeys serving the keys of the JWKS document at the given path, see FromJSON
+func FromFile(path string) (*JSONWebKeys, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read key set: %w", err)
+	}
+	return FromJSON(data)
+}
+
+// acceptLocalKey keeps the oct signature keys on top of the ones kept by acceptSigKey
+func acceptLocalKey(key Key) string {
+	if key.Kty == "oct" && key.Use != "enc" {
+		return ""
+	}
+	return acceptSigKey(key)
+}
@@ -0,0 +1,106 @@
+package jwk
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+// newTestOctKey returns an HS256 oct key holding the given secret
+func newTestOctKey(t *testing.T, kid string, secret []byte) Key {
+	key := Key{}
+	data := `{"kty":"oct","kid":"` + kid + `","alg":"HS256","use":"sig","k":"` +
+		base64.RawURLEncoding.EncodeToString(secret) + `"}`
+	if err := json.Unmarshal([]byte(data), &key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestFromFile(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	body, _ := json.Marshal(map[string]interface{}{"keys": []Key{newTestOctKey(t, "internal", secret), testKey}})
+	dir, err := ioutil.TempDir("", "jwks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "jwks.json")
+	if err := ioutil.WriteFile(path, body, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	j, err := FromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certs, err := j.GetKeys(ForceRefresh())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(certs.Keys) != 2 {
+		t.Fatalf("expecting the oct and RSA keys, got %v", certs.Keys)
+	}
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.HS256, Key: secret},
+		(&jose.SignerOptions{}).WithHeader("kid", "internal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := signTestToken(t, signer, jwt.Claims{Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour))})
+	claims, err := j.VerifyToken(context.Background(), raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims["scope"] != "read" {
+		t.Fatalf("unexpected claims %v", claims)
+	}
+
+	if _, err := FromFile(filepath.Join(dir, "missing.json")); err == nil {
+		t.Fatal("expecting a missing file to be reported")
+	}
+}
+
+func TestRemoteOctKeysRefused(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	body, _ := json.Marshal(map[string]interface{}{"keys": []Key{newTestOctKey(t, "internal", secret), testKey}})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	j := &JSONWebKeys{JWKURL: server.URL}
+	certs, err := j.GetKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := certs.Keys["internal"]; ok {
+		t.Fatal("expecting the remote oct key to be refused")
+	}
+	if len(certs.Report.Skipped) != 1 || !strings.Contains(certs.Report.Skipped[0].Reason, "oct") {
+		t.Fatalf("unexpected skipped keys %+v", certs.Report.Skipped)
+	}
+	if _, err := certs.Report.Skipped[0].Key.Secret(); err == nil {
+		t.Fatal("expecting the secret to be left out of the report")
+	}
+}
+
+func TestOctKeyValidate(t *testing.T) {
+	if err := newTestOctKey(t, "short", []byte("too short")).Validate(); err == nil || !strings.Contains(err.Error(), "at least 32 bytes") {
+		t.Fatalf("expecting a short secret to be rejected, got %v", err)
+	}
+	if err := (Key{Kty: "oct", Kid: "empty"}).Validate(); err == nil || !strings.Contains(err.Error(), "missing member k") {
+		t.Fatalf("expecting a missing k to be reported, got %v", err)
+	}
+}
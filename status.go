@@ -0,0 +1,107 @@
+package jwk
+
+import (
+	"time"
+)
+
+// RefreshState summarizes the state of the fetches, like the state of a circuit breaker
+type RefreshState int
+
+const (
+	// RefreshHealthy means that the last fetch succeeded, or that none happened yet
+	RefreshHealthy RefreshState = iota
+
+	// RefreshBackingOff means that the last fetches failed: RunRefresher tries again after Backoff
+	RefreshBackingOff
+
+	// RefreshRateLimited means that the JWKS endpoint answered 429: no fetch happens until RateLimitedUntil
+	RefreshRateLimited
+)
+
+// String implements fmt.Stringer
+func (s RefreshState) String() string {
+	switch s {
+	case RefreshBackingOff:
+		return "backing off"
+	case RefreshRateLimited:
+		return "rate limited"
+	default:
+		return "healthy"
+	}
+}
+
+// RefreshStatus describes the state of the fetches, to find out why the keys are stale
+type RefreshStatus struct {
+	// State summarizes the fields below
+	State RefreshState
+
+	// LastAttempt and LastSuccess are when the last fetch, and the last successful one, ended
+	LastAttempt time.Time
+	LastSuccess time.Time
+
+	// LastError is the error of the last fetch, nil if it succeeded
+	LastError error
+
+	// ConsecutiveFailures counts the fetches failed since the last successful one
+	ConsecutiveFailures int
+
+	// NextRefresh is when RunRefresher is going to refresh the keys, zero if it's not running
+	NextRefresh time.Time
+
+	// Backoff is how long RunRefresher waits after the last failed refresh, zero after a successful one
+	Backoff time.Duration
+
+	// RateLimitedUntil is when the rate limiting of the JWKS endpoint ends, see JSONWebKeys.RateLimitedUntil
+	RateLimitedUntil time.Time
+
+	// Expiry is when the cached keys expire, zero if none is cached, and Fallback tells whether they are
+	// the fallback ones
+	Expiry   time.Time
+	Fallback bool
+}
+
+// RefreshStatus returns the current state of the fetches
+func (j *JSONWebKeys) RefreshStatus() RefreshStatus {
+	j.statusMutex.Lock()
+	status := j.status
+	j.statusMutex.Unlock()
+
+	j.certsMutex.RLock()
+	status.RateLimitedUntil = j.rateLimitedUntil
+	if j.cachedCerts != nil {
+		status.Expiry = j.cachedCerts.Expiry
+		status.Fallback = j.cachedCerts.Fallback
+	}
+	j.certsMutex.RUnlock()
+
+	switch {
+	case time.Now().Before(status.RateLimitedUntil):
+		status.State = RefreshRateLimited
+	case status.ConsecutiveFailures > 0:
+		status.State = RefreshBackingOff
+	}
+	return status
+}
+
+// recordFetch updates the status with the outcome of a fetch
+func (j *JSONWebKeys) recordFetch(err error) {
+	j.statusMutex.Lock()
+	defer j.statusMutex.Unlock()
+	j.status.LastAttempt = time.Now()
+	j.status.LastError = err
+	if err != nil {
+		j.status.ConsecutiveFailures++
+		return
+	}
+	j.status.LastSuccess = j.status.LastAttempt
+	j.status.ConsecutiveFailures = 0
+}
+
+// recordSchedule updates the status with the next refresh planned by RunRefresher, a zero next meaning
+// it stopped
+func (j *JSONWebKeys) recordSchedule(next time.Time, backoff time.Duration) {
+	j.statusMutex.Lock()
+	defer j.statusMutex.Unlock()
+	j.status.NextRefresh = next
+	j.status.Backoff = backoff
+}
@@ -0,0 +1,44 @@
+package jwk
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRefreshStatus(t *testing.T) {
+	server, _ := newTestJWKSServer(t, "max-age=3600", 2)
+	defer server.Close()
+
+	j := &JSONWebKeys{JWKURL: server.URL, FailedRefreshInterval: time.Hour}
+	for i := 0; i < 2; i++ {
+		if _, err := j.GetKeys(); err == nil {
+			t.Fatal("expecting the fetch to fail")
+		}
+	}
+	status := j.RefreshStatus()
+	if status.State != RefreshBackingOff || status.ConsecutiveFailures != 2 || status.LastError == nil || status.LastAttempt.IsZero() || !status.LastSuccess.IsZero() {
+		t.Fatalf("unexpected status after failures %+v", status)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		j.RunRefresher(ctx)
+		close(stopped)
+	}()
+	waitFor(t, func() bool { return !j.RefreshStatus().NextRefresh.IsZero() })
+	status = j.RefreshStatus()
+	if status.State != RefreshHealthy || status.ConsecutiveFailures != 0 || status.LastError != nil || status.Backoff != 0 {
+		t.Fatalf("unexpected status after a success %+v", status)
+	}
+	if until := time.Until(status.NextRefresh); until < 50*time.Minute || status.Expiry.Before(status.NextRefresh) {
+		t.Fatalf("expecting the next refresh shortly before the expiry, got %+v", status)
+	}
+
+	cancel()
+	<-stopped
+	if !j.RefreshStatus().NextRefresh.IsZero() {
+		t.Fatal("expecting no refresh to be scheduled once the refresher stopped")
+	}
+}
@@ -0,0 +1,92 @@
+package jwk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// DefaultTenantPath is the path template TenantHandler serves the key sets at
+const DefaultTenantPath = "/tenants/{id}/.well-known/jwks.json"
+
+// ErrUnknownTenant is returned by a TenantStore for the tenants it doesn't hold
+var ErrUnknownTenant = errors.New("unknown tenant")
+
+// TenantStore holds the Rotator of each tenant, for platforms issuing tokens on behalf of many customers
+type TenantStore interface {
+	// Rotator returns the Rotator of the given tenant, or ErrUnknownTenant
+	Rotator(ctx context.Context, tenant string) (*Rotator, error)
+}
+
+// TenantRotators is an in-memory TenantStore, creating the Rotator of a tenant on first use
+type TenantRotators struct {
+	// New builds the Rotator of a tenant, i.e. with a FileKeyStore of its own. Tenants are unknown when nil
+	New func(tenant string) (*Rotator, error)
+
+	mutex    sync.Mutex
+	rotators map[string]*Rotator
+}
+
+// Rotator implements TenantStore
+func (t *TenantRotators) Rotator(ctx context.Context, tenant string) (*Rotator, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if rotator, ok := t.rotators[tenant]; ok {
+		return rotator, nil
+	}
+	if t.New == nil {
+		return nil, ErrUnknownTenant
+	}
+	rotator, err := t.New(tenant)
+	if err != nil {
+		return nil, err
+	}
+	if t.rotators == nil {
+		t.rotators = map[string]*Rotator{}
+	}
+	t.rotators[tenant] = rotator
+	return rotator, nil
+}
+
+// TenantHandler serves the key set of each tenant, published by its own Rotator
+type TenantHandler struct {
+	Tenants TenantStore
+
+	// Path is the template of the key set paths, where {id} stands for the tenant, DefaultTenantPath by default
+	Path string
+}
+
+// ServeHTTP serves the key set of the tenant named by the request path, answering 404 to unknown tenants
+func (h *TenantHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	tenant, ok := h.tenant(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	rotator, err := h.Tenants.Rotator(r.Context(), tenant)
+	if errors.Is(err, ErrUnknownTenant) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	rotator.ServeHTTP(w, r)
+}
+
+// tenant extracts the tenant from a request path matching Path
+func (h *TenantHandler) tenant(path string) (string, bool) {
+	template := h.Path
+	if template == "" {
+		template = DefaultTenantPath
+	}
+	prefix, suffix, _ := strings.Cut(template, "{id}")
+	if !strings.HasPrefix(path, prefix) || !strings.HasSuffix(path, suffix) || len(path) <= len(prefix)+len(suffix) {
+		return "", false
+	}
+	tenant := path[len(prefix) : len(path)-len(suffix)]
+	return tenant, !strings.Contains(tenant, "/")
+}
@@ -0,0 +1,55 @@
+package jwk
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTenantHandler(t *testing.T) {
+	handler := &TenantHandler{Tenants: &TenantRotators{New: func(tenant string) (*Rotator, error) {
+		if tenant == "unknown" {
+			return nil, ErrUnknownTenant
+		}
+		return &Rotator{}, nil
+	}}}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	acme := &JSONWebKeys{JWKURL: server.URL + "/tenants/acme/.well-known/jwks.json"}
+	initech := &JSONWebKeys{JWKURL: server.URL + "/tenants/initech/.well-known/jwks.json"}
+	acmeKeys, err := acme.GetKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	initechKeys, err := initech.GetKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acmeKeys.Len() != 1 || initechKeys.Len() != 1 || acmeKeys.Kids()[0] == initechKeys.Kids()[0] {
+		t.Errorf("expecting each tenant to have a key of its own, got %v and %v", acmeKeys.Kids(), initechKeys.Kids())
+	}
+	again, err := (&JSONWebKeys{JWKURL: acme.JWKURL}).GetKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Kids()[0] != acmeKeys.Kids()[0] {
+		t.Error("expecting the tenant rotator to be reused")
+	}
+
+	for _, path := range []string{
+		"/tenants/unknown/.well-known/jwks.json",
+		"/tenants//.well-known/jwks.json",
+		"/tenants/a/b/.well-known/jwks.json",
+		"/.well-known/jwks.json",
+	} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("expecting %s to be not found, got %d", path, resp.StatusCode)
+		}
+	}
+}
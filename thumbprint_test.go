@@ -0,0 +1,27 @@
+package jwk
+
+import "testing"
+
+func TestThumbprint(t *testing.T) {
+	// example from https://tools.ietf.org/html/rfc7638#section-3.1
+	key := Key{
+		Kty: "RSA",
+		Alg: "RS256",
+		Kid: "2011-04-29",
+		N:   "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		E:   "AQAB",
+	}
+	thumbprint, err := key.Thumbprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if thumbprint != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Fatalf("unexpected thumbprint %s", thumbprint)
+	}
+}
+
+func TestThumbprintUnsupported(t *testing.T) {
+	if _, err := (Key{Kty: "oct"}).Thumbprint(); err == nil {
+		t.Fatal("expecting an error for an unsupported key type")
+	}
+}
@@ -0,0 +1,107 @@
+package jwk
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// minTLSVersion returns MinTLSVersion, or its default
+func (j *JSONWebKeys) minTLSVersion() uint16 {
+	if j.MinTLSVersion == 0 {
+		return tls.VersionTLS12
+	}
+	return j.MinTLSVersion
+}
+
+// defaultTransport returns the transport of the default client, enforcing the TLS policy
+func (j *JSONWebKeys) defaultTransport() *http.Transport {
+	transport := &http.Transport{}
+	if base, ok := http.DefaultTransport.(*http.Transport); ok {
+		transport = base.Clone()
+	}
+	transport.TLSClientConfig = &tls.Config{
+		MinVersion:   j.minTLSVersion(),
+		CipherSuites: j.CipherSuites,
+	}
+	if dial := j.dialContext(); dial != nil {
+		transport.DialContext = dial
+	}
+	return transport
+}
+
+// checkTLS makes sure the connection the response came through complies with the TLS policy, whatever the client
+func (j *JSONWebKeys) checkTLS(resp *http.Response) error {
+	state := resp.TLS
+	if state == nil {
+		if len(j.PinnedServerSPKI) > 0 {
+			return errors.New("pinned server keys require TLS")
+		}
+		return nil
+	}
+	if state.Version < j.minTLSVersion() {
+		return fmt.Errorf("TLS version %s is below the minimum %s", tlsVersionName(state.Version), tlsVersionName(j.minTLSVersion()))
+	}
+	if len(j.CipherSuites) > 0 && state.Version < tls.VersionTLS13 && !containsUint16(j.CipherSuites, state.CipherSuite) {
+		return fmt.Errorf("TLS cipher suite %#04x is not allowed", state.CipherSuite)
+	}
+	if len(j.PinnedServerSPKI) > 0 && !matchPinnedSPKI(state, j.PinnedServerSPKI) {
+		return errors.New("no server certificate matches the pinned keys")
+	}
+	return nil
+}
+
+// SPKIHash returns the pin of the given certificate for PinnedServerSPKI: the base64 SHA-256 of its
+// SubjectPublicKeyInfo, as printed by
+// openssl x509 -pubkey -noout | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64
+func SPKIHash(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// matchPinnedSPKI tells if a certificate of the verified chains, or the leaf one when the chains weren't verified,
+// holds one of the pinned keys
+func matchPinnedSPKI(state *tls.ConnectionState, pins []string) bool {
+	var certs []*x509.Certificate
+	for _, chain := range state.VerifiedChains {
+		certs = append(certs, chain...)
+	}
+	if len(state.VerifiedChains) == 0 && len(state.PeerCertificates) > 0 {
+		certs = state.PeerCertificates[:1]
+	}
+	for _, cert := range certs {
+		if containsString(pins, SPKIHash(cert)) {
+			return true
+		}
+	}
+	return false
+}
+
+// tlsVersionName returns the name of the given TLS version
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "1.0"
+	case tls.VersionTLS11:
+		return "1.1"
+	case tls.VersionTLS12:
+		return "1.2"
+	case tls.VersionTLS13:
+		return "1.3"
+	}
+	return fmt.Sprintf("%#04x", version)
+}
+
+// containsUint16 tells if values holds value
+func containsUint16(values []uint16, value uint16) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package jwk
+
+import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTLSJWKSServer serves the test JWKS over TLS, negotiating at most the given version
+func newTLSJWKSServer(t *testing.T, maxVersion uint16) *httptest.Server {
+	body, err := ioutil.ReadFile("testdata/jwks.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+	server.TLS = &tls.Config{MaxVersion: maxVersion}
+	server.StartTLS()
+	return server
+}
+
+func TestTLSPolicy(t *testing.T) {
+	tls12 := newTLSJWKSServer(t, tls.VersionTLS12)
+	defer tls12.Close()
+	tls13 := newTLSJWKSServer(t, tls.VersionTLS13)
+	defer tls13.Close()
+
+	for _, tc := range []struct {
+		name         string
+		server       *httptest.Server
+		minVersion   uint16
+		cipherSuites []uint16
+		err          string
+	}{
+		{"default", tls12, 0, nil, ""},
+		{"require 1.3", tls12, tls.VersionTLS13, nil, "TLS version 1.2 is below the minimum 1.3"},
+		{"require 1.3 on 1.3", tls13, tls.VersionTLS13, nil, ""},
+		{"cipher suite", tls12, 0, []uint16{tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305}, "cipher suite"},
+		{"cipher suite on 1.3", tls13, 0, []uint16{tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305}, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// the policy is enforced on the injected client too
+			j := &JSONWebKeys{
+				JWKURL:        tc.server.URL,
+				Client:        tc.server.Client(),
+				MinTLSVersion: tc.minVersion,
+				CipherSuites:  tc.cipherSuites,
+			}
+			_, err := j.GetKey(testKid)
+			if tc.err == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+				t.Fatalf("expecting an error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestDefaultClientTLSPolicy(t *testing.T) {
+	j := &JSONWebKeys{MinTLSVersion: tls.VersionTLS13}
+	transport, ok := j.httpClient().Transport.(*http.Transport)
+	if !ok || transport.TLSClientConfig.MinVersion != tls.VersionTLS13 {
+		t.Fatal("expecting the default client to refuse versions below TLS 1.3")
+	}
+	if transport := (&JSONWebKeys{}).defaultTransport(); transport.TLSClientConfig.MinVersion != tls.VersionTLS12 {
+		t.Fatal("expecting the default client to refuse versions below TLS 1.2 by default")
+	}
+}
+
+func TestPinnedServerSPKI(t *testing.T) {
+	server := newTLSJWKSServer(t, tls.VersionTLS13)
+	defer server.Close()
+	pin := SPKIHash(server.Certificate())
+
+	for _, tc := range []struct {
+		name string
+		pins []string
+		err  string
+	}{
+		{"pinned", []string{"backup", pin}, ""},
+		{"not pinned", []string{"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}, "no server certificate matches the pinned keys"},
+		{"no pin", nil, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			j := &JSONWebKeys{JWKURL: server.URL, Client: server.Client(), PinnedServerSPKI: tc.pins}
+			_, err := j.GetKey(testKid)
+			if tc.err == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+				t.Fatalf("expecting an error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+
+	plain, _ := newTestJWKSServer(t, "", 0)
+	defer plain.Close()
+	j := &JSONWebKeys{JWKURL: plain.URL, PinnedServerSPKI: []string{pin}}
+	if _, err := j.GetKey(testKid); err == nil || !strings.Contains(err.Error(), "require TLS") {
+		t.Fatalf("expecting plain HTTP to be refused, got %v", err)
+	}
+	j.Introspection = &Introspection{Endpoint: plain.URL}
+	if _, err := j.VerifyToken(context.Background(), "opaque"); err == nil || !strings.Contains(err.Error(), "require TLS") {
+		t.Fatalf("expecting plain HTTP introspection to be refused, got %v", err)
+	}
+}
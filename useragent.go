@@ -0,0 +1,45 @@
+package jwk
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"runtime/debug"
+	"sync"
+)
+
+// modulePath is the import path of this module, used to find its version in the build info
+const modulePath = "github.com/serjlee/jwk-go"
+
+var (
+	defaultUserAgent     string
+	defaultUserAgentOnce sync.Once
+)
+
+// userAgent returns the User-Agent sent with the fetches: UserAgent, or jwk-go/<version> where version is read
+// from the build info of the binary
+func (j *JSONWebKeys) userAgent() string {
+	if j.UserAgent != "" {
+		return j.UserAgent
+	}
+	defaultUserAgentOnce.Do(func() {
+		version := "devel"
+		if info, ok := debug.ReadBuildInfo(); ok {
+			for _, dep := range append(info.Deps, &info.Main) {
+				if dep.Path == modulePath && dep.Version != "" && dep.Version != "(devel)" {
+					version = dep.Version
+				}
+			}
+		}
+		defaultUserAgent = "jwk-go/" + version
+	})
+	return defaultUserAgent
+}
+
+// newCorrelationID returns a random ID identifying a single fetch
+func newCorrelationID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,180 @@
+package jwk
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultUserInfoCacheAge is how long the userinfo responses are cached when CacheAge is not set
+	defaultUserInfoCacheAge = time.Minute
+	// defaultUserInfoEntries bounds the cached userinfo responses when MaxEntries is not set
+	defaultUserInfoEntries = 1024
+)
+
+// UserInfo fetches the OpenID Connect userinfo of access tokens, to enrich the claims of a verified token,
+// caching the responses by token hash. See https://openid.net/specs/openid-connect-core-1_0.html#UserInfo
+type UserInfo struct {
+	// Issuer is the OpenID Connect issuer, whose discovery document gives the userinfo endpoint
+	Issuer string
+
+	// Endpoint is the userinfo endpoint, skipping the discovery when set
+	Endpoint string
+
+	// Client is the HTTP client used for the discovery and the userinfo requests. If nil a Client with a
+	// 10-seconds timeout is used
+	Client *http.Client
+
+	// CacheAge is how long a response is reused for the same access token, one minute by default
+	CacheAge time.Duration
+
+	// MaxEntries bounds the cached responses, 1024 by default
+	MaxEntries int
+
+	// cache holds the responses by access token hash, guarded by cacheMutex
+	cache      map[[sha256.Size]byte]userInfoEntry
+	cacheMutex sync.Mutex
+
+	// endpoint is the discovered userinfo endpoint, guarded by endpointMutex
+	endpoint      string
+	endpointMutex sync.Mutex
+}
+
+// userInfoEntry is a cached userinfo response
+type userInfoEntry struct {
+	claims map[string]interface{}
+	expiry time.Time
+}
+
+// Get returns the userinfo claims of the given access token, from the cache when fresh
+func (u *UserInfo) Get(ctx context.Context, accessToken string) (map[string]interface{}, error) {
+	hash := sha256.Sum256([]byte(accessToken))
+	u.cacheMutex.Lock()
+	entry, ok := u.cache[hash]
+	u.cacheMutex.Unlock()
+	if ok && time.Now().Before(entry.expiry) {
+		return entry.claims, nil
+	}
+
+	claims, err := u.fetch(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	u.store(hash, claims)
+	return claims, nil
+}
+
+// Merge adds the userinfo claims of the given access token to the claims of the verified token, the latter
+// winning on conflicts. The claims are left untouched on error
+func (u *UserInfo) Merge(ctx context.Context, accessToken string, claims map[string]interface{}) error {
+	info, err := u.Get(ctx, accessToken)
+	if err != nil {
+		return err
+	}
+	for name, value := range info {
+		if _, ok := claims[name]; !ok {
+			claims[name] = value
+		}
+	}
+	return nil
+}
+
+// fetch requests the userinfo of the given access token
+func (u *UserInfo) fetch(ctx context.Context, accessToken string) (map[string]interface{}, error) {
+	endpoint, err := u.userInfoEndpoint()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build userinfo request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := u.client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("userinfo request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{URL: endpoint, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	body, err := readBody(resp, defaultMaxDocumentSize)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read userinfo response: %w", err)
+	}
+	claims := map[string]interface{}{}
+	if err := json.Unmarshal(body, &claims); err != nil {
+		return nil, fmt.Errorf("unable to decode userinfo response: %w", err)
+	}
+	return claims, nil
+}
+
+// store caches the given response, making room by dropping the expired entries, or any entry when none is
+func (u *UserInfo) store(hash [sha256.Size]byte, claims map[string]interface{}) {
+	cacheAge := u.CacheAge
+	if cacheAge <= 0 {
+		cacheAge = defaultUserInfoCacheAge
+	}
+	limit := u.MaxEntries
+	if limit <= 0 {
+		limit = defaultUserInfoEntries
+	}
+
+	u.cacheMutex.Lock()
+	defer u.cacheMutex.Unlock()
+	if u.cache == nil {
+		u.cache = map[[sha256.Size]byte]userInfoEntry{}
+	}
+	if len(u.cache) >= limit {
+		now := time.Now()
+		for key, entry := range u.cache {
+			if now.After(entry.expiry) {
+				delete(u.cache, key)
+			}
+		}
+		for key := range u.cache {
+			if len(u.cache) < limit {
+				break
+			}
+			delete(u.cache, key)
+		}
+	}
+	u.cache[hash] = userInfoEntry{claims: claims, expiry: time.Now().Add(cacheAge)}
+}
+
+// userInfoEndpoint returns Endpoint, or the one discovered from Issuer once
+func (u *UserInfo) userInfoEndpoint() (string, error) {
+	if u.Endpoint != "" {
+		return u.Endpoint, nil
+	}
+	u.endpointMutex.Lock()
+	defer u.endpointMutex.Unlock()
+	if u.endpoint != "" {
+		return u.endpoint, nil
+	}
+	metadata, err := discover(u.Client, u.Issuer)
+	if err != nil {
+		return "", err
+	}
+	if metadata.UserInfoEndpoint == "" {
+		return "", errors.New("discovery document does not define a userinfo_endpoint")
+	}
+	u.endpoint = metadata.UserInfoEndpoint
+	return u.endpoint, nil
+}
+
+// client returns Client, or a Client with a 10-seconds timeout
+func (u *UserInfo) client() *http.Client {
+	if u.Client != nil {
+		return u.Client
+	}
+	return &http.Client{Timeout: 10 * time.Second}
+}
@@ -0,0 +1,73 @@
+package jwk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestUserInfo(t *testing.T) {
+	var requests int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case discoveryPath:
+			w.Write([]byte(`{"issuer":"` + server.URL + `","userinfo_endpoint":"` + server.URL + `/userinfo"}`))
+		case "/userinfo":
+			atomic.AddInt32(&requests, 1)
+			if r.Header.Get("Authorization") != "Bearer token-a" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"sub":"user","email":"user@example.com"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	u := &UserInfo{Issuer: server.URL}
+	claims := map[string]interface{}{"sub": "user", "scope": "read"}
+	if err := u.Merge(context.Background(), "token-a", claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims["email"] != "user@example.com" || claims["scope"] != "read" {
+		t.Fatalf("unexpected merged claims %v", claims)
+	}
+	if _, err := u.Get(context.Background(), "token-a"); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatalf("expecting the response to be cached, got %d requests", n)
+	}
+
+	if _, err := u.Get(context.Background(), "token-b"); err == nil {
+		t.Fatal("expecting the unauthorized status to be reported")
+	}
+}
+
+func TestUserInfoCacheBound(t *testing.T) {
+	u := &UserInfo{MaxEntries: 2}
+	for i := byte(0); i < 5; i++ {
+		u.store([32]byte{i}, map[string]interface{}{})
+	}
+	if len(u.cache) != 2 {
+		t.Fatalf("expecting at most 2 cached entries, got %d", len(u.cache))
+	}
+}
+
+func TestUserInfoTooLarge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"sub":"` + strings.Repeat("a", defaultMaxDocumentSize) + `"}`))
+	}))
+	defer server.Close()
+
+	u := &UserInfo{Endpoint: server.URL}
+	if _, err := u.Get(context.Background(), "token"); !errors.Is(err, ErrDocumentTooLarge) {
+		t.Fatalf("expecting an oversized response to be rejected, got %v", err)
+	}
+}
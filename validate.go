@@ -0,0 +1,179 @@
+package jwk
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// requiredMembers maps each supported key type to the members it can't do without
+var requiredMembers = map[string][]string{
+	"RSA": {"n", "e"},
+	"EC":  {"crv", "x", "y"},
+	"OKP": {"crv", "x"},
+	"oct": {"k"},
+}
+
+// jweAlgKeyTypes maps the JWE key management algorithms to the key type they require
+var jweAlgKeyTypes = map[string]string{
+	"RSA1_5":         "RSA",
+	"RSA-OAEP":       "RSA",
+	"RSA-OAEP-256":   "RSA",
+	"ECDH-ES":        "EC",
+	"ECDH-ES+A128KW": "EC",
+	"ECDH-ES+A192KW": "EC",
+	"ECDH-ES+A256KW": "EC",
+}
+
+// hmacKeySizes maps the HMAC algorithms to their minimum secret size, see RFC 7518 section 3.2
+var hmacKeySizes = map[string]int{
+	"HS256": 32,
+	"HS384": 48,
+	"HS512": 64,
+}
+
+// algCurves maps the JWS algorithms bound to a single curve to that curve
+var algCurves = map[string]string{
+	"ES256": "P-256",
+	"ES384": "P-384",
+	"ES512": "P-521",
+	"EdDSA": "Ed25519",
+}
+
+// keyOpsByUse maps each use to the key_ops values consistent with it
+var keyOpsByUse = map[string]map[string]bool{
+	"sig": {"sign": true, "verify": true},
+	"enc": {"encrypt": true, "decrypt": true, "wrapKey": true, "unwrapKey": true, "deriveKey": true, "deriveBits": true},
+}
+
+// Validate checks the consistency of the key members per RFC 7517 and RFC 7518: the members required by its kty
+// must be present and properly encoded, its alg must suit its kty and curve, its use must agree with its key_ops
+// and alg, and its first x5c certificate must hold the same public key
+func (k Key) Validate() error {
+	if k.Kty == "" {
+		return errors.New("missing kty")
+	}
+	required, ok := requiredMembers[k.Kty]
+	if !ok {
+		return fmt.Errorf("unsupported kty %q", k.Kty)
+	}
+	members := map[string]string{"n": k.N, "e": k.E, "crv": k.Crv, "x": k.X, "y": k.Y}
+	for _, name := range required {
+		if _, ok := k.Extra[name]; !ok && members[name] == "" {
+			return fmt.Errorf("missing member %s", name)
+		}
+	}
+	if k.Kty == "oct" {
+		return k.validateSecret()
+	}
+
+	publicKey, err := k.PublicKey()
+	if err != nil {
+		return err
+	}
+	if rsaKey, ok := publicKey.(*rsa.PublicKey); ok && (rsaKey.N.Sign() <= 0 || rsaKey.E <= 1) {
+		return errors.New("invalid RSA modulus or exponent")
+	}
+
+	if err := k.validateAlg(); err != nil {
+		return err
+	}
+	if err := k.validateUse(); err != nil {
+		return err
+	}
+	return k.validateX5c(publicKey)
+}
+
+// validateSecret makes sure the k member of an oct key decodes, to a secret as long as the hash of its HMAC alg
+func (k Key) validateSecret() error {
+	secret, err := k.Secret()
+	if err != nil {
+		return err
+	}
+	if err := k.validateAlg(); err != nil {
+		return err
+	}
+	if size, ok := hmacKeySizes[k.Alg]; ok && len(secret) < size {
+		return fmt.Errorf("alg %q requires a secret of at least %d bytes", k.Alg, size)
+	}
+	return k.validateUse()
+}
+
+// validateAlg makes sure the alg, when set and known, suits the key type and curve
+func (k Key) validateAlg() error {
+	if k.Alg == "" {
+		return nil
+	}
+	if k.Alg == "none" {
+		return errors.New(`alg "none" is not allowed`)
+	}
+	kty, ok := algKeyTypes[k.Alg]
+	if !ok {
+		kty, ok = jweAlgKeyTypes[k.Alg]
+	}
+	if !ok {
+		// unknown algorithms are left to the application
+		return nil
+	}
+	if k.Kty == "OKP" && strings.HasPrefix(k.Alg, "ECDH-ES") {
+		// X25519 keys agree on ECDH-ES keys just like the EC ones
+		if k.Crv != "X25519" {
+			return fmt.Errorf("alg %q can't be used with curve %q", k.Alg, k.Crv)
+		}
+		return nil
+	}
+	if kty != k.Kty {
+		return fmt.Errorf("alg %q can't be used with a %s key", k.Alg, k.Kty)
+	}
+	if crv, ok := algCurves[k.Alg]; ok && crv != k.Crv {
+		return fmt.Errorf("alg %q can't be used with curve %q", k.Alg, k.Crv)
+	}
+	return nil
+}
+
+// validateUse makes sure use, key_ops and alg agree with each other
+func (k Key) validateUse() error {
+	seen := map[string]bool{}
+	for _, op := range k.KeyOps {
+		if seen[op] {
+			return fmt.Errorf("duplicate key_ops value %q", op)
+		}
+		seen[op] = true
+		if ops, ok := keyOpsByUse[k.Use]; ok && !ops[op] {
+			return fmt.Errorf("key_ops value %q is inconsistent with use %q", op, k.Use)
+		}
+	}
+	if k.Crv == "X25519" && k.Use == "sig" {
+		return errors.New(`curve "X25519" is inconsistent with use "sig"`)
+	}
+	if _, ok := algKeyTypes[k.Alg]; ok && k.Use == "enc" {
+		return fmt.Errorf("signature alg %q is inconsistent with use %q", k.Alg, k.Use)
+	}
+	if _, ok := jweAlgKeyTypes[k.Alg]; ok && k.Use == "sig" {
+		return fmt.Errorf("encryption alg %q is inconsistent with use %q", k.Alg, k.Use)
+	}
+	return nil
+}
+
+// validateX5c makes sure every certificate is properly encoded and the first one holds the given public key
+func (k Key) validateX5c(publicKey interface{}) error {
+	certs, err := k.Certificates()
+	if err != nil || len(certs) == 0 {
+		return err
+	}
+	certKey, err := x509.MarshalPKIXPublicKey(certs[0].PublicKey)
+	if err != nil {
+		return fmt.Errorf("unsupported x5c leaf certificate key: %w", err)
+	}
+	key, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(certKey, key) {
+		return errors.New("x5c leaf certificate does not match the key")
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package jwk
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := FromPublicKey(&ecdsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey.Alg, ecKey.Use = "ES256", "sig"
+
+	valid := []Key{
+		testKey,
+		ecKey,
+		{Kty: "RSA", N: testKey.N, E: testKey.E, Use: "enc", Alg: "RSA-OAEP", KeyOps: []string{"wrapKey", "unwrapKey"}},
+		{Kty: "RSA", N: testKey.N, E: testKey.E, Alg: "RS256", KeyOps: []string{"verify"}},
+		{Kty: "RSA", N: testKey.N, E: testKey.E, Alg: "custom-alg"},
+		{Kty: "EC", Crv: "P-256", X: ecKey.X, Y: ecKey.Y, Use: "enc", Alg: "ECDH-ES+A128KW"},
+		{Kty: "OKP", Crv: "X25519", X: testX25519, Use: "enc", Alg: "ECDH-ES"},
+	}
+	for _, key := range valid {
+		if err := key.Validate(); err != nil {
+			t.Errorf("unexpected error for %+v: %v", key, err)
+		}
+	}
+
+	withKey := func(edit func(k *Key)) Key {
+		key := testKey
+		edit(&key)
+		return key
+	}
+	wrongCurve := ecKey
+	wrongCurve.Alg = "ES384"
+	invalid := map[string]Key{
+		"missing kty":                        {N: testKey.N, E: testKey.E},
+		`unsupported kty "foo"`:              {Kty: "foo"},
+		"missing member k":                   {Kty: "oct"},
+		"missing member e":                   withKey(func(k *Key) { k.E = "" }),
+		"missing member y":                   {Kty: "EC", Crv: "P-256", X: ecKey.X},
+		"illegal base64":                     withKey(func(k *Key) { k.N = "not base64!" }),
+		"exponent too large":                 withKey(func(k *Key) { k.E = "AQAAAAAAAAAAAQ" }),
+		"invalid RSA modulus":                withKey(func(k *Key) { k.N = "AA" }),
+		`alg "none"`:                         withKey(func(k *Key) { k.Alg = "none" }),
+		`alg "ES256" can't be used`:          withKey(func(k *Key) { k.Alg = "ES256" }),
+		`can't be used with curve "P-256"`:   wrongCurve,
+		"inconsistent with use":              withKey(func(k *Key) { k.KeyOps = []string{"encrypt"} }),
+		"duplicate key_ops":                  withKey(func(k *Key) { k.KeyOps = []string{"verify", "verify"} }),
+		`signature alg "RS256" is inconsist`: withKey(func(k *Key) { k.Use = "enc" }),
+		"malformed x5c certificate 1":        withKey(func(k *Key) { k.X5c = []string{testX5c, "%%%"} }),
+		"does not match the key":             withKey(func(k *Key) { k.N = ecKey.X }),
+		`can't be used with curve "Ed25519"`: {Kty: "OKP", Crv: "Ed25519", X: testX25519, Alg: "ECDH-ES"},
+		`"X25519" is inconsistent with use`:  {Kty: "OKP", Crv: "X25519", X: testX25519, Use: "sig"},
+		`alg "EdDSA" can't be used with cur`: {Kty: "OKP", Crv: "X25519", X: testX25519, Alg: "EdDSA"},
+	}
+	for expected, key := range invalid {
+		err := key.Validate()
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expecting an error containing %q, got %v", expected, err)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package jwk
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VaultFetcher reads the JWKS document from a HashiCorp Vault KV secret, for internal issuers publishing their
+// keys through Vault. The secret holds the document in its Field member, or else one PEM public key or
+// certificate per member, named after the kid of the key
+type VaultFetcher struct {
+	// Address is the URL of the Vault server, i.e. https://vault.internal:8200
+	Address string
+
+	// Path is the API path of the secret under /v1, i.e. secret/data/idp/jwks for a KV version 2 engine
+	// mounted at secret/
+	Path string
+
+	// Field is the member of the secret holding the JWKS document, jwks by default
+	Field string
+
+	// Token authenticates the requests. When empty the fetcher logs in with AppRole using RoleID and SecretID,
+	// logging in again once the token is refused
+	Token    string
+	RoleID   string
+	SecretID string
+
+	// Namespace is sent as X-Vault-Namespace when set, for Vault Enterprise namespaces
+	Namespace string
+
+	// Client is the HTTP client of the requests. If unset it will default to a Client with a 10-seconds timeout
+	Client *http.Client
+
+	// CacheAge is how long the document is cached, DefaultCacheAge of JSONWebKeys when zero
+	CacheAge time.Duration
+
+	// loginMutex guards loginToken, the token of the last AppRole login
+	loginMutex sync.Mutex
+	loginToken string
+}
+
+// vaultResponse is the envelope of the Vault API responses
+type vaultResponse struct {
+	Data map[string]json.RawMessage `json:"data"`
+	Auth struct {
+		ClientToken string `json:"client_token"`
+	} `json:"auth"`
+}
+
+// Fetch implements Fetcher
+func (v *VaultFetcher) Fetch(ctx context.Context) ([]byte, time.Duration, error) {
+	secret, err := v.read(ctx, false)
+	if err != nil {
+		var status *StatusError
+		if !errors.As(err, &status) || status.StatusCode != http.StatusForbidden || v.Token != "" {
+			return nil, 0, err
+		}
+		if secret, err = v.read(ctx, true); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	body, err := v.document(secret)
+	if err != nil {
+		return nil, 0, fmt.Errorf("malformed secret %s: %w", v.Path, err)
+	}
+	return body, v.CacheAge, nil
+}
+
+// String describes the source of the certs
+func (v *VaultFetcher) String() string {
+	return strings.TrimSuffix(v.Address, "/") + "/v1/" + v.Path
+}
+
+// read reads the data of the secret, unwrapping the one of KV version 2 secrets. When relogin is set a new token
+// is obtained with AppRole first
+func (v *VaultFetcher) read(ctx context.Context, relogin bool) (map[string]json.RawMessage, error) {
+	token, err := v.token(ctx, relogin)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := v.call(ctx, http.MethodGet, v.Path, token, nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := resp.Data["metadata"]; ok {
+		data := map[string]json.RawMessage{}
+		if err := json.Unmarshal(resp.Data["data"], &data); err != nil {
+			return nil, fmt.Errorf("malformed secret %s: %w", v.Path, err)
+		}
+		return data, nil
+	}
+	return resp.Data, nil
+}
+
+// token returns Token, or the token of an AppRole login done once, or again when relogin is set
+func (v *VaultFetcher) token(ctx context.Context, relogin bool) (string, error) {
+	if v.Token != "" {
+		return v.Token, nil
+	}
+	v.loginMutex.Lock()
+	defer v.loginMutex.Unlock()
+	if v.loginToken != "" && !relogin {
+		return v.loginToken, nil
+	}
+
+	credentials, err := json.Marshal(map[string]string{"role_id": v.RoleID, "secret_id": v.SecretID})
+	if err != nil {
+		return "", err
+	}
+	resp, err := v.call(ctx, http.MethodPost, "auth/approle/login", "", credentials)
+	if err != nil {
+		return "", fmt.Errorf("AppRole login failed: %w", err)
+	}
+	if resp.Auth.ClientToken == "" {
+		return "", errors.New("AppRole login returned no token")
+	}
+	v.loginToken = resp.Auth.ClientToken
+	return v.loginToken, nil
+}
+
+// call sends a request to the Vault API, decoding its response
+func (v *VaultFetcher) call(ctx context.Context, method, path, token string, payload []byte) (*vaultResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(v.Address, "/")+"/v1/"+strings.TrimPrefix(path, "/"),
+		bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("unable to build Vault request: %w", err)
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if v.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.Namespace)
+	}
+
+	resp, err := objectClient(v.Client).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{URL: req.URL.String(), StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	body, err := readBody(resp, contextMaxDocumentSize(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read Vault response: %w", err)
+	}
+	decoded := &vaultResponse{}
+	if err := json.Unmarshal(body, decoded); err != nil {
+		return nil, fmt.Errorf("unable to decode Vault response: %w", err)
+	}
+	return decoded, nil
+}
+
+// document returns the JWKS document of the secret: its Field member, as a string or a JSON object, or else
+// the document of its PEM members
+func (v *VaultFetcher) document(secret map[string]json.RawMessage) ([]byte, error) {
+	field := v.Field
+	if field == "" {
+		field = "jwks"
+	}
+	if raw, ok := secret[field]; ok {
+		encoded := ""
+		if err := json.Unmarshal(raw, &encoded); err == nil {
+			return []byte(encoded), nil
+		}
+		return raw, nil
+	}
+
+	kids := make([]string, 0, len(secret))
+	for kid := range secret {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+	keys := make([]Key, 0, len(kids))
+	for _, kid := range kids {
+		encoded := ""
+		if err := json.Unmarshal(secret[kid], &encoded); err != nil {
+			return nil, fmt.Errorf("member %q is not a string", kid)
+		}
+		key, err := fromPEM([]byte(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("member %q: %w", kid, err)
+		}
+		key.Kid, key.Use = kid, "sig"
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no %s member nor PEM key", field)
+	}
+	return json.Marshal(jwks{Keys: keys})
+}
+
+// fromPEM builds a Key from a PEM certificate, PKIX or PKCS #1 public key
+func fromPEM(data []byte) (Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, errors.New("no PEM block")
+	}
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return Key{}, err
+		}
+		return FromCertificate(cert)
+	case "PUBLIC KEY":
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return Key{}, err
+		}
+		return FromPublicKey(pub)
+	case "RSA PUBLIC KEY":
+		pub, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return Key{}, err
+		}
+		return FromPublicKey(pub)
+	default:
+		return Key{}, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
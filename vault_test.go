@@ -0,0 +1,88 @@
+package jwk
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestVaultFetcherAppRole(t *testing.T) {
+	var logins int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/approle/login":
+			credentials := map[string]string{}
+			json.NewDecoder(r.Body).Decode(&credentials)
+			if credentials["role_id"] != "role" || credentials["secret_id"] != "secret" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			// the first token is refused, as if it expired
+			token := "expired"
+			if atomic.AddInt32(&logins, 1) > 1 {
+				token = "valid"
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"auth": map[string]string{"client_token": token}})
+		case "/v1/secret/data/idp":
+			if r.Header.Get("X-Vault-Token") != "valid" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{
+				"data":     map[string]string{"rsa": withPEMHeaders(testX5c)},
+				"metadata": map[string]int{"version": 1},
+			}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	fetcher := &VaultFetcher{Address: server.URL, Path: "secret/data/idp", RoleID: "role", SecretID: "secret"}
+	j := &JSONWebKeys{Fetcher: fetcher}
+	certs, err := j.GetKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, ok := certs.Get("rsa")
+	if !ok || key.N != testKey.N || len(key.X5c) != 1 {
+		t.Fatalf("expecting the PEM certificate to be converted, got %+v", certs.Keys)
+	}
+	if atomic.LoadInt32(&logins) != 2 {
+		t.Fatalf("expecting a login again once the token is refused, got %d logins", atomic.LoadInt32(&logins))
+	}
+}
+
+func TestVaultFetcherDocument(t *testing.T) {
+	document, err := ioutil.ReadFile("testdata/jwks.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/kv/idp" || r.Header.Get("X-Vault-Token") != "token" ||
+			r.Header.Get("X-Vault-Namespace") != "team" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{"keys": string(document)}})
+	}))
+	defer server.Close()
+
+	fetcher := &VaultFetcher{Address: server.URL, Path: "kv/idp", Field: "keys", Token: "token", Namespace: "team"}
+	body, _, err := fetcher.Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != string(document) {
+		t.Fatalf("unexpected document %s", body)
+	}
+
+	fetcher.Token = "refused"
+	if _, _, err := fetcher.Fetch(context.Background()); err == nil {
+		t.Fatal("expecting a refused token to fail")
+	}
+}
@@ -0,0 +1,374 @@
+package jwk
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+// VerifyToken checks the signature of the given compact JWT against the key matching its kid, then validates
+// its registered claims: exp and nbf are always enforced, iss and aud only when Issuer and Audience are set.
+// RequiredClaims and ClaimValidator are checked last. It returns all the claims of the verified token.
+// The options can tune the key lookup and override the expected audiences, i.e. per route with ExpectAudience.
+// Opaque tokens are checked with Introspection, and nested JWTs decrypted with DecryptionKeys, when set.
+// Errors are redacted, see RedactError
+func (j *JSONWebKeys) VerifyToken(ctx context.Context, raw string, opts ...CallOption) (map[string]interface{}, error) {
+	start := time.Now()
+	claims, err := j.verifyToken(ctx, raw, opts)
+	err = RedactError(err)
+	j.audit(ctx, start, raw, claims, err)
+	return claims, err
+}
+
+// verifyToken verifies the token, see VerifyToken
+func (j *JSONWebKeys) verifyToken(ctx context.Context, raw string, opts []CallOption) (map[string]interface{}, error) {
+	if len(j.DecryptionKeys) > 0 && strings.Count(raw, ".") == 4 {
+		inner, err := j.decryptNested(raw)
+		if err != nil {
+			return nil, err
+		}
+		raw = inner
+	}
+	if j.Introspection != nil && strings.Count(raw, ".") != 2 {
+		return j.introspect(ctx, raw, opts)
+	}
+	registered, claims, err := j.verifiedClaims(ctx, raw, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := j.validateClaims(ctx, registered, claims, opts); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// validateClaims validates the claims of a verified token, see VerifyToken
+func (j *JSONWebKeys) validateClaims(ctx context.Context, registered jwt.Claims, claims map[string]interface{}, opts []CallOption) error {
+	config := j.config()
+	if err := registered.Validate(jwt.Expected{Time: time.Now()}); err != nil {
+		return fmt.Errorf("invalid token claims: %w", err)
+	}
+	if err := j.checkIssuer(config.Issuer, registered.Issuer); err != nil {
+		return fmt.Errorf("invalid token claims: %w", err)
+	}
+	audiences, match := config.Audiences, config.AudienceMatch
+	if options := newCallOptions(opts); options.audiences != nil {
+		audiences, match = options.audiences, options.audienceMatch
+	}
+	if !matchAudience(registered.Audience, audiences, match) {
+		return fmt.Errorf("invalid token claims: %w", jwt.ErrInvalidAudience)
+	}
+	if err := j.checkClaims(claims); err != nil {
+		return err
+	}
+	if j.Revocation != nil {
+		revoked, err := j.Revocation.Revoked(ctx, claims)
+		if err != nil {
+			return fmt.Errorf("unable to check token revocation: %w", err)
+		}
+		if revoked {
+			return errors.New("token has been revoked")
+		}
+	}
+	return nil
+}
+
+// verifyTokenSignature checks the headers and the signature of the given compact JWT, returning its claims
+func (j *JSONWebKeys) verifyTokenSignature(ctx context.Context, raw string, opts []CallOption) (jwt.Claims, map[string]interface{}, error) {
+	token, err := jwt.ParseSigned(raw)
+	if err != nil {
+		return jwt.Claims{}, nil, fmt.Errorf("unable to parse token: %w", err)
+	}
+
+	if len(token.Headers) != 1 {
+		return jwt.Claims{}, nil, errors.New("expecting a token with a single signature")
+	}
+	if err := j.checkHeader(token.Headers[0]); err != nil {
+		return jwt.Claims{}, nil, err
+	}
+	key, err := j.keyForHeader(ctx, token.Headers[0], opts...)
+	if err != nil {
+		return jwt.Claims{}, nil, err
+	}
+	publicKey, err := key.verificationKey()
+	if err != nil {
+		return jwt.Claims{}, nil, fmt.Errorf("malformed key: %w", err)
+	}
+
+	registered := jwt.Claims{}
+	claims := map[string]interface{}{}
+	if payload, ok, err := verifyCustomAlg(raw, token.Headers[0].Algorithm, publicKey); ok {
+		if err != nil {
+			return jwt.Claims{}, nil, fmt.Errorf("invalid token signature: %w", err)
+		}
+		if err := json.Unmarshal(payload, &registered); err != nil {
+			return jwt.Claims{}, nil, fmt.Errorf("malformed token claims: %w", err)
+		}
+		if err := json.Unmarshal(payload, &claims); err != nil {
+			return jwt.Claims{}, nil, fmt.Errorf("malformed token claims: %w", err)
+		}
+	} else if err := token.Claims(publicKey, &registered, &claims); err != nil {
+		return jwt.Claims{}, nil, fmt.Errorf("invalid token signature: %w", err)
+	}
+
+	return registered, claims, nil
+}
+
+// checkHeader runs the checks of the token header not depending on its key: the typ and cty media types, and
+// the alg allowed by the configuration
+func (j *JSONWebKeys) checkHeader(header jose.Header) error {
+	if err := checkMediaType(header, "typ", j.Types); err != nil {
+		return err
+	}
+	if err := checkMediaType(header, "cty", j.ContentTypes); err != nil {
+		return err
+	}
+	return j.config().checkAllowedAlg(header.Algorithm)
+}
+
+// checkClaims runs the RequiredClaims and ClaimValidator checks on the claims of a token
+func (j *JSONWebKeys) checkClaims(claims map[string]interface{}) error {
+	for _, name := range j.RequiredClaims {
+		if value, ok := claims[name]; !ok || value == nil || value == "" {
+			return fmt.Errorf("invalid token claims: missing %s", name)
+		}
+	}
+	if j.ClaimValidator != nil {
+		if err := j.ClaimValidator(claims); err != nil {
+			return fmt.Errorf("invalid token claims: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetKeyForToken finds the key matching the header of the given token: by kid first, falling back to the x5t
+// certificate thumbprint. It also makes sure the header alg can be used with the key, rejecting a mismatching
+// key alg or type
+func (j *JSONWebKeys) GetKeyForToken(ctx context.Context, token *jwt.JSONWebToken) (Key, error) {
+	if len(token.Headers) != 1 {
+		return Key{}, errors.New("expecting a token with a single signature")
+	}
+	return j.keyForHeader(ctx, token.Headers[0])
+}
+
+// VerifySignature checks the signature of the given compact JWS against the key matching its header, returning
+// its payload. No claim is validated: along with its signature, it makes JSONWebKeys satisfy the KeySet
+// interface of github.com/coreos/go-oidc, whose verifier takes care of the claims
+func (j *JSONWebKeys) VerifySignature(ctx context.Context, raw string) ([]byte, error) {
+	jws, err := jose.ParseSigned(raw)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse token: %w", err)
+	}
+	if len(jws.Signatures) != 1 {
+		return nil, errors.New("expecting a token with a single signature")
+	}
+
+	header := jws.Signatures[0].Header
+	key, err := j.keyForHeader(ctx, header)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := key.verificationKey()
+	if err != nil {
+		return nil, fmt.Errorf("malformed key: %w", err)
+	}
+	payload, ok, err := verifyCustomAlg(raw, header.Algorithm, publicKey)
+	if !ok {
+		payload, err = jws.Verify(publicKey)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid token signature: %w", err)
+	}
+	return payload, nil
+}
+
+// VerifyJWS checks a JWS in compact or JSON serialization against the keys matching its headers, returning its
+// payload and the key that signed it, for the JWS other than JWTs: signed metadata documents, software
+// statements and the like. A JSON JWS holding several signatures is valid as soon as one of them is.
+// Errors are redacted, see RedactError
+func (j *JSONWebKeys) VerifyJWS(ctx context.Context, compactOrJSON []byte) ([]byte, Key, error) {
+	start := time.Now()
+	raw := strings.TrimSpace(string(compactOrJSON))
+	payload, key, err := j.verifyJWS(ctx, raw)
+	err = RedactError(err)
+	j.audit(ctx, start, raw, nil, err)
+	return payload, key, err
+}
+
+// verifyJWS verifies the JWS, see VerifyJWS
+func (j *JSONWebKeys) verifyJWS(ctx context.Context, raw string) ([]byte, Key, error) {
+	jws, err := jose.ParseSigned(raw)
+	if err != nil {
+		return nil, Key{}, fmt.Errorf("unable to parse JWS: %w", err)
+	}
+
+	compact := !strings.HasPrefix(raw, "{")
+	err = errors.New("no signature")
+	for _, signature := range jws.Signatures {
+		var key Key
+		key, err = j.keyForHeader(ctx, signature.Header)
+		if err != nil {
+			continue
+		}
+		var verificationKey interface{}
+		if verificationKey, err = key.verificationKey(); err != nil {
+			err = fmt.Errorf("malformed key: %w", err)
+			continue
+		}
+		var payload []byte
+		var ok bool
+		if compact {
+			payload, ok, err = verifyCustomAlg(raw, signature.Header.Algorithm, verificationKey)
+		}
+		if !ok {
+			// only this signature is checked: the key was picked by its header, not by the others
+			single := *jws
+			single.Signatures = []jose.Signature{signature}
+			payload, err = single.Verify(verificationKey)
+		}
+		if err == nil {
+			return payload, key, nil
+		}
+		err = fmt.Errorf("invalid signature: %w", err)
+	}
+	return nil, Key{}, err
+}
+
+// keyForHeader finds the key matching the given JWS header, see GetKeyForToken
+func (j *JSONWebKeys) keyForHeader(ctx context.Context, header jose.Header, opts ...CallOption) (Key, error) {
+	certs, err := j.getKeys(ctx, opts...)
+	if err != nil {
+		return Key{}, err
+	}
+	options := newCallOptions(opts)
+	key, ok := certs.Keys[header.KeyID]
+	if !ok && header.KeyID != "" && !options.cacheBypass && !options.forceRefresh {
+		if certs, err = j.refreshForKid(ctx, header.KeyID, certs); err != nil {
+			return Key{}, err
+		}
+		key, ok = certs.Keys[header.KeyID]
+	}
+	if !ok || header.KeyID == "" {
+		x5t, _ := header.ExtraHeaders["x5t"].(string)
+		key, ok = findByX5t(certs, x5t)
+	}
+	if !ok {
+		return Key{}, certs.missingKeyError(header.KeyID)
+	}
+
+	if err := j.config().checkAllowedAlg(header.Algorithm); err != nil {
+		return Key{}, err
+	}
+	if err := checkAlg(header.Algorithm, key); err != nil {
+		return Key{}, err
+	}
+	return key, nil
+}
+
+// checkMediaType makes sure the given media type header is among the accepted ones, when any
+func checkMediaType(header jose.Header, name string, accepted []string) error {
+	if len(accepted) == 0 {
+		return nil
+	}
+	value, _ := header.ExtraHeaders[jose.HeaderKey(name)].(string)
+	for _, expected := range accepted {
+		if value != "" && normalizeMediaType(value) == normalizeMediaType(expected) {
+			return nil
+		}
+	}
+	return fmt.Errorf("unexpected token %s %q", name, value)
+}
+
+// normalizeMediaType lowercases the media type, dropping the application/ prefix as allowed by RFC 7515
+func normalizeMediaType(mediaType string) string {
+	return strings.TrimPrefix(strings.ToLower(mediaType), "application/")
+}
+
+// algKeyTypes maps the supported JWS algorithms to the key type they require
+var algKeyTypes = map[string]string{
+	"RS256": "RSA",
+	"RS384": "RSA",
+	"RS512": "RSA",
+	"PS256": "RSA",
+	"PS384": "RSA",
+	"PS512": "RSA",
+	"ES256": "EC",
+	"ES384": "EC",
+	"ES512": "EC",
+	"EdDSA": "OKP",
+	"HS256": "oct",
+	"HS384": "oct",
+	"HS512": "oct",
+}
+
+// checkAlg makes sure a token signed with the given alg can be verified with the key: the alg must be a supported
+// one, never "none", and agree with the key alg, type and curve, as well as with its key_ops when set
+func checkAlg(alg string, key Key) error {
+	if alg == "none" {
+		return errors.New(`token alg "none" is not allowed`)
+	}
+	kty, ok := algKeyTypes[alg]
+	if !ok {
+		return fmt.Errorf("unsupported token alg %q", alg)
+	}
+	if key.Alg != "" && key.Alg != alg {
+		return fmt.Errorf("token alg %q does not match key alg %q", alg, key.Alg)
+	}
+	if key.Kty != kty {
+		return fmt.Errorf("token alg %q can't be used with a %s key", alg, key.Kty)
+	}
+	if crv, ok := algCurves[alg]; ok && key.Crv != crv {
+		return fmt.Errorf("token alg %q can't be used with curve %q", alg, key.Crv)
+	}
+	if key.Use == "enc" {
+		return errors.New("key use enc does not allow verification")
+	}
+	if len(key.KeyOps) > 0 && !containsString(key.KeyOps, "verify") {
+		return fmt.Errorf("key_ops %v does not allow verification", key.KeyOps)
+	}
+	return nil
+}
+
+// containsString tells whether the values hold the given one
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// findByX5t looks for the key with the given SHA-1 certificate thumbprint, either declared in its x5t member
+// or computed from its leaf certificate
+func findByX5t(certs *Certs, x5t string) (Key, bool) {
+	if x5t == "" {
+		return Key{}, false
+	}
+	for _, key := range certs.Keys {
+		if key.X5t == x5t {
+			return key, true
+		}
+		if len(key.X5c) == 0 {
+			continue
+		}
+		der, err := base64.StdEncoding.DecodeString(key.X5c[0])
+		if err != nil {
+			continue
+		}
+		sum := sha1.Sum(der)
+		if base64.RawURLEncoding.EncodeToString(sum[:]) == x5t {
+			return key, true
+		}
+	}
+	return Key{}, false
+}
@@ -0,0 +1,388 @@
+package jwk
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+// newTestSigner generates an RSA key, returning a signer for it and a JSONWebKeys already caching its public half
+func newTestSigner(t testing.TB, kid string) (jose.Signer, *JSONWebKeys) {
+	return newTestSignerAlg(t, kid, jose.RS256)
+}
+
+// newTestSignerAlg is newTestSigner with the given RSA alg
+func newTestSignerAlg(t testing.TB, kid string, alg jose.SignatureAlgorithm) (jose.Signer, *JSONWebKeys) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: alg, Key: privateKey},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", kid),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := Key{
+		Alg: string(alg),
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		N:   base64.RawURLEncoding.EncodeToString(privateKey.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(privateKey.E)).Bytes()),
+	}
+	certs, err := parseCerts(&jwks{Keys: []Key{key}}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer, &JSONWebKeys{cachedCerts: certs}
+}
+
+func signTestToken(t testing.TB, signer jose.Signer, claims jwt.Claims) string {
+	raw, err := jwt.Signed(signer).Claims(claims).Claims(map[string]interface{}{"scope": "read"}).CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestVerifyToken(t *testing.T) {
+	signer, j := newTestSigner(t, "test")
+	j.Issuer = "https://issuer.example.com/"
+	j.Audience = "api"
+
+	raw := signTestToken(t, signer, jwt.Claims{
+		Issuer:   "https://issuer.example.com/",
+		Audience: jwt.Audience{"api"},
+		Expiry:   jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	})
+	claims, err := j.VerifyToken(context.Background(), raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims["scope"] != "read" {
+		t.Fatalf("unexpected claims: %v", claims)
+	}
+}
+
+func TestVerifyTokenPSS(t *testing.T) {
+	for _, alg := range []jose.SignatureAlgorithm{jose.PS256, jose.PS384, jose.PS512} {
+		t.Run(string(alg), func(t *testing.T) {
+			signer, j := newTestSignerAlg(t, "test", alg)
+			raw := signTestToken(t, signer, jwt.Claims{Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour))})
+			if _, err := j.VerifyToken(context.Background(), raw); err != nil {
+				t.Fatal(err)
+			}
+
+			j.UpdateConfig(Config{Algorithms: []string{"RS256"}})
+			if _, err := j.VerifyToken(context.Background(), raw); err == nil || !strings.Contains(err.Error(), "not allowed") {
+				t.Fatalf("expecting %s to be rejected by the allowlist, got %v", alg, err)
+			}
+			j.UpdateConfig(Config{Algorithms: []string{string(alg)}})
+			if _, err := j.VerifyToken(context.Background(), raw); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	// a PSS token can't be verified with a key meant for PKCS #1 v1.5
+	signer, _ := newTestSignerAlg(t, "test", jose.PS256)
+	_, j := newTestSigner(t, "test")
+	raw := signTestToken(t, signer, jwt.Claims{Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour))})
+	if _, err := j.VerifyToken(context.Background(), raw); err == nil || !strings.Contains(err.Error(), "does not match key alg") {
+		t.Fatalf("expecting an alg mismatch, got %v", err)
+	}
+}
+
+func TestVerifyTokenInvalid(t *testing.T) {
+	signer, j := newTestSigner(t, "test")
+	j.Audience = "api"
+	otherSigner, _ := newTestSigner(t, "test")
+
+	tests := map[string]string{
+		"expired": signTestToken(t, signer, jwt.Claims{
+			Audience: jwt.Audience{"api"},
+			Expiry:   jwt.NewNumericDate(time.Now().Add(-time.Hour)),
+		}),
+		"wrong audience": signTestToken(t, signer, jwt.Claims{
+			Audience: jwt.Audience{"other"},
+		}),
+		"wrong signature": signTestToken(t, otherSigner, jwt.Claims{
+			Audience: jwt.Audience{"api"},
+		}),
+		"malformed": "not.a.token",
+	}
+	for name, raw := range tests {
+		if _, err := j.VerifyToken(context.Background(), raw); err == nil {
+			t.Errorf("%s: expecting an error", name)
+		}
+	}
+}
+
+// unsignedToken builds a token with the given header, only suitable for key lookups
+func unsignedToken(t *testing.T, header string) *jwt.JSONWebToken {
+	raw := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte("{}")) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte("signature"))
+	token, err := jwt.ParseSigned(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestGetKeyForToken(t *testing.T) {
+	testCerts, err := getTestCerts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	j := JSONWebKeys{cachedCerts: testCerts}
+
+	der, err := base64.StdEncoding.DecodeString(testX5c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha1.Sum(der)
+	x5t := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	found := []string{
+		`{"alg":"RS256","kid":"` + testKid + `"}`,
+		`{"alg":"RS256","x5t":"` + x5t + `"}`,
+		`{"alg":"RS256","kid":"unknown","x5t":"` + x5t + `"}`,
+	}
+	for _, header := range found {
+		key, err := j.GetKeyForToken(context.Background(), unsignedToken(t, header))
+		if err != nil {
+			t.Errorf("%s: %v", header, err)
+			continue
+		}
+		if key.Kid != testKid {
+			t.Errorf("%s: unexpected key %s", header, key.Kid)
+		}
+	}
+
+	notFound := []string{
+		`{"alg":"RS256","kid":"unknown"}`,
+		`{"alg":"RS384","kid":"` + testKid + `"}`,
+		`{"alg":"ES256","kid":"` + testKid + `"}`,
+		`{"alg":"HS256","kid":"` + testKid + `"}`,
+	}
+	for _, header := range notFound {
+		if _, err := j.GetKeyForToken(context.Background(), unsignedToken(t, header)); err == nil {
+			t.Errorf("%s: expecting an error", header)
+		}
+	}
+}
+
+// keySet is the KeySet interface of github.com/coreos/go-oidc
+type keySet interface {
+	VerifySignature(ctx context.Context, jwt string) (payload []byte, err error)
+}
+
+var _ keySet = &JSONWebKeys{}
+
+func TestVerifySignature(t *testing.T) {
+	signer, j := newTestSigner(t, "test")
+	otherSigner, _ := newTestSigner(t, "test")
+
+	// claims are not validated
+	expired := jwt.Claims{Subject: "user", Expiry: jwt.NewNumericDate(time.Now().Add(-time.Hour))}
+	payload, err := j.VerifySignature(context.Background(), signTestToken(t, signer, expired))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(payload), `"sub":"user"`) {
+		t.Fatalf("unexpected payload: %s", payload)
+	}
+
+	if _, err := j.VerifySignature(context.Background(), signTestToken(t, otherSigner, expired)); err == nil {
+		t.Fatal("expecting an error for a wrong signature")
+	}
+}
+
+func TestCheckAlg(t *testing.T) {
+	ecKey := Key{Kty: "EC", Crv: "P-256", Use: "sig"}
+	rsaKey := Key{Kty: "RSA", Use: "sig"}
+	tests := []struct {
+		alg string
+		key Key
+		err string
+	}{
+		{"RS256", testKey, ""},
+		{"ES256", ecKey, ""},
+		{"PS256", rsaKey, ""},
+		{"RS256", Key{Kty: "RSA", KeyOps: []string{"verify"}}, ""},
+		{"none", rsaKey, `"none" is not allowed`},
+		{"HS256", testKey, `does not match key alg "RS256"`},
+		{"RS256", ecKey, "can't be used with a EC key"},
+		{"ES256", rsaKey, "can't be used with a RSA key"},
+		{"HS256", rsaKey, "can't be used with a RSA key"},
+		{"ES384", ecKey, `can't be used with curve "P-256"`},
+		{"EdDSA", Key{Kty: "OKP", Crv: "X25519"}, `can't be used with curve "X25519"`},
+		{"RS256", Key{Kty: "RSA", KeyOps: []string{"encrypt"}}, "does not allow verification"},
+		{"RS256", Key{Kty: "RSA", Use: "enc"}, "use enc does not allow verification"},
+		{"XS256", rsaKey, "unsupported token alg"},
+	}
+	for _, test := range tests {
+		err := checkAlg(test.alg, test.key)
+		if test.err == "" && err != nil {
+			t.Errorf("unexpected error for %s with %+v: %v", test.alg, test.key, err)
+		}
+		if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("expecting %q for %s with %+v, got %v", test.err, test.alg, test.key, err)
+		}
+	}
+}
+
+func TestVerifyTokenClaimChecks(t *testing.T) {
+	signer, j := newTestSigner(t, "test")
+	raw := signTestToken(t, signer, jwt.Claims{Subject: "user", Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour))})
+
+	j.RequiredClaims = []string{"sub", "scope"}
+	if _, err := j.VerifyToken(context.Background(), raw); err != nil {
+		t.Fatal(err)
+	}
+	j.RequiredClaims = []string{"sub", "azp"}
+	if _, err := j.VerifyToken(context.Background(), raw); err == nil || err.Error() != "invalid token claims: missing azp" {
+		t.Fatalf("expecting the missing azp to be reported, got %v", err)
+	}
+
+	j.RequiredClaims = nil
+	j.ClaimValidator = func(claims map[string]interface{}) error {
+		if claims["scope"] != "write" {
+			return errors.New("scope write required")
+		}
+		return nil
+	}
+	if _, err := j.VerifyToken(context.Background(), raw); err == nil || err.Error() != "invalid token claims: scope write required" {
+		t.Fatalf("expecting the validator error, got %v", err)
+	}
+}
+
+func TestVerifyTokenTypes(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := FromPublicKey(&privateKey.PublicKey)
+	key.Kid, key.Use = "test", "sig"
+	certs, _ := parseCerts(&jwks{Keys: []Key{key}}, time.Hour)
+	j := &JSONWebKeys{cachedCerts: certs}
+	sign := func(typ string) string {
+		options := (&jose.SignerOptions{}).WithHeader("kid", "test")
+		if typ != "" {
+			options = options.WithType(jose.ContentType(typ))
+		}
+		signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: privateKey}, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signTestToken(t, signer, jwt.Claims{Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour))})
+	}
+
+	j.Types = []string{"at+jwt"}
+	for _, typ := range []string{"at+jwt", "application/AT+JWT"} {
+		if _, err := j.VerifyToken(context.Background(), sign(typ)); err != nil {
+			t.Fatalf("unexpected error for typ %q: %v", typ, err)
+		}
+	}
+	for _, typ := range []string{"JWT", ""} {
+		if _, err := j.VerifyToken(context.Background(), sign(typ)); err == nil || !strings.Contains(err.Error(), "unexpected token typ") {
+			t.Fatalf("expecting typ %q to be rejected, got %v", typ, err)
+		}
+	}
+
+	j.Types, j.ContentTypes = nil, []string{"JWT"}
+	if _, err := j.VerifyToken(context.Background(), sign("")); err == nil || !strings.Contains(err.Error(), "unexpected token cty") {
+		t.Fatalf("expecting the missing cty to be rejected, got %v", err)
+	}
+}
+
+func TestVerifyJWS(t *testing.T) {
+	known, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknown, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := FromPublicKey(&known.PublicKey)
+	key.Kid, key.Use = "known", "sig"
+	certs, _ := parseCerts(&jwks{Keys: []Key{key}}, time.Hour)
+	j := &JSONWebKeys{cachedCerts: certs}
+	payload := []byte(`{"software_id":"app"}`)
+
+	signer, err := jose.NewMultiSigner([]jose.SigningKey{
+		{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: unknown, KeyID: "unknown"}},
+		{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: known, KeyID: "known"}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	general := jws.FullSerialize()
+	compactSigner, _ := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: known, KeyID: "known"}}, nil)
+	compactJWS, _ := compactSigner.Sign(payload)
+	compact, _ := compactJWS.CompactSerialize()
+
+	for name, raw := range map[string]string{"json": general, "compact": compact} {
+		verified, signedBy, err := j.VerifyJWS(context.Background(), []byte(raw))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if string(verified) != string(payload) || signedBy.Kid != "known" {
+			t.Fatalf("%s: unexpected payload %s or key %+v", name, verified, signedBy)
+		}
+	}
+
+	unknownSigner, _ := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: unknown, KeyID: "known"}}, nil)
+	forged, _ := unknownSigner.Sign(payload)
+	raw, _ := forged.CompactSerialize()
+	if _, _, err := j.VerifyJWS(context.Background(), []byte(raw)); err == nil || !strings.Contains(err.Error(), "invalid signature") {
+		t.Fatalf("expecting a forged signature to be rejected, got %v", err)
+	}
+
+	// the key picked by the header of a signature must not verify another one
+	mixedSigner, err := jose.NewMultiSigner([]jose.SigningKey{
+		{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: unknown, KeyID: "known"}},
+		{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: known, KeyID: "missing"}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mixed, err := mixedSigner.Sign(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := j.VerifyJWS(context.Background(), []byte(mixed.FullSerialize())); err == nil {
+		t.Fatal("expecting each signature to be verified with the key of its own header")
+	}
+}
+
+func BenchmarkVerifyToken(b *testing.B) {
+	signer, j := newTestSigner(b, "test")
+	raw := signTestToken(b, signer, jwt.Claims{Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour))})
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := j.VerifyToken(context.Background(), raw); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
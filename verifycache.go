@@ -0,0 +1,120 @@
+package jwk
+
+import (
+	"context"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+// verifiedToken is a token whose signature was verified, as cached by VerifyToken
+type verifiedToken struct {
+	// header is checked again on each hit, as the accepted types and algs may have changed since
+	header     jose.Header
+	registered jwt.Claims
+	claims     map[string]interface{}
+	// certs are the ones cached when the token was verified, the entry is only valid as long as they are
+	certs  *Certs
+	expiry time.Time
+}
+
+// verifiedCache holds the verified tokens by hash
+type verifiedCache struct {
+	entries map[[sha256.Size]byte]verifiedToken
+	mutex   sync.Mutex
+}
+
+// verifiedClaims returns the claims of the given token once its signature is verified, skipping the verification
+// when the token is found in the cache enabled by VerifiedCacheSize. The claims themselves are always validated
+// by the caller, as their validity depends on the time and the call options
+func (j *JSONWebKeys) verifiedClaims(ctx context.Context, raw string, opts []CallOption) (jwt.Claims, map[string]interface{}, error) {
+	options := newCallOptions(opts)
+	if j.VerifiedCacheSize <= 0 || j.NoCache || options.cacheBypass || options.forceRefresh {
+		return j.verifyTokenSignature(ctx, raw, opts)
+	}
+
+	hash := sha256.Sum256([]byte(raw))
+	j.certsMutex.RLock()
+	certs := j.cachedCerts
+	j.certsMutex.RUnlock()
+	if entry, ok := j.verified.get(hash, certs); ok {
+		if err := j.checkHeader(entry.header); err != nil {
+			return jwt.Claims{}, nil, err
+		}
+		return entry.registered, copyClaims(entry.claims), nil
+	}
+
+	registered, claims, err := j.verifyTokenSignature(ctx, raw, opts)
+	if err != nil || registered.Expiry == nil || certs == nil {
+		// tokens without exp are not cached, as nothing bounds their lifetime
+		return registered, claims, err
+	}
+	// the token was already parsed by verifyTokenSignature
+	token, _ := jwt.ParseSigned(raw)
+	j.verified.put(hash, verifiedToken{
+		header:     token.Headers[0],
+		registered: registered,
+		claims:     copyClaims(claims),
+		certs:      certs,
+		expiry:     registered.Expiry.Time(),
+	}, j.VerifiedCacheSize)
+	return registered, claims, nil
+}
+
+// get returns the entry of the given token, if still valid with the given certs
+func (c *verifiedCache) get(hash [sha256.Size]byte, certs *Certs) (verifiedToken, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	entry, ok := c.entries[hash]
+	if !ok {
+		return entry, false
+	}
+	if entry.certs != certs || !time.Now().Before(entry.expiry) {
+		delete(c.entries, hash)
+		return entry, false
+	}
+	return entry, true
+}
+
+// put caches the given entry, making room by dropping the expired entries, or any entry when none is
+func (c *verifiedCache) put(hash [sha256.Size]byte, entry verifiedToken, limit int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.entries == nil {
+		c.entries = map[[sha256.Size]byte]verifiedToken{}
+	}
+	if len(c.entries) >= limit {
+		now := time.Now()
+		for key, cached := range c.entries {
+			if !now.Before(cached.expiry) {
+				delete(c.entries, key)
+			}
+		}
+		for key := range c.entries {
+			if len(c.entries) < limit {
+				break
+			}
+			delete(c.entries, key)
+		}
+	}
+	c.entries[hash] = entry
+}
+
+// flush drops all the entries
+func (c *verifiedCache) flush() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries = nil
+}
+
+// copyClaims returns a shallow copy of the claims, so that callers can't alter the cached ones
+func copyClaims(claims map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(claims))
+	for name, value := range claims {
+		copied[name] = value
+	}
+	return copied
+}
@@ -0,0 +1,90 @@
+package jwk
+
+import (
+	"context"
+	"crypto/sha256"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+func TestVerifiedCache(t *testing.T) {
+	signer, j := newTestSigner(t, "test")
+	j.VerifiedCacheSize = 2
+	raw := signTestToken(t, signer, jwt.Claims{Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour))})
+
+	claims, err := j.VerifyToken(context.Background(), raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(j.verified.entries) != 1 {
+		t.Fatalf("expecting the token to be cached, got %d entries", len(j.verified.entries))
+	}
+	claims["scope"] = "altered"
+	if claims, err = j.VerifyToken(context.Background(), raw); err != nil || claims["scope"] != "read" {
+		t.Fatalf("expecting the cached claims to be untouched, got %v %v", claims, err)
+	}
+
+	// the claims are still validated on a cache hit
+	j.Audience = "api"
+	if _, err := j.VerifyToken(context.Background(), raw); err == nil {
+		t.Fatal("expecting the audience to be checked")
+	}
+	j.Audience = ""
+
+	// refreshed certs invalidate the entries verified with the previous ones
+	j.certsMutex.Lock()
+	refreshed := *j.cachedCerts
+	j.cachedCerts = &refreshed
+	j.certsMutex.Unlock()
+	if _, ok := j.verified.get(sha256.Sum256([]byte(raw)), &refreshed); ok {
+		t.Fatal("expecting the entry to be invalidated by the refresh")
+	}
+
+	// tokens without exp are not cached, and the size is bounded
+	if _, err := j.VerifyToken(context.Background(), signTestToken(t, signer, jwt.Claims{})); err != nil {
+		t.Fatal(err)
+	}
+	if len(j.verified.entries) != 0 {
+		t.Fatalf("expecting no entry for a token without exp, got %d", len(j.verified.entries))
+	}
+	for i := 0; i < 4; i++ {
+		token := signTestToken(t, signer, jwt.Claims{ID: string(rune('a' + i)), Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour))})
+		if _, err := j.VerifyToken(context.Background(), token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(j.verified.entries) != 2 {
+		t.Fatalf("expecting at most 2 entries, got %d", len(j.verified.entries))
+	}
+}
+
+func TestVerifiedCacheHeaderChecks(t *testing.T) {
+	signer, j := newTestSigner(t, "test")
+	j.VerifiedCacheSize = 10
+	raw := signTestToken(t, signer, jwt.Claims{Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour))})
+	if _, err := j.VerifyToken(context.Background(), raw); err != nil {
+		t.Fatal(err)
+	}
+
+	// the header is checked again on a cache hit
+	j.Types = []string{"at+jwt"}
+	if _, err := j.VerifyToken(context.Background(), raw); err == nil {
+		t.Fatal("expecting the typ to be checked on a cache hit")
+	}
+	j.Types = nil
+	if _, err := j.VerifyToken(context.Background(), raw); err != nil {
+		t.Fatal(err)
+	}
+
+	// narrowing the algs drops the verified tokens
+	j.UpdateConfig(Config{Algorithms: []string{"ES256"}})
+	if len(j.verified.entries) != 0 {
+		t.Fatalf("expecting UpdateConfig to flush the verified tokens, got %d entries", len(j.verified.entries))
+	}
+	if _, err := j.VerifyToken(context.Background(), raw); err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Fatalf("expecting the RS256 token to be rejected, got %v", err)
+	}
+}
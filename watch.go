@@ -0,0 +1,201 @@
+package jwk
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// KeyChangeType tells what a KeyChangeEvent is about
+type KeyChangeType int
+
+const (
+	// KeyAdded is sent for a key added to the set, as well as for each key of the set when watching starts
+	KeyAdded KeyChangeType = iota
+	// KeyRemoved is sent for a key removed from the set
+	KeyRemoved
+	// KeyChanged is sent for a key whose members changed, keeping its kid
+	KeyChanged
+	// KeysExpired is sent when the cached key set expired and refreshing it failed, without any Key
+	KeysExpired
+)
+
+// KeyChangeEvent is a change of the key set delivered by Watch
+type KeyChangeEvent struct {
+	Type KeyChangeType
+	Key  Key
+	Time time.Time
+}
+
+// events returns the change as a sequence of events: removals first, then changes and additions
+func (c KeySetChange) events() []KeyChangeEvent {
+	events := make([]KeyChangeEvent, 0, len(c.Added)+len(c.Removed)+len(c.Changed))
+	for _, group := range []struct {
+		changeType KeyChangeType
+		keys       []Key
+	}{{KeyRemoved, c.Removed}, {KeyChanged, c.Changed}, {KeyAdded, c.Added}} {
+		for _, key := range group.keys {
+			events = append(events, KeyChangeEvent{Type: group.changeType, Key: key, Time: c.Time})
+		}
+	}
+	return events
+}
+
+// Watch delivers the changes of the key set on the returned channel until ctx is done or Close is called,
+// when it's closed.
+// The current keys are sent first as KeyAdded events, fetching them if needed: an error is returned when that
+// fails. A background refresher keeps the keys fresh while watching, unless RunRefresher is already running.
+// Updates are queued, so a slow reader never blocks the refreshes: past a few of them they are coalesced, the
+// reader then receiving at once the changes between the last keys it was told about and the latest ones
+func (j *JSONWebKeys) Watch(ctx context.Context) (<-chan KeyChangeEvent, error) {
+	if _, err := j.getKeys(ctx); err != nil {
+		return nil, err
+	}
+	if !j.startWorker() {
+		return nil, ErrClosed
+	}
+
+	w := &watcher{wake: make(chan struct{}, 1)}
+	j.watchMutex.Lock()
+	j.certsMutex.RLock()
+	certs := j.cachedCerts
+	j.certsMutex.RUnlock()
+	if certs != nil {
+		w.push(watchUpdate{certs: certs, time: time.Now()})
+	}
+	if j.watchers == nil {
+		j.watchers = map[*watcher]struct{}{}
+	}
+	j.watchers[w] = struct{}{}
+	if j.stopWatchRefresher == nil && atomic.LoadInt32(&j.refreshers) == 0 {
+		refresherCtx, cancel := context.WithCancel(context.Background())
+		j.stopWatchRefresher = cancel
+		// the keys were just fetched, no need to refresh them right away
+		go j.runRefresher(refresherCtx, false)
+	}
+	j.watchMutex.Unlock()
+
+	events := make(chan KeyChangeEvent)
+	go func() {
+		defer j.workers.Done()
+		runCtx, cancel := j.withLifecycle(ctx)
+		defer cancel()
+		w.run(runCtx, events)
+		j.watchMutex.Lock()
+		delete(j.watchers, w)
+		if len(j.watchers) == 0 && j.stopWatchRefresher != nil {
+			j.stopWatchRefresher()
+			j.stopWatchRefresher = nil
+		}
+		j.watchMutex.Unlock()
+	}()
+	return events, nil
+}
+
+// notifyWatchers queues the given update for every watcher, watchMutex must be held
+func (j *JSONWebKeys) notifyWatchers(update watchUpdate) {
+	for w := range j.watchers {
+		w.push(update)
+	}
+}
+
+// reportExpiry sends a KeysExpired event when the cached certs expired, once per key set
+func (j *JSONWebKeys) reportExpiry() {
+	j.watchMutex.Lock()
+	defer j.watchMutex.Unlock()
+
+	j.certsMutex.RLock()
+	certs := j.cachedCerts
+	j.certsMutex.RUnlock()
+	if certs == nil || certs == j.expiredCerts || time.Now().Before(certs.Expiry) {
+		return
+	}
+	j.expiredCerts = certs
+	j.notifyWatchers(watchUpdate{expired: true, time: certs.Expiry})
+}
+
+// maxWatchUpdates bounds the updates queued for a watcher, past which only the latest key set is kept
+const maxWatchUpdates = 16
+
+// watchUpdate is an update of the key set for the watchers: new certs, or the expiry of the previous ones
+type watchUpdate struct {
+	certs   *Certs
+	expired bool
+	time    time.Time
+}
+
+// watcher queues the updates of a Watch call until they are read
+type watcher struct {
+	mutex sync.Mutex
+	queue []watchUpdate
+	// wake signals that the queue is no longer empty
+	wake chan struct{}
+
+	// sent is the key set the reader was last told about, only used by run
+	sent *Certs
+}
+
+// push queues the given update, coalescing it with the previous one when both are key sets
+func (w *watcher) push(update watchUpdate) {
+	w.mutex.Lock()
+	n := len(w.queue)
+	switch {
+	case update.certs != nil && n > 0 && w.queue[n-1].certs != nil:
+		w.queue[n-1] = update
+	case n >= maxWatchUpdates:
+		// the queued expiries are dropped, the reader catches up with the latest key set
+		latest := w.queue[:0]
+		for i := n - 1; i >= 0; i-- {
+			if w.queue[i].certs != nil {
+				latest = append(latest, w.queue[i])
+				break
+			}
+		}
+		w.queue = append(latest, update)
+	default:
+		w.queue = append(w.queue, update)
+	}
+	w.mutex.Unlock()
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// events returns the events telling the reader about the given update
+func (w *watcher) events(update watchUpdate) []KeyChangeEvent {
+	if update.expired {
+		return []KeyChangeEvent{{Type: KeysExpired, Time: update.time}}
+	}
+	added, removed, changed := Diff(w.sent, update.certs)
+	w.sent = update.certs
+	return KeySetChange{Time: update.time, Added: added, Removed: removed, Changed: changed}.events()
+}
+
+// run sends the events of the queued updates on out until ctx is done, then closes it
+func (w *watcher) run(ctx context.Context, out chan<- KeyChangeEvent) {
+	defer close(out)
+	for {
+		w.mutex.Lock()
+		queue := w.queue
+		w.queue = nil
+		w.mutex.Unlock()
+
+		for _, update := range queue {
+			for _, event := range w.events(update) {
+				select {
+				case out <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+
+		select {
+		case <-w.wake:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package jwk
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// nextEvent reads an event, failing the test if none comes in time
+func nextEvent(t *testing.T, events <-chan KeyChangeEvent) KeyChangeEvent {
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatal("unexpected end of the events")
+		}
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("expecting an event")
+	}
+	return KeyChangeEvent{}
+}
+
+func TestWatch(t *testing.T) {
+	initial, err := ioutil.ReadFile("testdata/jwks.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotatedKey := testKey
+	rotatedKey.Kid = "rotated"
+	rotated, err := json.Marshal(jwks{Keys: []Key{rotatedKey}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body atomic.Value
+	body.Store(initial)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=1")
+		w.Write(body.Load().([]byte))
+	}))
+	defer server.Close()
+
+	j := &JSONWebKeys{JWKURL: server.URL}
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := j.Watch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event := nextEvent(t, events); event.Type != KeyAdded || event.Key.Kid != testKid {
+		t.Fatalf("expecting the current key first, got %+v", event)
+	}
+
+	body.Store(rotated)
+	if event := nextEvent(t, events); event.Type != KeyRemoved || event.Key.Kid != testKid {
+		t.Fatalf("expecting the removal of the previous key, got %+v", event)
+	}
+	if event := nextEvent(t, events); event.Type != KeyAdded || event.Key.Kid != "rotated" {
+		t.Fatalf("expecting the addition of the rotated key, got %+v", event)
+	}
+
+	cancel()
+	for range events {
+	}
+	waitFor(t, func() bool { return atomic.LoadInt32(&j.refreshers) == 0 })
+}
+
+func TestWatchExpiry(t *testing.T) {
+	j := &JSONWebKeys{cachedCerts: &Certs{Keys: map[string]Key{}, Expiry: time.Now().Add(-time.Minute)}}
+	w := &watcher{wake: make(chan struct{}, 1)}
+	j.watchers = map[*watcher]struct{}{w: {}}
+
+	j.reportExpiry()
+	j.reportExpiry()
+	if len(w.queue) != 1 || !w.queue[0].expired {
+		t.Fatalf("expecting a single expiry event, got %+v", w.queue)
+	}
+}
+
+func TestWatchSingleFetch(t *testing.T) {
+	server, requests := newTestJWKSServer(t, "max-age=3600", 0)
+	defer server.Close()
+
+	j := &JSONWebKeys{JWKURL: server.URL}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := j.Watch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nextEvent(t, events)
+	waitFor(t, func() bool { return !j.RefreshStatus().NextRefresh.IsZero() })
+	if n := atomic.LoadInt32(requests); n != 1 {
+		t.Fatalf("expecting the refresher to keep the keys just fetched, got %d requests", n)
+	}
+}
+
+func TestWatchCoalescing(t *testing.T) {
+	w := &watcher{wake: make(chan struct{}, 1)}
+	set := func(kids ...string) *Certs {
+		certs := &Certs{Keys: map[string]Key{}}
+		for _, kid := range kids {
+			key := testKey
+			key.Kid = kid
+			certs.Keys[kid] = key
+		}
+		return certs
+	}
+	w.push(watchUpdate{certs: set("a")})
+	for i := 0; i < 1000; i++ {
+		w.push(watchUpdate{certs: set("a", "b")})
+		w.push(watchUpdate{expired: true})
+	}
+	w.push(watchUpdate{certs: set("b", "c")})
+	if len(w.queue) > maxWatchUpdates {
+		t.Fatalf("expecting the queue to be bounded, got %d updates", len(w.queue))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan KeyChangeEvent)
+	go w.run(ctx, events)
+	kids := map[KeyChangeType][]string{}
+	for len(kids[KeyAdded]) < 3 {
+		event := nextEvent(t, events)
+		kids[event.Type] = append(kids[event.Type], event.Key.Kid)
+	}
+	cancel()
+	if added := kids[KeyAdded]; added[0] != "a" || added[1] != "b" || added[2] != "c" {
+		t.Errorf("unexpected added keys %v", added)
+	}
+}
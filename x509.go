@@ -0,0 +1,97 @@
+package jwk
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"sync"
+)
+
+// Certificates decodes the x5c chain of the key, leaf certificate first
+func (k Key) Certificates() ([]*x509.Certificate, error) {
+	certs := make([]*x509.Certificate, 0, len(k.X5c))
+	for i, encoded := range k.X5c {
+		der, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("malformed x5c certificate %d: %w", i, err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("malformed x5c certificate %d: %w", i, err)
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+// chainCache memoizes the decoded x5c chains of a key set, so that they are decoded once per fetch
+type chainCache struct {
+	mutex  sync.Mutex
+	chains map[string]decodedChain
+}
+
+// decodedChain is the outcome of decoding the given x5c chain
+type decodedChain struct {
+	x5c   []string
+	certs []*x509.Certificate
+	err   error
+}
+
+// Certificates decodes the x5c chain of the key with the given kid, leaf certificate first. The chains of fetched
+// certs are decoded on first use only, later calls sharing the same certificates, which must not be modified
+func (c Certs) Certificates(kid string) ([]*x509.Certificate, error) {
+	key, ok := c.Keys[kid]
+	if !ok {
+		return nil, &KeyNotFoundError{Kid: kid}
+	}
+	return c.chain(kid, key)
+}
+
+// chain decodes the x5c chain of the key, through the chain cache when the certs have one
+func (c Certs) chain(kid string, key Key) ([]*x509.Certificate, error) {
+	if c.chains == nil || len(key.X5c) == 0 {
+		return key.Certificates()
+	}
+	c.chains.mutex.Lock()
+	defer c.chains.mutex.Unlock()
+	decoded, ok := c.chains.chains[kid]
+	if !ok || !equalStrings(decoded.x5c, key.X5c) {
+		// the Keys map may have been changed since, so the chain is checked as well as the kid
+		certs, err := key.Certificates()
+		decoded = decodedChain{x5c: key.X5c, certs: certs, err: err}
+		if c.chains.chains == nil {
+			c.chains.chains = map[string]decodedChain{}
+		}
+		c.chains.chains[kid] = decoded
+	}
+	return append([]*x509.Certificate(nil), decoded.certs...), decoded.err
+}
+
+// equalStrings tells if both slices hold the same strings
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// CertPool returns a pool holding every certificate of every x5c chain of the set, so that the same JWKS
+// can drive mTLS client certificate validation, e.g. as tls.Config.ClientCAs
+func (c Certs) CertPool() (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	for kid, key := range c.Keys {
+		certs, err := c.chain(kid, key)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", kid, err)
+		}
+		for _, cert := range certs {
+			pool.AddCert(cert)
+		}
+	}
+	return pool, nil
+}
@@ -0,0 +1,86 @@
+package jwk
+
+import (
+	"crypto/x509"
+	"errors"
+	"testing"
+)
+
+func TestCertPool(t *testing.T) {
+	certs, err := getTestCerts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool, err := certs.CertPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the test certificate is self-signed, so it verifies against the pool
+	chain, err := testKey.Certificates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chain) != 1 {
+		t.Fatalf("expecting a single certificate, got %d", len(chain))
+	}
+	opts := x509.VerifyOptions{Roots: pool, CurrentTime: chain[0].NotBefore, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}}
+	if _, err := chain[0].Verify(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	malformed := testKey
+	malformed.X5c = []string{"AQAB"}
+	if _, err := (Certs{Keys: map[string]Key{"malformed": malformed}}).CertPool(); err == nil {
+		t.Fatal("expecting an error for a malformed certificate")
+	}
+}
+
+func TestCertificatesMemoized(t *testing.T) {
+	certs, err := getTestCerts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := certs.Certificates(testKid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := certs.clone().Certificates(testKid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 1 || len(second) != 1 || first[0] != second[0] {
+		t.Fatal("expecting the chain to be decoded once and shared by the copies of the certs")
+	}
+	if _, err := certs.CertPool(); err != nil {
+		t.Fatal(err)
+	}
+	if allocs := testing.AllocsPerRun(10, func() { certs.Certificates(testKid) }); allocs > 1 {
+		t.Fatalf("expecting the memoized chain to be returned without decoding, got %v allocs", allocs)
+	}
+
+	// a key replaced in the map is decoded again
+	changed := certs.clone()
+	malformed := testKey
+	malformed.X5c = []string{"AQAB"}
+	changed.Keys[testKid] = malformed
+	if _, err := changed.Certificates(testKid); err == nil {
+		t.Fatal("expecting an error for the replaced chain")
+	}
+	if _, err := certs.Certificates("missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expecting ErrKeyNotFound, got %v", err)
+	}
+}
+
+func BenchmarkCertPool(b *testing.B) {
+	certs, err := getTestCerts()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := certs.CertPool(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}